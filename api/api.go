@@ -0,0 +1,44 @@
+// Package api holds the types shared by the clients of the Dapla APIs, such as the data-maintenance service and the
+// dapla-pseudo-service, so that the clients do not depend on each other.
+package api
+
+import (
+	"context"
+	"strconv"
+)
+
+// HTTPError holds information returned from an erroneous HTTP request, such as status code and error message
+type HTTPError struct {
+	statusCode int
+	message    string
+}
+
+// NewHTTPError creates an HTTPError with the status code and message of an erroneous response
+func NewHTTPError(statusCode int, message string) *HTTPError {
+	return &HTTPError{statusCode: statusCode, message: message}
+}
+
+func (httpError *HTTPError) Error() string {
+	return httpError.message + " (" + strconv.Itoa(httpError.statusCode) + ")"
+}
+
+// StatusCode returns the HTTP status code of the erroneous response
+func (httpError *HTTPError) StatusCode() int {
+	return httpError.statusCode
+}
+
+// Message returns the error message in the body of the erroneous response
+func (httpError *HTTPError) Message() string {
+	return httpError.message
+}
+
+// TokenSource returns the auth token to send with a request. It is called for every request.
+type TokenSource func(ctx context.Context) (string, error)
+
+// PseudoRule describes how a field of a dataset is pseudonymized, e.g. the fields matching the glob pattern **/fnr
+// with the func fpe-fnr(secret1). Datasets have pseudo rules, and export requests take them.
+type PseudoRule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	Func    string `json:"func"`
+}
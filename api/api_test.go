@@ -0,0 +1,16 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPError(t *testing.T) {
+	err := NewHTTPError(http.StatusNotFound, "no such dataset")
+
+	assert.EqualError(t, err, "no such dataset (404)")
+	assert.Equal(t, http.StatusNotFound, err.StatusCode())
+	assert.Equal(t, "no such dataset", err.Message())
+}
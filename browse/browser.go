@@ -0,0 +1,412 @@
+// Package browse implements a full-screen browser of the dataset catalog. The folders are navigated with the
+// keyboard, the metadata of the selected dataset or folder is shown in a details pane, and datasets can be marked
+// for deletion or export. The browser only collects the marks: applying them is left to the caller, once the user
+// has confirmed.
+//
+// The browser reads key presses from any reader and draws on any writer, so it can be run headless in tests. On a
+// terminal, the terminal should be in raw mode, see shell.MakeRaw.
+package browse
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"github.com/statisticsnorway/dapla-cli/shell"
+)
+
+// Mark is an action that a dataset is marked for
+type Mark int
+
+// The marks of datasets
+const (
+	Unmarked Mark = iota
+	MarkDelete
+	MarkExport
+)
+
+func (m Mark) String() string {
+	switch m {
+	case MarkDelete:
+		return "delete"
+	case MarkExport:
+		return "export"
+	}
+	return ""
+}
+
+// help is shown on the status line when there is nothing else to show
+const help = "↑↓ move  → open  ← back  d delete  e export  x apply  q quit"
+
+// Browser is the state of the browser: the folder being browsed, the selected element and the marked datasets
+type Browser struct {
+	// Client lists the folders
+	Client maintenance.API
+	// Width and Height are the size of the screen, in columns and rows
+	Width, Height int
+	// CanMark returns an error if a dataset can not be marked, e.g. because it is protected. The error is shown
+	// on the status line. All datasets can be marked if nil.
+	CanMark func(path string, mark Mark) error
+	// Question returns the question asking the user to confirm the marks, given the number of datasets with each
+	// mark, e.g. "Delete 2 datasets?". A generic question is asked if nil.
+	Question func(marked map[Mark]int) string
+
+	folder  string
+	entries []maintenance.ListDatasetElement
+	cursor  int
+	// offset is the index of the first entry shown, when the entries do not fit on the screen
+	offset     int
+	marks      map[string]Mark
+	confirming bool
+	status     string
+	done       bool
+	confirmed  bool
+}
+
+// New creates a browser of the datasets and folders of client, with a screen of 80x24
+func New(client maintenance.API) *Browser {
+	return &Browser{
+		Client: client,
+		Width:  80,
+		Height: 24,
+		marks:  map[string]Mark{},
+	}
+}
+
+// Open lists folder and shows its datasets and folders, with the cursor on the first one
+func (b *Browser) Open(ctx context.Context, folder string) error {
+	res, err := b.Client.ListDatasets(ctx, folder)
+	if err != nil {
+		return err
+	}
+	b.folder = folder
+	b.entries = *res
+	sort.Slice(b.entries, func(i, j int) bool {
+		return b.entries[i].Path < b.entries[j].Path
+	})
+	b.cursor, b.offset = 0, 0
+	return nil
+}
+
+// Run draws the browser on out, and handles the keys read from in, until the user quits or confirms the marks.
+// Returns true if the user confirmed. The end of the input is handled as quitting.
+func (b *Browser) Run(ctx context.Context, in *bufio.Reader, out io.Writer) (bool, error) {
+	for !b.done {
+		b.draw(out)
+		key, err := shell.ReadKey(in)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return false, err
+		}
+		b.HandleKey(ctx, key)
+	}
+	return b.confirmed, nil
+}
+
+// HandleKey updates the browser after a key press
+func (b *Browser) HandleKey(ctx context.Context, key shell.Key) {
+	b.status = ""
+	if b.confirming {
+		switch key {
+		case 'y', 'Y':
+			b.done, b.confirmed = true, true
+		case 3: // Ctrl+C
+			b.done = true
+		default:
+			b.confirming = false
+			b.status = "Cancelled"
+		}
+		return
+	}
+
+	switch key {
+	case shell.KeyUp, 'k':
+		b.move(-1)
+	case shell.KeyDown, 'j':
+		b.move(1)
+	case shell.KeyPageUp:
+		b.move(-b.rows())
+	case shell.KeyPageDown:
+		b.move(b.rows())
+	case shell.KeyHome, 'g':
+		b.move(-len(b.entries))
+	case shell.KeyEnd, 'G':
+		b.move(len(b.entries))
+	case shell.KeyRight, 'l', '\r', '\n':
+		if selected, ok := b.selected(); ok && selected.IsFolder() {
+			b.open(ctx, selected.Path, "")
+		}
+	case shell.KeyLeft, 'h', 8, 127: // Backspace
+		if b.folder != "/" {
+			b.open(ctx, path.Dir(b.folder), b.folder)
+		}
+	case 'r':
+		b.open(ctx, b.folder, b.selectedPath())
+	case 'd':
+		b.toggle(MarkDelete)
+	case 'e':
+		b.toggle(MarkExport)
+	case 'x':
+		if len(b.marks) == 0 {
+			b.status = "No datasets are marked"
+		} else {
+			b.confirming = true
+		}
+	case 'q', 3: // Ctrl+C
+		b.done = true
+	}
+}
+
+// Marked returns the paths of the datasets marked with mark, sorted
+func (b *Browser) Marked(mark Mark) []string {
+	var paths []string
+	for p, m := range b.marks {
+		if m == mark {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// View returns the lines of the screen, without any colors or escape sequences. The first line shows the folder,
+// the last line shows the status, and the lines in between show the datasets and folders of the folder to the
+// left, and the details of the selected one to the right.
+func (b *Browser) View() []string {
+	listWidth := b.listWidth()
+	detailsWidth := b.Width - listWidth - 3
+	rows := b.rows()
+
+	list := make([]string, 0, rows)
+	for i := b.offset; i < len(b.entries) && i < b.offset+rows; i++ {
+		entry := b.entries[i]
+		cursor := " "
+		if i == b.cursor {
+			cursor = ">"
+		}
+		mark := " "
+		switch b.marks[entry.Path] {
+		case MarkDelete:
+			mark = "D"
+		case MarkExport:
+			mark = "E"
+		}
+		name := path.Base(entry.Path)
+		if entry.IsFolder() {
+			name += "/"
+		}
+		list = append(list, cursor+" "+mark+" "+name)
+	}
+	if len(b.entries) == 0 {
+		list = append(list, "  (empty)")
+	}
+
+	var details []string
+	if b.confirming {
+		details = b.markedDetails()
+	} else if selected, ok := b.selected(); ok {
+		details = b.details(selected)
+	}
+
+	lines := []string{strings.TrimRight(fit("dapla browse: "+b.folder, b.Width), " ")}
+	for i := 0; i < rows; i++ {
+		var left, right string
+		if i < len(list) {
+			left = list[i]
+		}
+		if i < len(details) {
+			right = details[i]
+		}
+		lines = append(lines, strings.TrimRight(fit(left, listWidth)+" │ "+fit(right, detailsWidth), " "))
+	}
+
+	status := b.status
+	switch {
+	case b.confirming:
+		status = b.question()
+	case status == "":
+		status = help
+	}
+	return append(lines, strings.TrimRight(fit(status, b.Width), " "))
+}
+
+// draw draws the screen on out, highlighting the selected dataset or folder
+func (b *Browser) draw(out io.Writer) {
+	var screen strings.Builder
+	screen.WriteString("\x1b[H")
+	for i, line := range b.View() {
+		if i > 0 {
+			screen.WriteString("\r\n")
+		}
+		if i == b.cursor-b.offset+1 && len(b.entries) > 0 {
+			left := []rune(line)[:b.listWidth()]
+			line = "\x1b[7m" + string(left) + "\x1b[0m" + strings.TrimPrefix(line, string(left))
+		}
+		screen.WriteString(line + "\x1b[K")
+	}
+	fmt.Fprint(out, screen.String())
+}
+
+// open opens folder, with the cursor on the entry with the path selected if there is one. Errors are shown on the
+// status line, and the current folder is kept.
+func (b *Browser) open(ctx context.Context, folder string, selected string) {
+	if err := b.Open(ctx, folder); err != nil {
+		b.status = fmt.Sprintf("Could not list %s: %v", folder, err)
+		return
+	}
+	for i, entry := range b.entries {
+		if entry.Path == selected {
+			b.move(i)
+		}
+	}
+}
+
+// move moves the cursor n entries down (or up if n is negative), and scrolls the entries to keep it on the screen
+func (b *Browser) move(n int) {
+	b.cursor += n
+	if b.cursor >= len(b.entries) {
+		b.cursor = len(b.entries) - 1
+	}
+	if b.cursor < 0 {
+		b.cursor = 0
+	}
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	}
+	if b.cursor >= b.offset+b.rows() {
+		b.offset = b.cursor - b.rows() + 1
+	}
+}
+
+// toggle marks the selected dataset with mark, or removes the mark if it is already marked with it
+func (b *Browser) toggle(mark Mark) {
+	selected, ok := b.selected()
+	switch {
+	case !ok:
+		return
+	case selected.IsFolder():
+		b.status = "Only datasets can be marked"
+	case b.marks[selected.Path] == mark:
+		delete(b.marks, selected.Path)
+	default:
+		if b.CanMark != nil {
+			if err := b.CanMark(selected.Path, mark); err != nil {
+				b.status = err.Error()
+				return
+			}
+		}
+		b.marks[selected.Path] = mark
+		b.move(1)
+	}
+}
+
+func (b *Browser) selected() (maintenance.ListDatasetElement, bool) {
+	if b.cursor >= len(b.entries) {
+		return maintenance.ListDatasetElement{}, false
+	}
+	return b.entries[b.cursor], true
+}
+
+func (b *Browser) selectedPath() string {
+	selected, _ := b.selected()
+	return selected.Path
+}
+
+// details returns the lines of the details pane of element
+func (b *Browser) details(element maintenance.ListDatasetElement) []string {
+	kind := "dataset"
+	if element.IsFolder() {
+		kind = "folder"
+	}
+	lines := []string{
+		"Path        " + element.Path,
+		"Kind        " + kind,
+	}
+	for _, field := range []struct{ name, value string }{
+		{"Type", element.Type},
+		{"Valuation", element.Valuation},
+		{"State", element.State},
+		{"Created by", element.CreatedBy},
+	} {
+		if field.value != "" {
+			lines = append(lines, fmt.Sprintf("%-12s%s", field.name, field.value))
+		}
+	}
+	if !element.CreatedAt.IsZero() {
+		lines = append(lines, "Created     "+element.CreatedAt.Format(time.RFC3339))
+	}
+	if mark := b.marks[element.Path]; mark != Unmarked {
+		lines = append(lines, "Marked      "+mark.String())
+	}
+	return lines
+}
+
+// markedDetails returns the lines of the details pane while confirming, listing the marked datasets
+func (b *Browser) markedDetails() []string {
+	var lines []string
+	for _, mark := range []Mark{MarkDelete, MarkExport} {
+		paths := b.Marked(mark)
+		if len(paths) == 0 {
+			continue
+		}
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, capitalize(mark.String())+":")
+		for _, p := range paths {
+			lines = append(lines, "  "+p)
+		}
+	}
+	return lines
+}
+
+// question returns the question asking the user to confirm the marks
+func (b *Browser) question() string {
+	if b.Question == nil {
+		return "Apply the marks? (y/n)"
+	}
+	marked := map[Mark]int{}
+	for _, mark := range []Mark{MarkDelete, MarkExport} {
+		if n := len(b.Marked(mark)); n > 0 {
+			marked[mark] = n
+		}
+	}
+	return b.Question(marked) + " (y/n)"
+}
+
+// rows returns the number of rows available for the entries
+func (b *Browser) rows() int {
+	if b.Height < 3 {
+		return 1
+	}
+	return b.Height - 2
+}
+
+// listWidth returns the width of the list of entries, which takes up half of the screen
+func (b *Browser) listWidth() int {
+	return (b.Width - 3) / 2
+}
+
+// fit pads s with spaces, or cuts it, to exactly width characters
+func fit(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if n := utf8.RuneCountInString(s); n <= width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return string([]rune(s)[:width])
+}
+
+func capitalize(s string) string {
+	return strings.ToUpper(s[:1]) + s[1:]
+}
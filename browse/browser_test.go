@@ -0,0 +1,147 @@
+package browse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/statisticsnorway/dapla-cli/devserver"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"github.com/statisticsnorway/dapla-cli/shell"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBrowser(t *testing.T) *Browser {
+	server := httptest.NewServer(devserver.New(devserver.SeedCatalog()).MaintenanceHandler())
+	t.Cleanup(server.Close)
+
+	b := New(maintenance.New(server.URL))
+	b.Width, b.Height = 73, 8
+	assert.NoError(t, b.Open(context.Background(), "/"))
+	return b
+}
+
+func run(t *testing.T, b *Browser, keys string) bool {
+	var out bytes.Buffer
+	confirmed, err := b.Run(context.Background(), bufio.NewReader(strings.NewReader(keys)), &out)
+	assert.NoError(t, err)
+	return confirmed
+}
+
+func TestBrowser_Navigate(t *testing.T) {
+	b := newTestBrowser(t)
+	assert.Equal(t, []string{
+		"dapla browse: /",
+		">   produkt/                        │ Path        /produkt",
+		"    raw/                            │ Kind        folder",
+		"    skatt/                          │ Created by  kari.nordmann@ssb.no",
+		"                                    │ Created     2021-03-15T10:00:00Z",
+		"                                    │",
+		"                                    │",
+		help,
+	}, b.View())
+
+	// Down twice, open skatt, down to person and open it, and down to inntekt
+	run(t, b, "jj\rj\x1b[C\x1b[B")
+	assert.Equal(t, []string{
+		"dapla browse: /skatt/person",
+		"    formue                          │ Path        /skatt/person/inntekt",
+		">   inntekt                         │ Kind        dataset",
+		"                                    │ Type        BOUNDED",
+		"                                    │ Valuation   SENSITIVE",
+		"                                    │ State       INPUT",
+		"                                    │ Created by  ola.nordmann@ssb.no",
+		help,
+	}, b.View())
+
+	// Back to skatt, with the cursor on person
+	run(t, b, "h")
+	assert.Equal(t, "dapla browse: /skatt", b.View()[0])
+	assert.Contains(t, b.View()[2], ">   person/")
+
+	// Datasets can not be opened, and folders can not be marked
+	run(t, b, "kd")
+	assert.Equal(t, "dapla browse: /skatt", b.View()[0])
+	assert.Equal(t, "Only datasets can be marked", b.View()[7])
+}
+
+func TestBrowser_Scroll(t *testing.T) {
+	b := newTestBrowser(t)
+	b.Height = 4
+
+	run(t, b, "\x1b[6~")
+	view := b.View()
+	assert.Contains(t, view[1], "    raw/")
+	assert.Contains(t, view[2], ">   skatt/")
+
+	run(t, b, "g")
+	assert.Contains(t, b.View()[1], ">   produkt/")
+}
+
+func TestBrowser_Mark(t *testing.T) {
+	b := newTestBrowser(t)
+	b.CanMark = func(path string, mark Mark) error {
+		if strings.HasPrefix(path, "/skatt/2020") {
+			return errors.New(path + " is protected")
+		}
+		return nil
+	}
+	assert.NoError(t, b.Open(context.Background(), "/skatt/person"))
+
+	// Mark formue for export (moving down to inntekt), then inntekt for deletion, and formue for deletion instead
+	run(t, b, "edkd")
+	assert.Equal(t, []string{"/skatt/person/formue", "/skatt/person/inntekt"}, b.Marked(MarkDelete))
+	assert.Empty(t, b.Marked(MarkExport))
+
+	// Unmark inntekt by marking it again, and mark it for export
+	run(t, b, "de")
+	assert.Equal(t, []string{"/skatt/person/formue"}, b.Marked(MarkDelete))
+	assert.Equal(t, []string{"/skatt/person/inntekt"}, b.Marked(MarkExport))
+
+	// Protected datasets can not be marked
+	run(t, b, "hk\rd")
+	assert.Equal(t, "/skatt/2020/inntekt is protected", b.View()[7])
+	assert.Len(t, b.Marked(MarkDelete), 1)
+}
+
+func TestBrowser_Confirm(t *testing.T) {
+	b := newTestBrowser(t)
+	assert.NoError(t, b.Open(context.Background(), "/skatt/person"))
+
+	assert.False(t, run(t, b, "x"))
+	assert.Equal(t, "No datasets are marked", b.View()[7])
+
+	// Cancel the first confirmation
+	b = newTestBrowser(t)
+	assert.NoError(t, b.Open(context.Background(), "/skatt/person"))
+	b.HandleKey(context.Background(), 'd')
+	b.HandleKey(context.Background(), 'e')
+	b.HandleKey(context.Background(), 'x')
+	assert.Equal(t, []string{
+		"dapla browse: /skatt/person",
+		"  D formue                          │ Delete:",
+		"> E inntekt                         │   /skatt/person/formue",
+		"                                    │",
+		"                                    │ Export:",
+		"                                    │   /skatt/person/inntekt",
+		"                                    │",
+		"Apply the marks? (y/n)",
+	}, b.View())
+	b.HandleKey(context.Background(), 'n')
+	assert.Equal(t, "Cancelled", b.View()[7])
+
+	assert.True(t, run(t, b, "xy"))
+}
+
+func TestBrowser_Quit(t *testing.T) {
+	b := newTestBrowser(t)
+	assert.False(t, run(t, b, "dq"))
+
+	b = newTestBrowser(t)
+	b.HandleKey(context.Background(), shell.Key(3))
+	assert.False(t, run(t, b, "j"))
+}
@@ -0,0 +1,335 @@
+// Package cache implements a disk cache of the folder listings of the data-maintenance API, so that completing paths
+// is fast, and folders that have been listed before can be browsed offline.
+//
+// Each cache directory holds the listings of one API and one user (see Dir), in a JSON file per folder.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+)
+
+// DefaultTTL is how long listings are fresh by default
+const DefaultTTL = 5 * time.Minute
+
+// Client is a data-maintenance API that caches the listings of folders on disk. Fresh listings, which are younger
+// than the TTL, are served from the cache. Stale listings are listed again, unless the client serves stale listings
+// while revalidating them (see StaleWhileRevalidate), or the API can not be reached, e.g. when offline. Live clients
+// (see Live) list folders from the API every time, and only update the cache.
+//
+// The listings are invalidated when datasets are deleted, moved, copied or restored through the client.
+type Client struct {
+	maintenance.API
+	dir        string
+	ttl        time.Duration
+	now        func() time.Time
+	revalidate func(path string)
+	live       bool
+}
+
+var _ maintenance.API = (*Client)(nil)
+
+// Option configures a Client created by New
+type Option func(c *Client)
+
+// WithTTL sets how long listings are fresh
+func WithTTL(ttl time.Duration) Option {
+	return func(c *Client) {
+		c.ttl = ttl
+	}
+}
+
+// WithClock sets the function returning the current time, which is used to decide if listings are fresh
+func WithClock(now func() time.Time) Option {
+	return func(c *Client) {
+		c.now = now
+	}
+}
+
+// New creates a client that caches the listings of api in dir. The directory is created when the first listing is
+// cached. Without options the listings are fresh for DefaultTTL.
+func New(api maintenance.API, dir string, opts ...Option) *Client {
+	c := &Client{
+		API: api,
+		dir: dir,
+		ttl: DefaultTTL,
+		now: time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Dir returns the directory holding the listings of the API at apiURL for user, within the cache directory root.
+// Users and APIs are kept apart, since they may see different datasets.
+func Dir(root string, apiURL string, user string) string {
+	sum := sha256.Sum256([]byte(apiURL + "\n" + user))
+	return filepath.Join(root, hex.EncodeToString(sum[:8]))
+}
+
+// Clear deletes all the listings in the cache directory root, for all APIs and users
+func Clear(root string) error {
+	return os.RemoveAll(root)
+}
+
+// StaleWhileRevalidate returns a copy of the client that serves stale listings right away, and calls revalidate
+// with their paths so that they can be refreshed in the background, e.g. by Refresh
+func (c *Client) StaleWhileRevalidate(revalidate func(path string)) *Client {
+	clone := *c
+	clone.revalidate = revalidate
+	return &clone
+}
+
+// Live returns a copy of the client that lists folders from the API every time, even when offline, and caches the
+// listings for other clients. Changes through the client still invalidate the cached listings.
+func (c *Client) Live() *Client {
+	clone := *c
+	clone.live = true
+	return &clone
+}
+
+// Cached returns a copy of the client that serves listings from the cache, see Client
+func (c *Client) Cached() *Client {
+	clone := *c
+	clone.live = false
+	return &clone
+}
+
+// entry is a cached listing
+type entry struct {
+	Path      string                          `json:"path"`
+	FetchedAt time.Time                       `json:"fetchedAt"`
+	Datasets  maintenance.ListDatasetResponse `json:"datasets"`
+}
+
+// ListDatasets returns the datasets and folders directly under path, from the cache if possible
+func (c *Client) ListDatasets(ctx context.Context, path string) (*maintenance.ListDatasetResponse, error) {
+	cached := c.read(path)
+	if c.serve(cached) {
+		return &cached.Datasets, nil
+	}
+
+	res, err := c.API.ListDatasets(ctx, path)
+	if err != nil {
+		if cached != nil && !c.live && isOffline(ctx, err) {
+			return &cached.Datasets, nil
+		}
+		return nil, err
+	}
+	c.write(path, *res)
+	return res, nil
+}
+
+// IterateDatasets calls fn for each dataset and folder directly under path, from the cache if possible. Listings
+// that are not served from the cache are cached once the iteration is complete.
+func (c *Client) IterateDatasets(ctx context.Context, path string, fn maintenance.IterateFunc) error {
+	cached := c.read(path)
+	if c.serve(cached) {
+		return iterate(cached.Datasets, fn)
+	}
+
+	elements := maintenance.ListDatasetResponse{}
+	var fnErr error
+	err := c.API.IterateDatasets(ctx, path, func(element maintenance.ListDatasetElement) error {
+		elements = append(elements, element)
+		fnErr = fn(element)
+		return fnErr
+	})
+	if err != nil {
+		// Only fall back to the cache if none of the elements have been seen yet
+		if fnErr == nil && len(elements) == 0 && cached != nil && !c.live && isOffline(ctx, err) {
+			return iterate(cached.Datasets, fn)
+		}
+		return err
+	}
+	c.write(path, elements)
+	return nil
+}
+
+// Refresh lists path and caches the listing, even if the cached listing is fresh
+func (c *Client) Refresh(ctx context.Context, path string) error {
+	res, err := c.API.ListDatasets(ctx, path)
+	if err != nil {
+		return err
+	}
+	c.write(path, *res)
+	return nil
+}
+
+// Invalidate removes the cached listings affected by a change to the dataset or folder at path: the listing of the
+// path itself, the listings of the folders under it, and the listings of the folders above it
+func (c *Client) Invalidate(path string) error {
+	path = key(path)
+	files, err := ioutil.ReadDir(c.dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		cachedPath, err := url.PathUnescape(strings.TrimSuffix(file.Name(), ".json"))
+		if err != nil || !related(cachedPath, path) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(c.dir, file.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteDatasets deletes the dataset at path, and invalidates its listings
+func (c *Client) DeleteDatasets(ctx context.Context, path string, dryRun bool) (*maintenance.DeleteDatasetResponse, error) {
+	res, err := c.API.DeleteDatasets(ctx, path, dryRun)
+	c.invalidate(dryRun, path)
+	return res, err
+}
+
+// DeleteDatasetVersions deletes versions of the dataset at path, and invalidates its listings
+func (c *Client) DeleteDatasetVersions(ctx context.Context, path string, timestamps []time.Time, dryRun bool) (*maintenance.DeleteDatasetResponse, error) {
+	res, err := c.API.DeleteDatasetVersions(ctx, path, timestamps, dryRun)
+	c.invalidate(dryRun, path)
+	return res, err
+}
+
+// MoveDataset moves the dataset at src to dst, and invalidates the listings of both
+func (c *Client) MoveDataset(ctx context.Context, src string, dst string, conflict maintenance.ConflictPolicy, dryRun bool) (*maintenance.MoveDatasetResponse, error) {
+	res, err := c.API.MoveDataset(ctx, src, dst, conflict, dryRun)
+	c.invalidate(dryRun, src, dst)
+	return res, err
+}
+
+// CopyDataset copies the dataset at src to dst, and invalidates the listings of dst
+func (c *Client) CopyDataset(ctx context.Context, src string, dst string, versions []time.Time, progress func(maintenance.CopyProgress)) (*maintenance.CopyDatasetResponse, error) {
+	res, err := c.API.CopyDataset(ctx, src, dst, versions, progress)
+	c.invalidate(false, dst)
+	return res, err
+}
+
+// TrashDataset moves the dataset at path to the trash, and invalidates its listings
+func (c *Client) TrashDataset(ctx context.Context, path string, expiry time.Duration, dryRun bool) (*maintenance.TrashedDataset, error) {
+	res, err := c.API.TrashDataset(ctx, path, expiry, dryRun)
+	c.invalidate(dryRun, path)
+	return res, err
+}
+
+// RestoreDataset restores the dataset at path from the trash, and invalidates the listings of path and dst
+func (c *Client) RestoreDataset(ctx context.Context, path string, dst string, conflict maintenance.ConflictPolicy) (*maintenance.MoveDatasetResponse, error) {
+	res, err := c.API.RestoreDataset(ctx, path, dst, conflict)
+	c.invalidate(false, path, dst)
+	return res, err
+}
+
+// invalidate invalidates the listings of paths, unless nothing was changed in a dry run. The listings are invalidated
+// even if the change failed, since it may have been partially done. Errors are ignored, since stale listings expire
+// anyway.
+func (c *Client) invalidate(dryRun bool, paths ...string) {
+	if dryRun {
+		return
+	}
+	for _, path := range paths {
+		if path != "" {
+			c.Invalidate(path)
+		}
+	}
+}
+
+// serve returns true if the cached listing should be served: if it is fresh, or if stale listings are served while
+// they are revalidated. Live clients never serve cached listings.
+func (c *Client) serve(cached *entry) bool {
+	if cached == nil || c.live {
+		return false
+	}
+	if c.now().Sub(cached.FetchedAt) < c.ttl {
+		return true
+	}
+	if c.revalidate != nil {
+		c.revalidate(cached.Path)
+		return true
+	}
+	return false
+}
+
+// read returns the cached listing of path, or nil if it is not cached (or can not be read)
+func (c *Client) read(path string) *entry {
+	data, err := ioutil.ReadFile(c.filename(path))
+	if err != nil {
+		return nil
+	}
+	var cached entry
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil
+	}
+	return &cached
+}
+
+// write caches the listing of path. The file is replaced atomically, since other dapla processes may read it at the
+// same time. Errors are ignored, since the listing can be listed again.
+func (c *Client) write(path string, datasets maintenance.ListDatasetResponse) {
+	data, err := json.Marshal(entry{Path: key(path), FetchedAt: c.now(), Datasets: datasets})
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return
+	}
+	file, err := ioutil.TempFile(c.dir, ".listing-*")
+	if err != nil {
+		return
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), c.filename(path))
+	}
+	if err != nil {
+		os.Remove(file.Name())
+	}
+}
+
+// filename returns the file holding the cached listing of path
+func (c *Client) filename(path string) string {
+	return filepath.Join(c.dir, url.PathEscape(key(path))+".json")
+}
+
+// key normalizes path, so that e.g. /skatt, /skatt/ share a listing, as do the root folder and the empty path
+func key(path string) string {
+	if path = strings.TrimSuffix(path, "/"); path == "" {
+		return "/"
+	}
+	return path
+}
+
+// related returns true if a or b is the same as, or a folder above, the other
+func related(a string, b string) bool {
+	return maintenance.IsWithin(a, b) || maintenance.IsWithin(b, a)
+}
+
+// isOffline returns true if err means that the API could not be reached, rather than an error response from it
+func isOffline(ctx context.Context, err error) bool {
+	_, isHTTPError := err.(*maintenance.HTTPError)
+	return !isHTTPError && ctx.Err() == nil
+}
+
+func iterate(elements maintenance.ListDatasetResponse, fn maintenance.IterateFunc) error {
+	for _, element := range elements {
+		if err := fn(element); err != nil {
+			return err
+		}
+	}
+	return nil
+}
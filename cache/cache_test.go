@@ -0,0 +1,217 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/statisticsnorway/dapla-cli/devserver"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"github.com/stretchr/testify/assert"
+)
+
+// testServer serves the seeded catalog of the dev server, and counts the requests
+type testServer struct {
+	*httptest.Server
+	requests int
+}
+
+func newTestServer(t *testing.T) *testServer {
+	handler := devserver.New(devserver.SeedCatalog()).MaintenanceHandler()
+	server := &testServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.requests++
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// clock is a fake clock, starting at the time of the fake server
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func newTestClient(t *testing.T, server *testServer) (*Client, *clock) {
+	clock := &clock{now: time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)}
+	client := New(maintenance.New(server.URL), Dir(t.TempDir(), server.URL, "user"), WithTTL(time.Minute), WithClock(clock.Now))
+	return client, clock
+}
+
+func paths(res *maintenance.ListDatasetResponse) []string {
+	var paths []string
+	for _, element := range *res {
+		paths = append(paths, element.Path)
+	}
+	return paths
+}
+
+func TestClient_ListDatasets(t *testing.T) {
+	server := newTestServer(t)
+	client, clock := newTestClient(t, server)
+	ctx := context.Background()
+
+	for _, path := range []string{"/skatt/person", "/skatt/person/"} {
+		res, err := client.ListDatasets(ctx, path)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"/skatt/person/formue", "/skatt/person/inntekt"}, paths(res))
+	}
+	assert.Equal(t, 1, server.requests)
+
+	// Stale listings are listed again
+	clock.now = clock.now.Add(time.Minute)
+	_, err := client.ListDatasets(ctx, "/skatt/person")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, server.requests)
+
+	// Errors from the server are not cached
+	_, err = client.ListDatasets(ctx, "/nope")
+	assert.Error(t, err)
+	_, err = client.ListDatasets(ctx, "/nope")
+	assert.Error(t, err)
+	assert.Equal(t, 4, server.requests)
+}
+
+func TestClient_IterateDatasets(t *testing.T) {
+	server := newTestServer(t)
+	client, _ := newTestClient(t, server)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		var iterated []string
+		err := client.IterateDatasets(ctx, "/skatt", func(element maintenance.ListDatasetElement) error {
+			iterated = append(iterated, element.Path)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"/skatt/2020", "/skatt/person"}, iterated)
+	}
+	assert.Equal(t, 1, server.requests)
+
+	res, err := client.ListDatasets(ctx, "/skatt")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/skatt/2020", "/skatt/person"}, paths(res))
+	assert.Equal(t, 1, server.requests)
+}
+
+func TestClient_Offline(t *testing.T) {
+	server := newTestServer(t)
+	client, clock := newTestClient(t, server)
+	ctx := context.Background()
+
+	_, err := client.ListDatasets(ctx, "/skatt")
+	assert.NoError(t, err)
+	server.Close()
+	clock.now = clock.now.Add(time.Hour)
+
+	res, err := client.ListDatasets(ctx, "/skatt")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/skatt/2020", "/skatt/person"}, paths(res))
+	_, err = client.ListDatasets(ctx, "/skatt/person")
+	assert.Error(t, err)
+}
+
+func TestClient_Live(t *testing.T) {
+	server := newTestServer(t)
+	client, _ := newTestClient(t, server)
+	live := client.Live()
+	ctx := context.Background()
+
+	// Live clients list every time, and cache the listings for other clients
+	for i := 0; i < 2; i++ {
+		res, err := live.ListDatasets(ctx, "/skatt")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"/skatt/2020", "/skatt/person"}, paths(res))
+	}
+	assert.Equal(t, 2, server.requests)
+	_, err := client.ListDatasets(ctx, "/skatt")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, server.requests)
+
+	// The cached listings are not used when offline
+	server.Close()
+	_, err = live.ListDatasets(ctx, "/skatt")
+	assert.Error(t, err)
+	err = live.IterateDatasets(ctx, "/skatt", func(element maintenance.ListDatasetElement) error {
+		return nil
+	})
+	assert.Error(t, err)
+	_, err = live.Cached().ListDatasets(ctx, "/skatt")
+	assert.NoError(t, err)
+}
+
+func TestClient_StaleWhileRevalidate(t *testing.T) {
+	server := newTestServer(t)
+	client, clock := newTestClient(t, server)
+	ctx := context.Background()
+
+	var revalidated []string
+	stale := client.StaleWhileRevalidate(func(path string) {
+		revalidated = append(revalidated, path)
+	})
+
+	// Listings that are not cached are listed right away
+	_, err := stale.ListDatasets(ctx, "/skatt/")
+	assert.NoError(t, err)
+	_, err = stale.ListDatasets(ctx, "/skatt")
+	assert.NoError(t, err)
+	assert.Empty(t, revalidated)
+
+	clock.now = clock.now.Add(time.Hour)
+	res, err := stale.ListDatasets(ctx, "/skatt")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/skatt/2020", "/skatt/person"}, paths(res))
+	assert.Equal(t, []string{"/skatt"}, revalidated)
+	assert.Equal(t, 1, server.requests)
+
+	assert.NoError(t, client.Refresh(ctx, "/skatt"))
+	_, err = stale.ListDatasets(ctx, "/skatt")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/skatt"}, revalidated)
+	assert.Equal(t, 2, server.requests)
+}
+
+func TestClient_Invalidate(t *testing.T) {
+	server := newTestServer(t)
+	client, _ := newTestClient(t, server)
+	ctx := context.Background()
+
+	list := func(paths ...string) {
+		for _, path := range paths {
+			_, err := client.ListDatasets(ctx, path)
+			assert.NoError(t, err)
+		}
+	}
+	list("/", "/skatt", "/skatt/person", "/skatt/2020", "/raw")
+	assert.Equal(t, 5, server.requests)
+
+	// Moving a dataset invalidates the folders above the source and the destination, but not /raw
+	_, err := client.MoveDataset(ctx, "/skatt/person/formue", "/skatt/2020/formue", maintenance.ConflictFail, false)
+	assert.NoError(t, err)
+	list("/", "/skatt", "/skatt/person", "/skatt/2020", "/raw")
+	assert.Equal(t, 6+4, server.requests)
+
+	// Dry runs do not change anything
+	_, err = client.TrashDataset(ctx, "/skatt/2020/formue", time.Hour, true)
+	assert.NoError(t, err)
+	list("/skatt/2020")
+	assert.Equal(t, 11, server.requests)
+
+	_, err = client.TrashDataset(ctx, "/skatt/2020/formue", time.Hour, false)
+	assert.NoError(t, err)
+	res, err := client.ListDatasets(ctx, "/skatt/2020")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/skatt/2020/inntekt"}, paths(res))
+}
+
+func TestDir(t *testing.T) {
+	assert.Equal(t, Dir("/cache", "http://api", "ola"), Dir("/cache", "http://api", "ola"))
+	assert.NotEqual(t, Dir("/cache", "http://api", "ola"), Dir("/cache", "http://api", "kari"))
+	assert.NotEqual(t, Dir("/cache", "http://api", "ola"), Dir("/cache", "http://other", "ola"))
+}
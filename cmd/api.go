@@ -6,7 +6,6 @@ import (
 	"os"
 	"strings"
 
-	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
@@ -16,14 +15,8 @@ const (
 	APINamePseudoSvc          = "dapla-pseudo-service"
 )
 
-func apiURLOf(apiName string) string {
-	var apiURL, err = apiURLOrError(apiName)
-	cobra.CheckErr(err)
-	return apiURL
-}
-
-func apiURLOrError(apiName string) (string, error) {
-	apiURLs := viper.GetStringMapString("apis")
+func apiURLOrError(config *viper.Viper, apiName string) (string, error) {
+	apiURLs := config.GetStringMapString("apis")
 	if apiURLs == nil {
 		return "", fmt.Errorf("unable to determine API URLs from config")
 	}
@@ -44,14 +37,23 @@ func apiURLOrError(apiName string) (string, error) {
 	return apiURL, nil
 }
 
-func allAPIUrls() map[string]string {
-	return map[string]string{
-		APINameDataMaintenanceSvc: apiURLOf(APINameDataMaintenanceSvc),
-		APINamePseudoSvc:          apiURLOf(APINamePseudoSvc),
+func allAPIUrls(config *viper.Viper) (map[string]string, error) {
+	apiURLs := map[string]string{}
+	for _, apiName := range []string{APINameDataMaintenanceSvc, APINamePseudoSvc} {
+		apiURL, err := apiURLOrError(config, apiName)
+		if err != nil {
+			return nil, err
+		}
+		apiURLs[apiName] = apiURL
 	}
+	return apiURLs, nil
 }
 
-func allAPIUrlsString() string {
-	apis, _ := json.MarshalIndent(allAPIUrls(), "", "\t")
-	return string(apis)
+func allAPIUrlsString(config *viper.Viper) (string, error) {
+	apiURLs, err := allAPIUrls(config)
+	if err != nil {
+		return "", err
+	}
+	apis, _ := json.MarshalIndent(apiURLs, "", "\t")
+	return string(apis), nil
 }
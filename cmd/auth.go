@@ -1,15 +1,21 @@
 package cmd
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
+	"sync"
 
 	errors2 "github.com/pkg/errors"
-	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
 )
 
 const (
@@ -21,14 +27,14 @@ const (
 
 // authToken returns the users JWT token either by using the provided bearer token
 // or retrieving it from the jupyter environment
-func authTokenOrError() (string, error) {
-	if viper.GetBool(CFGJupyter) && viper.GetString(CFGAuthToken) != "" {
+func authTokenOrError(config *viper.Viper) (string, error) {
+	if config.GetBool(CFGJupyter) && config.GetString(CFGAuthToken) != "" {
 		return "", errors2.New("cannot use both --jupyter and --authtoken")
 	}
 
 	switch {
 
-	case viper.GetBool(CFGJupyter):
+	case config.GetBool(CFGJupyter):
 		apiURL := os.Getenv(jupyterHUBTokenURL)
 		apiToken := os.Getenv(jupyterAPIToken)
 		if apiToken == "" || apiURL == "" {
@@ -36,20 +42,26 @@ func authTokenOrError() (string, error) {
 		}
 		return fetchJupyterToken(apiURL, apiToken)
 
-	case viper.GetString(CFGAuthToken) != "":
-		return viper.GetString(CFGAuthToken), nil
+	case config.GetString(CFGAuthToken) != "":
+		return config.GetString(CFGAuthToken), nil
 
 	default:
 		return "", errors2.New("Unable to find auth token. Either retrieve this from jupyter (--jupyter) or provide an auth token via --authtoken, $AUTHTOKEN (env) or in the .dapla-cli.yml config file")
 	}
 }
 
-// requiredAuthToken returns the users JWT token or panics if it could not be retrieved
-func authToken() string {
-	var authToken, err = authTokenOrError()
-	cobra.CheckErr(err)
-
-	return authToken
+// authTokenSource returns a token source that retrieves the users JWT token (see authTokenOrError) when first
+// called, and then reuses it
+func authTokenSource(config *viper.Viper) maintenance.TokenSource {
+	var once sync.Once
+	var token string
+	var err error
+	return func(ctx context.Context) (string, error) {
+		once.Do(func() {
+			token, err = authTokenOrError(config)
+		})
+		return token, err
+	}
 }
 
 // fetchJupyterToken retrieves the users JWT token from the jupyter environment
@@ -82,3 +94,34 @@ func fetchJupyterToken(apiURL, apiToken string) (string, error) {
 
 	return data["access_token"].(string), nil
 }
+
+// cacheUser returns the user that cached listings are kept for, without retrieving the auth token from jupyter, so
+// that cached listings can be used offline
+func cacheUser(config *viper.Viper) string {
+	if config.GetBool(CFGJupyter) {
+		return os.Getenv("JUPYTERHUB_USER")
+	}
+	return tokenUser(config.GetString(CFGAuthToken))
+}
+
+// tokenUser returns the user of a JWT token, taken from the claims without verifying the token. Tokens that are
+// not JWT tokens, or do not name the user, are identified by a hash of the token instead.
+func tokenUser(token string) string {
+	if parts := strings.Split(token, "."); len(parts) == 3 {
+		var claims struct {
+			PreferredUsername string `json:"preferred_username"`
+			Email             string `json:"email"`
+			Subject           string `json:"sub"`
+		}
+		payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+		if err == nil && json.Unmarshal(payload, &claims) == nil {
+			for _, user := range []string{claims.PreferredUsername, claims.Email, claims.Subject} {
+				if user != "" {
+					return user
+				}
+			}
+		}
+	}
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
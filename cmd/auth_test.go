@@ -26,3 +26,12 @@ func TestClient_fetchJupyterToken(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, token, "the access token")
 }
+
+func TestTokenUser(t *testing.T) {
+	// {"sub":"1234","preferred_username":"ola.nordmann"}
+	assert.Equal(t, "ola.nordmann", tokenUser("eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxMjM0IiwicHJlZmVycmVkX3VzZXJuYW1lIjoib2xhLm5vcmRtYW5uIn0.c2ln"))
+	// {"sub":"1234"}
+	assert.Equal(t, "1234", tokenUser("eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxMjM0In0.c2ln"))
+	assert.Len(t, tokenUser("not a jwt"), 16)
+	assert.NotEqual(t, tokenUser("token"), tokenUser("another token"))
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/statisticsnorway/dapla-cli/browse"
+	"github.com/statisticsnorway/dapla-cli/export"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"github.com/statisticsnorway/dapla-cli/retention"
+	"github.com/statisticsnorway/dapla-cli/shell"
+)
+
+func newBrowseCommand(env *Env) *cobra.Command {
+	var password string
+	targetFileType := "json"
+	browseCommand := &cobra.Command{
+		Use:   "browse [PATH]",
+		Short: "Browse the datasets and folders in a full-screen view",
+		Long: `The browse command shows the datasets and folders under PATH (default /) full-screen, with the details of the
+selected dataset or folder. The keys are:
+
+  ↑ ↓ (or k j)           select the previous or next dataset or folder
+  → Enter (or l)         open the selected folder
+  ← Backspace (or h)     go back to the parent folder
+  d                      mark the selected dataset for deletion
+  e                      mark the selected dataset for export
+  x                      delete and export the marked datasets, after confirming
+  r                      reload the folder
+  q                      quit without deleting or exporting anything
+
+Marked datasets are moved to the trash, like with the rm command, and exported with the password given by
+--password, like with the export command. Protected datasets can not be marked for deletion.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			folder := env.absPath("/")
+			if len(args) > 0 {
+				folder = env.absPath(args[0])
+			}
+
+			client, err := env.cachedMaintenance()
+			if err != nil {
+				return err
+			}
+			protected, err := newProtection(cmd.Context(), env, client, false)
+			if err != nil {
+				return err
+			}
+
+			browser := browse.New(client)
+			browser.CanMark = func(path string, mark browse.Mark) error {
+				if pattern := protected.match(path); mark == browse.MarkDelete && pattern != "" {
+					return fmt.Errorf("%s is protected by %s", path, pattern)
+				}
+				if mark == browse.MarkExport && password == "" {
+					return errors.New("use --password to export datasets")
+				}
+				return nil
+			}
+			browser.Question = browseQuestion
+			if err := browser.Open(cmd.Context(), folder); err != nil {
+				return reportAPIError(env, err)
+			}
+
+			confirmed, err := runBrowser(cmd.Context(), env, browser)
+			if err != nil || !confirmed {
+				return err
+			}
+
+			r := remover{
+				ctx:       cmd.Context(),
+				env:       env,
+				client:    client,
+				protected: protected,
+				summary: deleteSummary{
+					Datasets: []*maintenance.DeleteDatasetResponse{},
+					Trashed:  []*maintenance.TrashedDataset{},
+				},
+			}
+			if r.expiry, err = retention.ParseDuration(env.Config.GetString(CFGTrashExpiry)); err != nil {
+				return err
+			}
+			for _, path := range browser.Marked(browse.MarkDelete) {
+				if err := r.delete(path); err != nil {
+					return err
+				}
+			}
+			printDeleteSummary(&r.summary, env.Stdout)
+
+			return exportMarked(cmd.Context(), env, browser.Marked(browse.MarkExport), export.Request{
+				ColumnSelectors:   []string{},
+				TargetContentType: contentTypeMap[targetFileType],
+				TargetPassword:    password,
+				PseudoRules:       []export.PseudoRule{},
+			})
+		},
+		ValidArgsFunction: completePath(env),
+	}
+	browseCommand.Flags().StringVarP(&password, "password", "p", "", "password used to protect the archives of exported datasets")
+	browseCommand.Flags().VarP((*fileType)(&targetFileType), "target-filetype", "t",
+		"the filetype of exported datasets ("+strings.Join(fileTypes(), ", ")+")")
+	browseCommand.RegisterFlagCompletionFunc("target-filetype", completeFileType)
+	return browseCommand
+}
+
+// browseQuestion asks the user to confirm the marks of the browser, e.g. "Delete 2 datasets and export 1 dataset?"
+func browseQuestion(marked map[browse.Mark]int) string {
+	var actions []string
+	for _, mark := range []browse.Mark{browse.MarkDelete, browse.MarkExport} {
+		if n := marked[mark]; n > 0 {
+			actions = append(actions, fmt.Sprintf("%s %d %s", mark, n, pluralize("dataset", n)))
+		}
+	}
+	if len(actions) == 0 {
+		return "Nothing is marked, continue?"
+	}
+	question := strings.Join(actions, " and ")
+	return strings.ToUpper(question[:1]) + question[1:] + "?"
+}
+
+// runBrowser runs browser on the stderr of env, reading the keys from stdin, and returns true if the user confirmed
+// the marks. On a terminal the browser takes up the whole screen, which is restored afterwards. Otherwise, e.g. in
+// tests, the keys are read as they are, and the browser is drawn with the default size.
+func runBrowser(ctx context.Context, env *Env, browser *browse.Browser) (bool, error) {
+	if file, ok := env.Stdin.(*os.File); ok {
+		restore, err := shell.MakeRaw(file)
+		if err == nil {
+			defer restore()
+		}
+	}
+	if file, ok := env.Stderr.(*os.File); ok {
+		if width, height, err := shell.Size(file); err == nil {
+			browser.Width, browser.Height = width, height
+		}
+	}
+
+	// Switch to the alternate screen, and hide the cursor
+	fmt.Fprint(env.Stderr, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(env.Stderr, "\x1b[?25h\x1b[?1049l")
+	return browser.Run(ctx, env.prompts(), env.Stderr)
+}
+
+// exportMarked exports the datasets with the paths given, using the other parameters of req, and prints the URIs of
+// the archives
+func exportMarked(ctx context.Context, env *Env, paths []string, req export.Request) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	client, err := env.Export()
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		req.DatasetPath = path
+		spinner := env.spinner("Exporting " + path)
+		res, err := client.Export(ctx, req)
+		spinner.Stop()
+		if err != nil {
+			return fmt.Errorf("could not export %s: %v", path, err)
+		}
+		fmt.Fprintln(env.Stdout, res.TargetURI)
+	}
+	return nil
+}
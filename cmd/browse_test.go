@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/statisticsnorway/dapla-cli/browse"
+	"github.com/statisticsnorway/dapla-cli/export"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeExporter records the export requests
+type fakeExporter struct {
+	requests []export.Request
+}
+
+func (f *fakeExporter) Export(_ context.Context, req export.Request) (*export.Response, error) {
+	f.requests = append(f.requests, req)
+	return &export.Response{TargetURI: "gs://export" + req.DatasetPath + ".zip"}, nil
+}
+
+func TestRunBrowse(t *testing.T) {
+	api := newTestAPI()
+	api.protected = []string{"/skatt/2020/**"}
+	exporter := &fakeExporter{}
+	env, stdout, stderr := newTestEnv(api, "jde"+"g\rd\x7f"+"xy")
+	env.Export = func() (Exporter, error) {
+		return exporter, nil
+	}
+
+	// Mark formue for deletion and inntekt for export, try to mark the protected /skatt/2020/inntekt, and confirm
+	assert.Equal(t, 0, Run(context.Background(), env, []string{"browse", "--password", "secret", "-t", "csv", "/skatt"}))
+	assert.Equal(t, []string{"trash /skatt/formue"}, api.calls)
+	assert.Contains(t, stderr.String(), "/skatt/2020/inntekt is protected by /skatt/2020/**")
+	assert.Contains(t, stdout.String(), "Dataset /skatt/formue (0 versions, 0B) moved to trash")
+	assert.Contains(t, stdout.String(), "gs://export/skatt/inntekt.zip\n")
+	if assert.Len(t, exporter.requests, 1) {
+		assert.Equal(t, "/skatt/inntekt", exporter.requests[0].DatasetPath)
+		assert.Equal(t, "text/csv", exporter.requests[0].TargetContentType)
+		assert.Equal(t, "secret", exporter.requests[0].TargetPassword)
+	}
+}
+
+func TestRunBrowse_Quit(t *testing.T) {
+	api := newTestAPI()
+	env, stdout, stderr := newTestEnv(api, "jdq")
+
+	assert.Equal(t, 0, Run(context.Background(), env, []string{"browse", "/skatt"}))
+	assert.Empty(t, api.calls)
+	assert.Empty(t, stdout.String())
+	assert.Contains(t, stderr.String(), "dapla browse: /skatt")
+
+	env, _, stderr = newTestEnv(api, "je")
+	assert.Equal(t, 0, Run(context.Background(), env, []string{"browse", "/skatt"}))
+	assert.Contains(t, stderr.String(), "use --password to export datasets")
+}
+
+func TestBrowseQuestion(t *testing.T) {
+	assert.Equal(t, "Delete 2 datasets and export 1 dataset?",
+		browseQuestion(map[browse.Mark]int{browse.MarkDelete: 2, browse.MarkExport: 1}))
+	assert.Equal(t, "Export 3 datasets?", browseQuestion(map[browse.Mark]int{browse.MarkExport: 3}))
+}
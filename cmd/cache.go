@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/spf13/cobra"
+	"github.com/statisticsnorway/dapla-cli/cache"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"github.com/statisticsnorway/dapla-cli/retention"
+)
+
+func newCacheCommand(env *Env) *cobra.Command {
+	cacheCommand := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the cache of listings",
+		Long: `The listings of folders are cached, so that completing paths is fast and folders can be browsed offline.
+Completion, browse and shell use cached listings for 5 minutes (or as long as the cache-ttl config option says,
+e.g. 1h), or when the data-maintenance service can not be reached. Other commands list folders live and update the
+cache, unless the --cache flag is given. The listings affected by rm, mv, cp and restore are removed from the cache
+right away. Use the --no-cache flag to list folders without the cache.`,
+	}
+	cacheCommand.AddCommand(newCacheClearCommand(env), newCacheRefreshCommand(env))
+	return cacheCommand
+}
+
+func newCacheClearCommand(env *Env) *cobra.Command {
+	return &cobra.Command{
+		Use:   "clear",
+		Short: "Delete all cached listings",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if env.CacheDir == "" {
+				return errors.New("unable to determine the cache directory")
+			}
+			if err := cache.Clear(env.CacheDir); err != nil {
+				return err
+			}
+			fmt.Fprintln(env.Stdout, "Cleared the cache in", env.CacheDir)
+			return nil
+		},
+	}
+}
+
+// newCacheRefreshCommand creates the hidden command that refreshes stale listings in the background, see
+// refreshInBackground
+func newCacheRefreshCommand(env *Env) *cobra.Command {
+	return &cobra.Command{
+		Use:    "refresh [PATH]...",
+		Short:  "List folders again and cache the listings",
+		Hidden: true,
+		Args:   cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := env.Maintenance()
+			if err != nil {
+				return err
+			}
+			cached, ok := client.(*cache.Client)
+			if !ok {
+				return nil
+			}
+			for _, path := range args {
+				if err := cached.Refresh(cmd.Context(), path); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// cachedClient returns client with the listings cached in the cache directory of env, unless caching is turned off
+func cachedClient(env *Env, client maintenance.API, apiURL string) (maintenance.API, error) {
+	if env.CacheDir == "" || env.Config.GetBool(CFGNoCache) {
+		return client, nil
+	}
+	ttl := cache.DefaultTTL
+	if value := env.Config.GetString(CFGCacheTTL); value != "" {
+		var err error
+		if ttl, err = retention.ParseDuration(value); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", CFGCacheTTL, err)
+		}
+	}
+	dir := cache.Dir(env.CacheDir, apiURL, cacheUser(env.Config))
+	cached := cache.New(client, dir, cache.WithTTL(ttl), cache.WithClock(env.Now))
+	if env.Config.GetBool(CFGCache) {
+		return cached, nil
+	}
+	// Commands list folders live, so that they never act on stale listings, but keep the cache up to date
+	return cached.Live(), nil
+}
+
+// cachedMaintenance returns the data-maintenance client of env, serving listings from the cache. It is used by
+// completion, browse and the shell, which list the same folders over and over, and may be used offline.
+func (env *Env) cachedMaintenance() (maintenance.API, error) {
+	client, err := env.Maintenance()
+	if err != nil {
+		return nil, err
+	}
+	if cached, ok := client.(*cache.Client); ok {
+		return cached.Cached(), nil
+	}
+	return client, nil
+}
+
+// staleWhileRevalidate returns a client serving stale listings right away, and refreshing them in the background
+// (once per path), if client caches listings and env can refresh them
+func staleWhileRevalidate(env *Env, client maintenance.API) maintenance.API {
+	cached, ok := client.(*cache.Client)
+	if !ok || env.RefreshInBackground == nil {
+		return client
+	}
+	refreshed := map[string]bool{}
+	return cached.StaleWhileRevalidate(func(path string) {
+		if !refreshed[path] {
+			refreshed[path] = true
+			env.RefreshInBackground(path)
+		}
+	})
+}
+
+// refreshInBackground starts a dapla process that refreshes the cached listing of path. The process carries on
+// after this process exits, which happens right after completing a path. The API URL and the auth token are passed
+// on, since they may have been given as flags.
+func refreshInBackground(env *Env, path string) {
+	executable, err := os.Executable()
+	if err != nil {
+		return
+	}
+	apiURL, err := apiURLOrError(env.Config, APINameDataMaintenanceSvc)
+	if err != nil {
+		return
+	}
+
+	args := []string{"--apis", APINameDataMaintenanceSvc + "=" + apiURL}
+	if env.Config.GetBool(CFGJupyter) {
+		args = append(args, "--jupyter")
+	}
+	refresh := exec.Command(executable, append(args, "cache", "refresh", "--", path)...)
+	// The token is passed in the environment, where other users can not see it
+	refresh.Env = os.Environ()
+	if token := env.Config.GetString(CFGAuthToken); token != "" {
+		refresh.Env = append(refresh.Env, "AUTHTOKEN="+token)
+	}
+	if err := refresh.Start(); err == nil {
+		refresh.Process.Release()
+	}
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/statisticsnorway/dapla-cli/devserver"
+	"github.com/stretchr/testify/assert"
+)
+
+// cacheTest runs dapla commands against a dev server, with the listings cached in a temporary directory
+type cacheTest struct {
+	t         *testing.T
+	url       string
+	cacheDir  string
+	now       time.Time
+	requests  int
+	refreshed []string
+}
+
+func newCacheTest(t *testing.T) *cacheTest {
+	c := &cacheTest{t: t, cacheDir: t.TempDir(), now: testNow}
+	handler := devserver.New(devserver.SeedCatalog()).MaintenanceHandler()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.requests++
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	c.url = server.URL
+	return c
+}
+
+// run runs the dapla command with args, and returns stdout
+func (c *cacheTest) run(args ...string) string {
+	var stdout, stderr bytes.Buffer
+	env := DefaultEnv()
+	env.Stdin = strings.NewReader("")
+	env.Stdout = &stdout
+	env.Stderr = &stderr
+	env.ConfigDir = ""
+	env.CacheDir = c.cacheDir
+	env.Now = func() time.Time {
+		return c.now
+	}
+	env.RefreshInBackground = func(path string) {
+		c.refreshed = append(c.refreshed, path)
+	}
+
+	code := Run(context.Background(), env, append([]string{
+		"--authtoken", "token", "--apis", APINameDataMaintenanceSvc + "=" + c.url,
+	}, args...))
+	assert.Equal(c.t, 0, code, stderr.String())
+	return stdout.String()
+}
+
+func TestRunCache(t *testing.T) {
+	c := newCacheTest(t)
+
+	// ls lists live, and caches the listing for completion
+	assert.Equal(t, "formue\ninntekt\n", c.run("ls", "/skatt/person"))
+	assert.Equal(t, "formue\ninntekt\n", c.run("ls", "/skatt/person"))
+	assert.Equal(t, 2, c.requests)
+	assert.Equal(t, "/skatt/person/formue\n/skatt/person/inntekt\n:6\n", c.run("__complete", "ls", "/skatt/person/"))
+	assert.Equal(t, "formue\ninntekt\n", c.run("--cache", "ls", "/skatt/person"))
+	assert.Equal(t, 2, c.requests)
+
+	// Deleting a dataset invalidates the listing
+	c.run("rm", "--permanent", "/skatt/person/formue")
+	requests := c.requests
+	assert.Equal(t, "inntekt\n", c.run("--cache", "ls", "/skatt/person"))
+	assert.Equal(t, requests+1, c.requests)
+
+	c.run("--no-cache", "--cache", "ls", "/skatt/person")
+	assert.Equal(t, requests+2, c.requests)
+
+	// Stale listings are listed again
+	c.now = c.now.Add(time.Hour)
+	c.run("--cache", "ls", "/skatt/person")
+	assert.Equal(t, requests+3, c.requests)
+
+	assert.Equal(t, "Cleared the cache in "+c.cacheDir+"\n", c.run("cache", "clear"))
+	_, err := os.Stat(c.cacheDir)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCompleteStaleWhileRevalidate(t *testing.T) {
+	c := newCacheTest(t)
+
+	assert.Equal(t, "/skatt/person/formue\n/skatt/person/inntekt\n:6\n", c.run("__complete", "ls", "/skatt/person/"))
+	requests := c.requests
+
+	// Stale listings are completed right away, and refreshed in the background
+	c.now = c.now.Add(time.Hour)
+	assert.Equal(t, "/skatt/person/formue\n/skatt/person/inntekt\n:6\n", c.run("__complete", "ls", "/skatt/person/"))
+	assert.Equal(t, requests, c.requests)
+	assert.Equal(t, []string{"/skatt/person"}, c.refreshed)
+
+	c.run("cache", "refresh", "/skatt/person")
+	assert.Equal(t, requests+1, c.requests)
+}
@@ -1,77 +1,81 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
+	"context"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/statisticsnorway/dapla-cli/maintenance"
 )
 
-var completionCmd = &cobra.Command{
-	Use:   "completion [bash|zsh|fish|powershell]",
-	Short: "Generate completion script",
-	Long: `To load completions:
+func newCompletionCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "completion [bash|zsh|fish|powershell]",
+		Short: "Generate completion script",
+		Long: `To load completions:
 Bash:
-$ source <(yourprogram completion bash)
+$ source <(dapla completion bash)
 # To load completions for each session, execute once:
 Linux:
-  $ yourprogram completion bash > /etc/bash_completion.d/yourprogram
+  $ dapla completion bash > /etc/bash_completion.d/dapla
 MacOS:
-  $ yourprogram completion bash > /usr/local/etc/bash_completion.d/yourprogram
+  $ dapla completion bash > /usr/local/etc/bash_completion.d/dapla
 Zsh:
 # If shell completion is not already enabled in your environment you will need
 # to enable it.  You can execute the following once:
 $ echo "autoload -U compinit; compinit" >> ~/.zshrc
 # To load completions for each session, execute once:
-$ yourprogram completion zsh > "${fpath[1]}/_yourprogram"
+$ dapla completion zsh > "${fpath[1]}/_dapla"
 # You will need to start a new shell for this setup to take effect.
 Fish:
-$ yourprogram completion fish | source
+$ dapla completion fish | source
 # To load completions for each session, execute once:
-$ yourprogram completion fish > ~/.config/fish/completions/yourprogram.fish
+$ dapla completion fish > ~/.config/fish/completions/dapla.fish
 Powershell:
-PS> yourprogram completion powershell | Out-String | Invoke-Expression
+PS> dapla completion powershell | Out-String | Invoke-Expression
 # To load completions for every new session, run:
-PS> yourprogram completion powershell > yourprogram.ps1
+PS> dapla completion powershell > dapla.ps1
 # and source this file from your powershell profile.
 `,
-	DisableFlagsInUseLine: true,
-	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
-	Args:                  cobra.ExactValidArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		switch args[0] {
-		case "bash":
-			cmd.Root().GenBashCompletion(os.Stdout)
-		case "zsh":
-			cmd.Root().GenZshCompletion(os.Stdout)
-		case "fish":
-			cmd.Root().GenFishCompletion(os.Stdout, true)
-		case "powershell":
-			cmd.Root().GenPowerShellCompletion(os.Stdout)
-		}
-	},
+		DisableFlagsInUseLine: true,
+		ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+		Args:                  cobra.ExactValidArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch args[0] {
+			case "bash":
+				return cmd.Root().GenBashCompletion(cmd.OutOrStdout())
+			case "zsh":
+				return cmd.Root().GenZshCompletion(cmd.OutOrStdout())
+			case "fish":
+				return cmd.Root().GenFishCompletion(cmd.OutOrStdout(), true)
+			case "powershell":
+				return cmd.Root().GenPowerShellCompletion(cmd.OutOrStdout())
+			}
+			return nil
+		},
+	}
 }
 
-func init() {
-	rootCmd.AddCommand(completionCmd)
-
-	// Here you will define your flags and configuration settings.
-
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// completionCmd.PersistentFlags().String("foo", "", "A help for foo")
-
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// completionCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+// completePath returns a completion function that completes dataset and folder paths, see doAutoComplete
+func completePath(env *Env) func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return doAutoComplete(env, toComplete)
+	}
 }
 
-// TODO: func doAutoComplete(toComplete string, client * rest.Client) ([]string, cobra.ShellCompDirective) {
-// TODO: func (client * rest.Client) DoAutoComplete(toComplete string) ([]string, cobra.ShellCompDirective) {
-func doAutoComplete(toComplete string) ([]string, cobra.ShellCompDirective) {
-	var client = maintenance.NewClient(apiURLOf(APINameDataMaintenanceSvc), authToken())
+func doAutoComplete(env *Env, toComplete string) ([]string, cobra.ShellCompDirective) {
+	// Relative paths are completed against the working folder of the shell, or the home folder
+	if env.workingDir() != "" && !strings.HasPrefix(toComplete, "/") {
+		return completeRelativePath(env, toComplete)
+	}
+
+	client, err := env.cachedMaintenance()
+	if err != nil {
+		return handleCompleteError("could not create client:", err)
+	}
+	// Complete from the cached listings right away, even if they are stale
+	client = staleWhileRevalidate(env, client)
+	var ctx = context.Background()
 
 	if toComplete == "" {
 		return []string{"/"}, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
@@ -80,9 +84,9 @@ func doAutoComplete(toComplete string) ([]string, cobra.ShellCompDirective) {
 	var res *maintenance.ListDatasetResponse
 
 	if toComplete == "/" {
-		res, err := client.ListDatasets(toComplete)
+		res, err := client.ListDatasets(ctx, toComplete)
 		if err != nil {
-			return handleCompleteError("could not fetch list: %s", err)
+			return handleCompleteError("could not fetch list:", err)
 		}
 
 		return formatCompleteResult(res)
@@ -95,9 +99,9 @@ func doAutoComplete(toComplete string) ([]string, cobra.ShellCompDirective) {
 
 	// Ask for list without last element
 	var parentPath = toComplete[0:strings.LastIndex(toComplete, "/")]
-	res, err := client.ListDatasets(parentPath)
+	res, err = client.ListDatasets(ctx, parentPath)
 	if err != nil {
-		return handleCompleteError("could not fetch list: ", err)
+		return handleCompleteError("could not fetch list:", err)
 	}
 
 	// Check if last element is a valid path / dataset
@@ -105,9 +109,9 @@ func doAutoComplete(toComplete string) ([]string, cobra.ShellCompDirective) {
 	for _, element := range *res {
 		// We have a complete match, ask data-maintenance for elements on that path
 		if toComplete == element.Path {
-			res, err = client.ListDatasets(toComplete)
+			res, err = client.ListDatasets(ctx, toComplete)
 			if err != nil {
-				return handleCompleteError("could not fetch list: ", err)
+				return handleCompleteError("could not fetch list:", err)
 			}
 
 			return formatCompleteResult(res)
@@ -125,6 +129,18 @@ func doAutoComplete(toComplete string) ([]string, cobra.ShellCompDirective) {
 	return formatCompleteResult(&matches)
 }
 
+// completeRelativePath completes a path relative to the working folder of the shell or the home folder, by completing
+// the absolute path and making the completions relative again
+func completeRelativePath(env *Env, toComplete string) ([]string, cobra.ShellCompDirective) {
+	dir := toComplete[:strings.LastIndex(toComplete, "/")+1]
+	absDir := strings.TrimSuffix(env.absPath(dir), "/") + "/"
+	completions, directive := doAutoComplete(env, absDir+toComplete[len(dir):])
+	for i, completion := range completions {
+		completions[i] = dir + strings.TrimPrefix(completion, absDir)
+	}
+	return completions, directive
+}
+
 // Format and set the flags based on the given elements
 func formatCompleteResult(elements *maintenance.ListDatasetResponse) ([]string, cobra.ShellCompDirective) {
 	var suggestions []string
@@ -146,9 +162,10 @@ func formatCompleteResult(elements *maintenance.ListDatasetResponse) ([]string,
 	return suggestions, flags
 }
 
-// Handle the errors from the auto complete method.
+// Handle the errors from the auto complete method. The errors are not printed, since they would garble the command
+// line while pressing TAB, but they are written to the file given by BASH_COMP_DEBUG_FILE, if set.
 func handleCompleteError(message string, err error) ([]string, cobra.ShellCompDirective) {
-	_, _ = fmt.Fprintln(os.Stderr, message, err.Error())
+	cobra.CompDebugln(message+" "+err.Error(), false)
 	return nil, cobra.ShellCompDirectiveError | cobra.ShellCompDirectiveNoFileComp
 }
 
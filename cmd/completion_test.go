@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/statisticsnorway/dapla-cli/devserver"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComplete_FlagValues(t *testing.T) {
+	server := devserver.New(devserver.SeedCatalog())
+	for _, test := range []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"filter fields", []string{"search", "--filter", "type=dataset,s"},
+			"type=dataset,state=\n:6\n"},
+		{"filter values", []string{"search", "--filter", "valuation=s"},
+			"valuation=SENSITIVE\nvaluation=SHIELDED\n:6\n"},
+		{"filter without values", []string{"search", "--filter", "createdBy="},
+			":6\n"},
+		{"pseudo rules path", []string{"export", "--pseudo-rules-path", "/skatt/person/"},
+			"/skatt/person/formue\n/skatt/person/inntekt\n:6\n"},
+		{"pseudo funcs", []string{"export", "/skatt/person/inntekt", "--pseudo-rules", "navn=fpe-"},
+			"navn=fpe-fnr(secret1)\tused by **/fnr in /skatt/person/inntekt\n" +
+				"navn=fpe-anychar(\tformat-preserving encryption of any characters\n" +
+				"navn=fpe-digits(\tformat-preserving encryption of the digits, keeping other characters\n" +
+				"navn=fpe-fnr(\tformat-preserving encryption of national identity numbers\n:6\n"},
+		{"pseudo funcs of last rule", []string{"export", "--pseudo-rules", "fnr=fpe-fnr(secret1),navn=t"},
+			"fnr=fpe-fnr(secret1),navn=tink-daead(\tdeterministic authenticated encryption\n:6\n"},
+		{"pseudo patterns", []string{"export", "--pseudo-rules", "fn"},
+			":6\n"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			code, stdout := runAgainstDevServer(t, server, append([]string{"__complete"}, test.args...)...)
+			assert.Equal(t, 0, code)
+			assert.Equal(t, test.expected, stdout)
+		})
+	}
+}
+
+func TestComplete_HomeFolder(t *testing.T) {
+	server := httptest.NewServer(devserver.New(devserver.SeedCatalog()).MaintenanceHandler())
+	defer server.Close()
+	env, stdout, _ := newTestEnv(maintenance.New(server.URL), "")
+	env.Config.Set(CFGHomeFolder, "/skatt")
+
+	code := Run(context.Background(), env, []string{"__complete", "ls", "person/in"})
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "person/inntekt\n:4\n", stdout.String())
+
+	stdout.Reset()
+	code = Run(context.Background(), env, []string{"__complete", "ls", "/skatt/p"})
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "/skatt/person/\n:6\n", stdout.String())
+}
+
+func TestComplete_SilentErrors(t *testing.T) {
+	env, stdout, stderr := newTestEnv(nil, "")
+	env.Maintenance = func() (maintenance.API, error) {
+		return nil, errors.New("the data-maintenance service is offline")
+	}
+
+	code := Run(context.Background(), env, []string{"__complete", "ls", "/skatt/"})
+	assert.Equal(t, 0, code)
+	assert.Equal(t, ":5\n", stdout.String())
+	assert.NotContains(t, stderr.String(), "offline")
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+)
+
+func newCpCommand(env *Env) *cobra.Command {
+	var recursive, latest bool
+	var version string
+	cpCommand := &cobra.Command{
+		Use:   "cp [SOURCE] [DESTINATION]",
+		Short: "Copy dataset(s)",
+		Long: `The cp command copies the dataset at SOURCE to DESTINATION. By default all versions are copied, use --latest
+or --version to only copy a single version. Use --recursive to copy all datasets under the folder SOURCE to the
+folder DESTINATION, keeping the folder structure. Each copied file is reported as the copy progresses.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if latest && version != "" {
+				return errors.New("cannot use both --latest and --version")
+			}
+			if latest {
+				version = "latest"
+			}
+
+			client, err := env.Maintenance()
+			if err != nil {
+				return err
+			}
+
+			src, dst := env.absPath(args[0]), env.absPath(args[1])
+			copies := [][2]string{{src, dst}}
+			if recursive {
+				copies, err = planFolderTargets(cmd.Context(), client, src, dst)
+				if err != nil {
+					return err
+				}
+			}
+
+			var files int
+			var size uint64
+			for _, c := range copies {
+				res, err := copyDataset(cmd.Context(), client, c[0], c[1], version, env.Stderr)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(env.Stdout, "Copied %s -> %s (%d %s, %s)\n", res.Source, res.Destination,
+					res.Files, pluralize("file", res.Files), formatSize(res.Size, true))
+				files += res.Files
+				size += res.Size
+			}
+
+			if len(copies) > 1 {
+				fmt.Fprintf(env.Stdout, "Copied %d datasets (%d %s, %s)\n", len(copies), files, pluralize("file", files), formatSize(size, true))
+			}
+			return nil
+		},
+		ValidArgsFunction: completePath(env),
+	}
+	cpCommand.Flags().BoolVarP(&recursive, "recursive", "r", false, "copy all datasets under a folder")
+	cpCommand.Flags().BoolVar(&latest, "latest", false, "only copy the latest version")
+	cpCommand.Flags().StringVar(&version, "version", "", "only copy a specific version (timestamp or latest~N)")
+	return cpCommand
+}
+
+// copyDataset copies the versions of the dataset src selected by version (all versions if empty) to dst, and
+// writes a progress line for each copied file to progress
+func copyDataset(ctx context.Context, client maintenance.API, src string, dst string, version string, progress io.Writer) (*maintenance.CopyDatasetResponse, error) {
+	versions, err := client.ListVersions(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := versions.Versions
+	if version != "" {
+		selector, err := maintenance.ParseVersionSelector(version)
+		if err != nil {
+			return nil, err
+		}
+		timestamp, err := selector.Resolve(versions.Timestamps())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", src, err)
+		}
+		selected = []maintenance.Version{*versions.Version(timestamp)}
+	}
+
+	total := 0
+	var timestamps []time.Time
+	for _, v := range selected {
+		total += len(v.Files)
+		timestamps = append(timestamps, v.Timestamp)
+	}
+	if version == "" {
+		// Copy all versions, including any versions created after we listed them
+		timestamps = nil
+	}
+
+	copied := 0
+	return client.CopyDataset(ctx, src, dst, timestamps, func(file maintenance.CopyProgress) {
+		copied++
+		fmt.Fprintf(progress, "[%d/%d] %s -> %s (%s)\n", copied, total, file.Source, file.Destination, formatSize(file.Size, true))
+	})
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/andreyvit/diff"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestCopyDataset(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Get("/api/v1/versions/foo/bar").
+		Reply(http.StatusOK).
+		BodyString(`{"datasetPath": "/foo/bar", "versions": [
+			{"timestamp": "2021-01-01T00:00:00Z", "files": [{"uri": "gs://bucket/foo/bar/1609459200000/file1", "size": 5}]},
+			{"timestamp": "2021-04-30T00:00:00Z", "files": [
+				{"uri": "gs://bucket/foo/bar/1619740800000/file1", "size": 1024},
+				{"uri": "gs://bucket/foo/bar/1619740800000/file2", "size": 2048}
+			]}
+		]}`)
+	gock.New("http://server.com").
+		Post("/api/v1/copy/foo/bar").
+		MatchParam("destination", "^tmp/bar$").
+		MatchParam("versions", "^1619740800000$").
+		Reply(http.StatusOK).
+		BodyString(`{"source": "gs://bucket/foo/bar/1619740800000/file1", "destination": "gs://bucket/tmp/bar/1619740800000/file1", "size": 1024}
+{"source": "gs://bucket/foo/bar/1619740800000/file2", "destination": "gs://bucket/tmp/bar/1619740800000/file2", "size": 2048}`)
+
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	client := maintenance.NewClient("http://server.com", "a secret secret")
+
+	var progress bytes.Buffer
+	res, err := copyDataset(context.Background(), client, "foo/bar", "tmp/bar", "latest", &progress)
+	if err != nil {
+		t.Fatalf("Got error %v", err)
+	}
+	if res.Files != 2 || res.Size != 3072 {
+		t.Errorf("Unexpected response %v", res)
+	}
+
+	expected := "[1/2] gs://bucket/foo/bar/1619740800000/file1 -> gs://bucket/tmp/bar/1619740800000/file1 (1.0K)\n" +
+		"[2/2] gs://bucket/foo/bar/1619740800000/file2 -> gs://bucket/tmp/bar/1619740800000/file2 (2.0K)\n"
+	if actual := progress.String(); actual != expected {
+		t.Errorf("Result not as expected:\n%v", diff.LineDiff(expected, actual))
+	}
+}
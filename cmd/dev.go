@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/statisticsnorway/dapla-cli/devserver"
+)
+
+func newDevCommand(env *Env) *cobra.Command {
+	devCommand := &cobra.Command{
+		Use:   "dev",
+		Short: "Tools for developing the dapla command",
+	}
+	devCommand.AddCommand(newDevServeCommand(env))
+	return devCommand
+}
+
+func newDevServeCommand(env *Env) *cobra.Command {
+	var catalogFile string
+	var maintenanceAddr string
+	var pseudoAddr string
+	serveCommand := &cobra.Command{
+		Use:   "serve",
+		Short: "Run fake data-maintenance and pseudo services for local development",
+		Long: `The serve command runs in-memory stand-ins for the data-maintenance service and the export endpoint of the
+dapla-pseudo-service, so that the dapla command can be run without access to the real services. By default they
+listen on the ports used by .dapla-cli-localdev.yml, e.g. in another terminal:
+
+  dapla --config .dapla-cli-localdev.yml ls /skatt
+
+The services start out with the datasets of a seeded catalog, or the catalog given by --catalog (see
+devserver.Catalog for the format). Changes such as deleted and trashed datasets are kept in memory until the
+services are stopped. Exports are validated and logged, but nothing is exported. Searching and copying is not
+supported.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			catalog := devserver.SeedCatalog()
+			if catalogFile != "" {
+				var err error
+				if catalog, err = devserver.LoadCatalog(catalogFile); err != nil {
+					return err
+				}
+			}
+			server := devserver.New(catalog)
+			server.Now = env.Now
+			return serveDev(cmd.Context(), env, server, maintenanceAddr, pseudoAddr)
+		},
+	}
+	serveCommand.Flags().StringVar(&catalogFile, "catalog", "", "YAML file with the datasets to serve (default is a seeded catalog)")
+	serveCommand.Flags().StringVar(&maintenanceAddr, "maintenance-addr", "localhost:10200", "address of the fake data-maintenance service")
+	serveCommand.Flags().StringVar(&pseudoAddr, "pseudo-addr", "localhost:30950", "address of the fake dapla-pseudo-service")
+	return serveCommand
+}
+
+// serveDev serves the fake services of server on their addresses until ctx is cancelled. The URLs of the services are
+// printed on stdout in the format of the apis config option, and the requests they handle on stderr.
+func serveDev(ctx context.Context, env *Env, server *devserver.Server, maintenanceAddr string, pseudoAddr string) error {
+	services := []struct {
+		name    string
+		addr    string
+		handler http.Handler
+	}{
+		{APINameDataMaintenanceSvc, maintenanceAddr, server.MaintenanceHandler()},
+		{APINamePseudoSvc, pseudoAddr, server.PseudoHandler()},
+	}
+
+	errs := make(chan error, len(services))
+	var httpServers []*http.Server
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		for _, httpServer := range httpServers {
+			httpServer.Shutdown(shutdownCtx)
+		}
+	}()
+
+	var logMu sync.Mutex
+	fmt.Fprintln(env.Stdout, "apis:")
+	for _, service := range services {
+		listener, err := net.Listen("tcp", service.addr)
+		if err != nil {
+			return err
+		}
+		httpServer := &http.Server{Handler: logRequests(env, &logMu, service.handler)}
+		httpServers = append(httpServers, httpServer)
+		go func() {
+			errs <- httpServer.Serve(listener)
+		}()
+		fmt.Fprintf(env.Stdout, "  %s: %s\n", service.name, listenerURL(listener))
+	}
+	fmt.Fprintln(env.Stderr, "Press Ctrl+C to stop")
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-errs:
+		return err
+	}
+}
+
+// statusRecorder records the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// logRequests prints the method, URL and response status of each request handled by handler on stderr, holding mu
+// while printing
+func logRequests(env *Env, mu *sync.Mutex, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(recorder, r)
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(env.Stderr, "%s %s %d\n", r.Method, r.URL.RequestURI(), recorder.status)
+	})
+}
+
+// listenerURL returns the URL of an HTTP server listening on listener
+func listenerURL(listener net.Listener) string {
+	addr := listener.Addr().(*net.TCPAddr)
+	host := addr.IP.String()
+	if addr.IP.IsUnspecified() || addr.IP.IsLoopback() {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, strconv.Itoa(addr.Port))
+}
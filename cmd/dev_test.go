@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/statisticsnorway/dapla-cli/devserver"
+	"github.com/stretchr/testify/assert"
+)
+
+// runAgainstDevServer runs the dapla command with args, using the real API clients against the fake services of
+// server. Returns the exit code and stdout.
+func runAgainstDevServer(t *testing.T, server *devserver.Server, args ...string) (int, string) {
+	maintenanceServer := httptest.NewServer(server.MaintenanceHandler())
+	defer maintenanceServer.Close()
+	pseudoServer := httptest.NewServer(server.PseudoHandler())
+	defer pseudoServer.Close()
+
+	var stdout, stderr bytes.Buffer
+	env := DefaultEnv()
+	env.Stdin = strings.NewReader("")
+	env.Stdout = &stdout
+	env.Stderr = &stderr
+	env.ConfigDir = ""
+	env.CacheDir = ""
+	env.Now = func() time.Time {
+		return testNow
+	}
+
+	code := Run(context.Background(), env, append([]string{
+		"--authtoken", "token",
+		"--apis", APINameDataMaintenanceSvc + "=" + maintenanceServer.URL + "," + APINamePseudoSvc + "=" + pseudoServer.URL,
+	}, args...))
+	if stderr.Len() > 0 {
+		t.Log(stderr.String())
+	}
+	return code, stdout.String()
+}
+
+func TestRunAgainstDevServer(t *testing.T) {
+	server := devserver.New(devserver.SeedCatalog())
+
+	code, stdout := runAgainstDevServer(t, server, "ls", "/skatt/person")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "formue\ninntekt\n", stdout)
+
+	code, stdout = runAgainstDevServer(t, server, "rm", "--permanent", "/skatt/person/formue")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "Dataset /skatt/person/formue (1 version) successfully deleted\n")
+
+	code, stdout = runAgainstDevServer(t, server, "ls", "/skatt/person")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "inntekt\n", stdout)
+
+	code, stdout = runAgainstDevServer(t, server, "export", "--password", "secret", "/skatt/person/inntekt@latest~1")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "gs://dapla-dev-export/export/skatt/person/inntekt/20210201-inntekt.zip\n", stdout)
+	assert.Len(t, server.Exports(), 1)
+
+	code, _ = runAgainstDevServer(t, server, "export", "--password", "secret", "/skatt/person/inntekt@")
+	assert.Equal(t, 1, code)
+	assert.Len(t, server.Exports(), 1)
+}
+
+func TestRunExportPreviewAgainstDevServer(t *testing.T) {
+	server := devserver.New(devserver.SeedCatalog())
+
+	code, stdout := runAgainstDevServer(t, server, "export", "--preview", "--pseudo-rules-path", "/skatt/person/inntekt",
+		"/skatt/person/formue")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "**/fnr")
+	assert.Contains(t, stdout, "fpe-fnr(secret1)  person/fnr")
+
+	code, _ = runAgainstDevServer(t, server, "export", "--preview", "/skatt/person/formue@latest")
+	assert.Equal(t, 1, code)
+	assert.Empty(t, server.Exports())
+}
+
+func TestListenerURL(t *testing.T) {
+	server := httptest.NewServer(nil)
+	defer server.Close()
+
+	assert.Equal(t, strings.Replace(server.URL, "127.0.0.1", "localhost", 1), listenerURL(server.Listener))
+}
@@ -6,23 +6,23 @@ import (
 	"github.com/spf13/cobra"
 )
 
-func newDoctorCommand() *cobra.Command {
+func newDoctorCommand(env *Env) *cobra.Command {
 	return &cobra.Command{
 		Use:   "doctor",
 		Short: "Print diagnostics and check the system for potential problems",
 		Long:  `doctor checks the system for potential problems and prints environmental stuff useful for debugging purposes. Exits with a non-zero status if any potential problems are found.`,
 		Args:  cobra.MaximumNArgs(0),
-		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println(fmt.Sprintf("dapla-cli %v", versionInfo()))
-			fmt.Println("\nConfig:")
-			fmt.Println(effectiveConfig())
-			fmt.Println("\nAPIs:")
-			fmt.Println(allAPIUrlsString())
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fmt.Fprintln(env.Stdout, fmt.Sprintf("dapla-cli %v", versionInfo()))
+			fmt.Fprintln(env.Stdout, "\nConfig:")
+			fmt.Fprintln(env.Stdout, effectiveConfig(env.Config))
+			fmt.Fprintln(env.Stdout, "\nAPIs:")
+			apis, err := allAPIUrlsString(env.Config)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(env.Stdout, apis)
+			return nil
 		},
 	}
 }
-
-func init() {
-	doctorCommand := newDoctorCommand()
-	rootCmd.AddCommand(doctorCommand)
-}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+)
+
+func newDuCommand(env *Env) *cobra.Command {
+	var humanReadable, summarize, sortBySize bool
+	var maxDepth, parallel int
+	duCommand := &cobra.Command{
+		Use:   "du [PATH]...",
+		Short: "Estimate the storage used by datasets and folders",
+		Long: `The du command summarizes the storage used by each dataset and folder under a PATH, recursively. The size
+of a dataset is the total size of the files of all its versions, and the size of a folder is the total size of
+all datasets under it.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if parallel < 1 {
+				return errors.New("--parallel must be at least 1")
+			}
+			if summarize {
+				maxDepth = 0
+			}
+
+			client, err := env.Maintenance()
+			if err != nil {
+				return err
+			}
+			walker := newUsageWalker(client, parallel)
+			for _, path := range env.absPaths(args) {
+				spinner := env.spinner("Calculating disk usage of " + path)
+				u, err := walker.root(cmd.Context(), path)
+				spinner.Stop()
+				if err != nil {
+					return err
+				}
+
+				printUsage(u, env.Stdout, maxDepth, humanReadable, sortBySize)
+			}
+			return nil
+		},
+		ValidArgsFunction: completePath(env),
+	}
+	// Free up -h for --human-readable, like the unix du command
+	duCommand.Flags().Bool("help", false, "help for du")
+	duCommand.Flags().BoolVarP(&humanReadable, "human-readable", "h", false, "print sizes in human readable format (e.g. 1.5K, 234M, 2.0G)")
+	duCommand.Flags().BoolVarP(&summarize, "summarize", "s", false, "display only a total for each PATH")
+	duCommand.Flags().IntVar(&maxDepth, "max-depth", -1, "print the total for a folder or dataset only if it is N or fewer levels below PATH")
+	duCommand.Flags().BoolVarP(&sortBySize, "sort-size", "S", false, "sort by size, largest first")
+	duCommand.Flags().IntVar(&parallel, "parallel", 8, "maximum number of concurrent API requests")
+	return duCommand
+}
+
+// usage holds the storage used by a dataset or folder. The size of a folder is the total size of its children.
+type usage struct {
+	Path     string
+	Folder   bool
+	Size     uint64
+	Children []*usage
+}
+
+// usageWalker calculates the storage used by the datasets in a tree, with a limited number of concurrent requests
+type usageWalker struct {
+	client maintenance.API
+	limit  chan struct{}
+}
+
+func newUsageWalker(client maintenance.API, parallel int) *usageWalker {
+	return &usageWalker{client: client, limit: make(chan struct{}, parallel)}
+}
+
+// call invokes fn when a request slot is available, unless ctx is done first
+func (w *usageWalker) call(ctx context.Context, fn func() error) error {
+	select {
+	case w.limit <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-w.limit }()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// root calculates the storage used by a path, which may be either a folder or a dataset
+func (w *usageWalker) root(ctx context.Context, path string) (*usage, error) {
+	u, err := w.folder(ctx, path)
+	if err == nil && len(u.Children) > 0 {
+		return u, nil
+	}
+	if dataset, datasetErr := w.dataset(ctx, path); datasetErr == nil {
+		return dataset, nil
+	}
+	return u, err
+}
+
+func (w *usageWalker) folder(ctx context.Context, path string) (*usage, error) {
+	var res *maintenance.ListDatasetResponse
+	err := w.call(ctx, func() (err error) {
+		res, err = w.client.ListDatasets(ctx, path)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Cancel the requests of the siblings as soon as one child fails, and return the error of that child
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var once sync.Once
+	var firstErr error
+
+	u := &usage{Path: path, Folder: true, Children: make([]*usage, len(*res))}
+	var wg sync.WaitGroup
+	for i, element := range *res {
+		wg.Add(1)
+		go func(i int, element maintenance.ListDatasetElement) {
+			defer wg.Done()
+			var err error
+			if element.IsFolder() {
+				u.Children[i], err = w.folder(ctx, element.Path)
+			} else {
+				u.Children[i], err = w.dataset(ctx, element.Path)
+			}
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(i, element)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	for _, child := range u.Children {
+		u.Size += child.Size
+	}
+	return u, nil
+}
+
+func (w *usageWalker) dataset(ctx context.Context, path string) (*usage, error) {
+	var res *maintenance.ListVersionsResponse
+	err := w.call(ctx, func() (err error) {
+		res, err = w.client.ListVersions(ctx, path)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &usage{Path: path, Size: res.TotalSize()}, nil
+}
+
+// printUsage prints the size of each dataset and folder in the tree, contents before the folder itself. Entries
+// deeper than maxDepth levels below the root are not printed, unless maxDepth is negative.
+func printUsage(root *usage, output io.Writer, maxDepth int, human bool, sortBySize bool) {
+	writer := tabwriter.NewWriter(output, 8, 0, 2, ' ', 0)
+	defer writer.Flush()
+
+	var print func(u *usage, depth int)
+	print = func(u *usage, depth int) {
+		if maxDepth >= 0 && depth > maxDepth {
+			return
+		}
+
+		children := append([]*usage{}, u.Children...)
+		if sortBySize {
+			sort.SliceStable(children, func(i, j int) bool { return children[i].Size > children[j].Size })
+		}
+		for _, child := range children {
+			print(child, depth+1)
+		}
+
+		path := u.Path
+		if u.Folder {
+			path = strings.TrimSuffix(path, "/") + "/"
+		}
+		fmt.Fprintf(writer, "%s\t%s\n", formatSize(u.Size, human), path)
+	}
+	print(root, 0)
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/andreyvit/diff"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestUsageWalker(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Get("/api/v1/list/foo$").
+		Reply(http.StatusOK).
+		BodyString(`[{"path": "foo/small", "depth": 0},{"path": "foo/bar", "depth": 1}]`)
+	gock.New("http://server.com").
+		Get("/api/v1/list/foo/bar$").
+		Reply(http.StatusOK).
+		BodyString(`[{"path": "foo/bar/large", "depth": 0}]`)
+	gock.New("http://server.com").
+		Get("/api/v1/versions/foo/small").
+		Reply(http.StatusOK).
+		BodyString(`{"versions": [{"files": [{"size": 100}]}, {"files": [{"size": 24}]}]}`)
+	gock.New("http://server.com").
+		Get("/api/v1/versions/foo/bar/large").
+		Reply(http.StatusOK).
+		BodyString(`{"versions": [{"files": [{"size": 2048}, {"size": 1024}]}]}`)
+
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	client := maintenance.NewClient("http://server.com", "a secret secret")
+	root, err := newUsageWalker(client, 2).root(context.Background(), "foo")
+	if err != nil {
+		t.Fatalf("Got error %v", err)
+	}
+
+	tests := []struct {
+		maxDepth   int
+		human      bool
+		sortBySize bool
+		expected   string
+	}{
+		{-1, false, false, "124     foo/small\n3072    foo/bar/large\n3072    foo/bar/\n3196    foo/\n"},
+		{-1, true, true, "3.0K    foo/bar/large\n3.0K    foo/bar/\n124B    foo/small\n3.1K    foo/\n"},
+		{1, false, false, "124     foo/small\n3072    foo/bar/\n3196    foo/\n"},
+		{0, true, false, "3.1K    foo/\n"},
+	}
+
+	for _, test := range tests {
+		var output bytes.Buffer
+		printUsage(root, &output, test.maxDepth, test.human, test.sortBySize)
+		if actual := output.String(); actual != test.expected {
+			t.Errorf("Result not as expected:\n%v", diff.LineDiff(test.expected, actual))
+		}
+	}
+}
+
+// failingUsageAPI only lists the versions of the datasets in /root/folder, and listing that large folder is slow
+type failingUsageAPI struct {
+	maintenance.API
+	folderCalls int32
+}
+
+func (f *failingUsageAPI) ListDatasets(ctx context.Context, path string) (*maintenance.ListDatasetResponse, error) {
+	if path == "/root" {
+		return &maintenance.ListDatasetResponse{{Path: "/root/denied"}, {Path: "/root/folder", Depth: 1}}, nil
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(time.Second):
+	}
+	res := maintenance.ListDatasetResponse{}
+	for i := 0; i < 100; i++ {
+		res = append(res, maintenance.ListDatasetElement{Path: fmt.Sprintf("%s/%d", path, i)})
+	}
+	return &res, nil
+}
+
+func (f *failingUsageAPI) ListVersions(_ context.Context, path string) (*maintenance.ListVersionsResponse, error) {
+	if !strings.HasPrefix(path, "/root/folder/") {
+		return nil, errors.New("forbidden")
+	}
+	atomic.AddInt32(&f.folderCalls, 1)
+	return &maintenance.ListVersionsResponse{}, nil
+}
+
+func TestUsageWalker_CancelOnError(t *testing.T) {
+	client := &failingUsageAPI{}
+	_, err := newUsageWalker(client, 2).root(context.Background(), "/root")
+
+	assert.EqualError(t, err, "forbidden")
+	assert.Equal(t, int32(0), atomic.LoadInt32(&client.folderCalls))
+}
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/briandowns/spinner"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/viper"
+	"github.com/statisticsnorway/dapla-cli/export"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+)
+
+// Exporter exports datasets, see export.Client
+type Exporter interface {
+	Export(ctx context.Context, req export.Request) (*export.Response, error)
+}
+
+// Env holds everything the commands depend on: the API clients, the IO streams, the configuration and the clock.
+// The commands are created from an Env by NewRootCommand and do not use any globals, so that they can be run end
+// to end in tests, with fake clients and buffers as IO streams.
+type Env struct {
+	// Maintenance returns a client for the data-maintenance API
+	Maintenance func() (maintenance.API, error)
+	// Export returns a client for the export API of the pseudo service
+	Export func() (Exporter, error)
+
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Config holds the configuration from the config file, the environment and the global flags
+	Config *viper.Viper
+	// ConfigDir is the directory holding the .dapla-cli config file, unless another file is given by --config.
+	// No config file is read if empty.
+	ConfigDir string
+	// Now returns the current time
+	Now func() time.Time
+	// CacheDir is the directory holding the cache of listings, see cache.Client. Listings are not cached if empty.
+	CacheDir string
+	// RefreshInBackground refreshes a cached listing in the background. It is used by completion, which serves stale
+	// listings right away. Stale listings are listed again before completing if nil.
+	RefreshInBackground func(path string)
+	// PluginPath is the list of directories searched for plugins, like $PATH, see the plugin package. No plugins are
+	// run if empty.
+	PluginPath string
+	// WorkingDir is the folder that relative dataset paths are resolved against, see absPath. It is only set by the
+	// shell command. Outside the shell, relative paths are resolved against the home-folder config option, or used
+	// as given if it is not set.
+	WorkingDir string
+
+	input *bufio.Reader
+}
+
+// DefaultEnv returns the Env used by the dapla command: the standard IO streams, the system clock, and clients
+// for the APIs given by the configuration
+func DefaultEnv() *Env {
+	// The config file and the cache are not required, so ignore the errors if the directories are unknown
+	home, _ := homedir.Dir()
+	cacheDir, err := os.UserCacheDir()
+	if err == nil {
+		cacheDir = filepath.Join(cacheDir, "dapla-cli")
+	}
+	env := &Env{
+		Stdin:      os.Stdin,
+		Stdout:     os.Stdout,
+		Stderr:     os.Stderr,
+		Config:     viper.New(),
+		ConfigDir:  home,
+		CacheDir:   cacheDir,
+		PluginPath: os.Getenv("PATH"),
+		Now:        time.Now,
+	}
+	env.Maintenance = func() (maintenance.API, error) {
+		apiURL, err := apiURLOrError(env.Config, APINameDataMaintenanceSvc)
+		if err != nil {
+			return nil, err
+		}
+		client := maintenance.New(apiURL, maintenance.WithTokenSource(authTokenSource(env.Config)),
+			maintenance.WithTransport(env.transport()))
+		return cachedClient(env, client, apiURL)
+	}
+	env.RefreshInBackground = func(path string) {
+		refreshInBackground(env, path)
+	}
+	env.Export = func() (Exporter, error) {
+		apiURL, err := apiURLOrError(env.Config, APINamePseudoSvc)
+		if err != nil {
+			return nil, err
+		}
+		token, err := authTokenOrError(env.Config)
+		if err != nil {
+			return nil, err
+		}
+		return export.New(apiURL, export.WithToken(token), export.WithTransport(env.transport())), nil
+	}
+	return env
+}
+
+// transport returns the transport of the API clients, which logs the requests on stderr if --debug is given
+func (env *Env) transport() http.RoundTripper {
+	if env.Config.GetBool(CFGDebug) {
+		return &debugTransport{out: env.Stderr, next: http.DefaultTransport}
+	}
+	return http.DefaultTransport
+}
+
+// debugTransport logs the method, URL, status and duration of each request on out
+type debugTransport struct {
+	out  io.Writer
+	next http.RoundTripper
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		fmt.Fprintf(t.out, "%s %s: %v\n", req.Method, req.URL, err)
+		return nil, err
+	}
+	fmt.Fprintf(t.out, "%s %s: %s (%v)\n", req.Method, req.URL, res.Status, time.Since(start).Round(time.Millisecond))
+	return res, nil
+}
+
+// prompts returns the reader that all prompts read their answers from, so that no input is lost in the buffer of
+// a previous reader
+func (env *Env) prompts() *bufio.Reader {
+	if env.input == nil {
+		env.input = bufio.NewReader(env.Stdin)
+	}
+	return env.input
+}
+
+// absPath resolves a dataset path against the working folder of the shell, or the home folder outside the shell.
+// The path is returned as given if there is neither.
+func (env *Env) absPath(p string) string {
+	dir := env.workingDir()
+	if dir == "" {
+		return p
+	}
+	if !strings.HasPrefix(p, "/") {
+		p = dir + "/" + p
+	}
+	return path.Clean(p)
+}
+
+// workingDir returns the folder that relative dataset paths are resolved against: the working folder of the shell,
+// or else the home folder given by the config
+func (env *Env) workingDir() string {
+	if env.WorkingDir != "" {
+		return env.WorkingDir
+	}
+	return env.Config.GetString(CFGHomeFolder)
+}
+
+// absPaths resolves dataset paths against the working folder of the shell, see absPath
+func (env *Env) absPaths(paths []string) []string {
+	resolved := make([]string, 0, len(paths))
+	for _, p := range paths {
+		resolved = append(resolved, env.absPath(p))
+	}
+	return resolved
+}
+
+// spinner creates a CLI spinner on stderr, which is only started if stderr is a terminal
+func (env *Env) spinner(prefix string) *spinner.Spinner {
+	s := spinner.New(spinner.CharSets[9], 100*time.Millisecond, spinner.WithWriter(env.Stderr))
+	s.Color("reset")
+	s.Prefix = prefix + " "
+	if isTerminal(env.Stderr) {
+		s.Start()
+	}
+	return s
+}
+
+// isTerminal returns true if output is a terminal, and false if it is piped, redirected or not a file at all
+func isTerminal(output io.Writer) bool {
+	file, ok := output.(*os.File)
+	if !ok {
+		return false
+	}
+	fileInfo, err := file.Stat()
+	return err == nil && (fileInfo.Mode()&os.ModeCharDevice) != 0
+}
+
+// confirm asks a yes/no question on stderr, and returns true if the user answers yes. Anything else, including no
+// answer at all, is taken as no.
+func confirm(env *Env, question string) bool {
+	fmt.Fprint(env.Stderr, question, " [y/N] ")
+	answer, err := env.prompts().ReadString('\n')
+	if err != nil && err != io.EOF {
+		fmt.Fprintln(env.Stderr, err)
+	}
+	switch strings.TrimSpace(answer) {
+	case "y", "Y", "yes":
+		return true
+	}
+	return false
+}
+
+// exitError makes the dapla command exit with a specific code, without printing an error. It is returned by
+// commands that have already reported the error themselves.
+type exitError struct {
+	code int
+}
+
+func (e *exitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+// reportAPIError prints an error from the data-maintenance API on stdout. HTTP errors are reported by the server,
+// and end the command with a zero exit code.
+func reportAPIError(env *Env, err error) error {
+	fmt.Fprintln(env.Stdout, err.Error()+"\n")
+	if _, ok := err.(*maintenance.HTTPError); ok {
+		return &exitError{code: 0}
+	}
+	return &exitError{code: 1}
+}
@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"github.com/stretchr/testify/assert"
+)
+
+var testNow = time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+
+// fakeAPI is an in-memory data-maintenance API holding the versions of each dataset. Folders are implied by the
+// dataset paths. Deleted datasets are removed, and the calls that change anything are recorded. Calls to methods
+// that are not implemented panic.
+type fakeAPI struct {
+	maintenance.API
+	datasets  map[string][]maintenance.Version
+	protected []string
+	calls     []string
+}
+
+func (f *fakeAPI) ListDatasets(_ context.Context, path string) (*maintenance.ListDatasetResponse, error) {
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	seen := map[string]bool{}
+	res := maintenance.ListDatasetResponse{}
+	for datasetPath := range f.datasets {
+		if !strings.HasPrefix(datasetPath, prefix) {
+			continue
+		}
+		name := strings.SplitN(strings.TrimPrefix(datasetPath, prefix), "/", 2)
+		if seen[name[0]] {
+			continue
+		}
+		seen[name[0]] = true
+		element := maintenance.ListDatasetElement{Path: prefix + name[0]}
+		if len(name) > 1 {
+			element.Depth = 1
+		}
+		res = append(res, element)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Path < res[j].Path })
+	return &res, nil
+}
+
+func (f *fakeAPI) IterateDatasets(ctx context.Context, path string, fn maintenance.IterateFunc) error {
+	res, _ := f.ListDatasets(ctx, path)
+	for _, element := range *res {
+		if err := fn(element); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeAPI) ListVersions(_ context.Context, path string) (*maintenance.ListVersionsResponse, error) {
+	versions, ok := f.datasets[path]
+	if !ok {
+		return nil, errors.New("no such dataset " + path)
+	}
+	return &maintenance.ListVersionsResponse{DatasetPath: path, Versions: versions}, nil
+}
+
+func (f *fakeAPI) DeleteDatasets(_ context.Context, path string, dryRun bool) (*maintenance.DeleteDatasetResponse, error) {
+	f.calls = append(f.calls, "delete "+path)
+	res := maintenance.DeleteDatasetResponse{DatasetPath: path}
+	for _, version := range f.datasets[path] {
+		res.DatasetVersion = append(res.DatasetVersion, maintenance.DatasetVersion{Timestamp: version.Timestamp, DeletedFiles: version.Files})
+		res.TotalSize += version.Size()
+	}
+	if !dryRun {
+		delete(f.datasets, path)
+	}
+	return &res, nil
+}
+
+func (f *fakeAPI) DeleteDatasetVersions(_ context.Context, path string, timestamps []time.Time, dryRun bool) (*maintenance.DeleteDatasetResponse, error) {
+	f.calls = append(f.calls, "delete versions "+path)
+	res := maintenance.DeleteDatasetResponse{DatasetPath: path}
+	for _, timestamp := range timestamps {
+		res.DatasetVersion = append(res.DatasetVersion, maintenance.DatasetVersion{Timestamp: timestamp})
+	}
+	return &res, nil
+}
+
+func (f *fakeAPI) TrashDataset(_ context.Context, path string, expiry time.Duration, dryRun bool) (*maintenance.TrashedDataset, error) {
+	f.calls = append(f.calls, "trash "+path)
+	if !dryRun {
+		delete(f.datasets, path)
+	}
+	return &maintenance.TrashedDataset{DatasetPath: path, TrashedAt: testNow, ExpiresAt: testNow.Add(expiry)}, nil
+}
+
+func (f *fakeAPI) ListProtectedPaths(_ context.Context) ([]string, error) {
+	return f.protected, nil
+}
+
+// newTestEnv returns an Env using client, with input on stdin, buffers as stdout and stderr, no config file and the
+// clock stopped at testNow
+func newTestEnv(client maintenance.API, input string) (*Env, *bytes.Buffer, *bytes.Buffer) {
+	var stdout, stderr bytes.Buffer
+	env := &Env{
+		Maintenance: func() (maintenance.API, error) {
+			return client, nil
+		},
+		Export: func() (Exporter, error) {
+			return nil, errors.New("export is not available in tests")
+		},
+		Stdin:  strings.NewReader(input),
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Config: viper.New(),
+		Now: func() time.Time {
+			return testNow
+		},
+	}
+	return env, &stdout, &stderr
+}
+
+func newTestAPI() *fakeAPI {
+	return &fakeAPI{datasets: map[string][]maintenance.Version{
+		"/skatt/inntekt": {
+			{Timestamp: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), Files: []maintenance.DatasetFile{{Size: 1}}},
+		},
+		"/skatt/formue": {
+			{Timestamp: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), Files: []maintenance.DatasetFile{{Size: 2}}},
+			{Timestamp: time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC), Files: []maintenance.DatasetFile{{Size: 2}}},
+		},
+		"/skatt/2020/inntekt": {
+			{Timestamp: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Files: []maintenance.DatasetFile{{Size: 4}}},
+		},
+	}}
+}
+
+func TestRunLs(t *testing.T) {
+	env, stdout, stderr := newTestEnv(newTestAPI(), "")
+
+	assert.Equal(t, 0, Run(context.Background(), env, []string{"ls", "/skatt"}))
+	assert.Equal(t, "2020\nformue\ninntekt\n", stdout.String())
+	assert.Empty(t, stderr.String())
+}
+
+func TestRunUsageError(t *testing.T) {
+	env, _, stderr := newTestEnv(newTestAPI(), "")
+
+	assert.Equal(t, 1, Run(context.Background(), env, []string{"rm"}))
+	assert.Contains(t, stderr.String(), "Error: requires at least 1 arg(s), only received 0")
+}
+
+func TestRunRmPermanentDryRun(t *testing.T) {
+	api := newTestAPI()
+	env, stdout, _ := newTestEnv(api, "")
+
+	assert.Equal(t, 0, Run(context.Background(), env, []string{"rm", "--permanent", "--dry-run", "/skatt/formue"}))
+	assert.Equal(t, "Dataset /skatt/formue (2 versions) successfully deleted\n"+
+		"  Version               Files  Size\n"+
+		"  2021-01-01T00:00:00Z  1      2B\n"+
+		"  2021-02-01T00:00:00Z  1      2B\n"+
+		"  Total                 2      4B\n"+
+		"The dry-run flag was set. NO FILES WERE DELETED.\n", stdout.String())
+	assert.Equal(t, []string{"delete /skatt/formue"}, api.calls)
+	assert.Contains(t, api.datasets, "/skatt/formue")
+}
+
+func TestRunRmRecursive(t *testing.T) {
+	api := newTestAPI()
+	env, stdout, stderr := newTestEnv(api, "y\nno\nyes\n")
+
+	assert.Equal(t, 0, Run(context.Background(), env, []string{"rm", "--recursive", "--trash-expiry", "7d", "/skatt"}))
+	assert.Equal(t, []string{"trash /skatt/2020/inntekt", "trash /skatt/inntekt"}, api.calls)
+	assert.Equal(t, "Move dataset /skatt/2020/inntekt to trash? "+
+		"Move dataset /skatt/formue to trash? ... skipped\n"+
+		"Move dataset /skatt/inntekt to trash? ", stderr.String())
+	assert.Contains(t, stdout.String(), "Dataset /skatt/inntekt (0 versions, 0B) moved to trash, expires 2021-05-08T00:00:00Z\n")
+}
+
+func TestRunRmProtected(t *testing.T) {
+	api := newTestAPI()
+	api.protected = []string{"/skatt/**"}
+
+	env, _, stderr := newTestEnv(api, "")
+	assert.Equal(t, 1, Run(context.Background(), env, []string{"rm", "/skatt/inntekt"}))
+	assert.Equal(t, "Error: /skatt/inntekt is protected by /skatt/** (use --override-protection to change it anyway)\n", stderr.String())
+	assert.Empty(t, api.calls)
+
+	env, _, stderr = newTestEnv(api, "/skatt/inntekt\n")
+	assert.Equal(t, 0, Run(context.Background(), env, []string{"rm", "--override-protection", "/skatt/inntekt"}))
+	assert.Equal(t, "/skatt/inntekt is protected by /skatt/**. Type the path to confirm: ", stderr.String())
+	assert.Equal(t, []string{"trash /skatt/inntekt"}, api.calls)
+}
+
+func TestRunPruneConfiguredProtection(t *testing.T) {
+	api := newTestAPI()
+	env, stdout, stderr := newTestEnv(api, "")
+	env.Config.Set(CFGProtectedPaths, []string{"/skatt/2020/**"})
+
+	assert.Equal(t, 0, Run(context.Background(), env, []string{"prune", "--recursive", "--keep-last", "1", "/skatt"}))
+	assert.Equal(t, []string{"delete versions /skatt/formue"}, api.calls)
+	assert.Equal(t, "Skipping dataset /skatt/2020/inntekt, protected by /skatt/2020/**\n", stderr.String())
+	assert.Contains(t, stdout.String(), "Dataset /skatt/inntekt (1 version): nothing to prune\n")
+}
+
+func TestDebugTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	stderr := &bytes.Buffer{}
+	client := &http.Client{Transport: &debugTransport{out: stderr, next: http.DefaultTransport}}
+
+	res, err := client.Get(server.URL + "/api/v1/list/skatt")
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Regexp(t, `^GET `+server.URL+`/api/v1/list/skatt: 404 Not Found \(\d+m?s\)\n$`, stderr.String())
+}
@@ -1,72 +1,323 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"github.com/statisticsnorway/dapla-cli/export"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
 )
 
-var (
-	req           export.Request
-	pseudoRuleMap map[string]string
-)
-
-// TODO: Use enumflag instead (https://pkg.go.dev/github.com/thediveo/enumflag)
+// contentTypeMap maps the supported export file types to the content type expected by the dapla-pseudo-service
 var contentTypeMap = map[string]string{
-	"json": "application/json",
-	"csv":  "text/csv",
+	"json":    "application/json",
+	"ndjson":  "application/x-ndjson",
+	"csv":     "text/csv",
+	"parquet": "application/vnd.apache.parquet",
+	"avro":    "application/avro",
+	"xlsx":    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
 }
 
-func newExportCommand() *cobra.Command {
-	return &cobra.Command{
+// fileTypeDescriptions are shown alongside the file types during shell completion
+var fileTypeDescriptions = map[string]string{
+	"json":    "JSON array",
+	"ndjson":  "newline delimited JSON",
+	"csv":     "comma separated values",
+	"parquet": "Apache Parquet",
+	"avro":    "Apache Avro",
+	"xlsx":    "Excel workbook",
+}
+
+// fileType is a flag value that only accepts the file types in contentTypeMap
+type fileType string
+
+func (f *fileType) String() string {
+	return string(*f)
+}
+
+func (f *fileType) Set(value string) error {
+	if _, ok := contentTypeMap[value]; !ok {
+		return fmt.Errorf("must be one of %s", strings.Join(fileTypes(), ", "))
+	}
+	*f = fileType(value)
+	return nil
+}
+
+func (f *fileType) Type() string {
+	return "filetype"
+}
+
+// fileTypes returns the supported export file types, sorted by name
+func fileTypes() []string {
+	types := make([]string, 0, len(contentTypeMap))
+	for t := range contentTypeMap {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func completeFileType(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var completions []string
+	for _, t := range fileTypes() {
+		if strings.HasPrefix(t, toComplete) {
+			completions = append(completions, t+"\t"+fileTypeDescriptions[t])
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completePseudoRules returns a completion function that completes the function of the last pseudo rule, e.g.
+// fnr=fpe-<TAB>. The functions of the pseudo rules of the dataset being exported, and of the --pseudo-rules-path
+// dataset, are suggested first, since they hold the IDs of the keys in use.
+func completePseudoRules(env *Env) func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		rule := toComplete[strings.LastIndex(toComplete, ",")+1:]
+		separator := strings.Index(rule, "=")
+		if separator < 0 {
+			return nil, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+		}
+		prefix := toComplete[:len(toComplete)-len(rule)+separator+1]
+		partialFunc := rule[separator+1:]
+
+		paths := append([]string{}, args...)
+		if rulesPath, _ := cmd.Flags().GetString("pseudo-rules-path"); rulesPath != "" {
+			paths = append(paths, rulesPath)
+		}
+		var completions []string
+		seen := map[string]bool{}
+		for _, path := range paths {
+			path, _, err := maintenance.SplitVersionPath(path)
+			if err != nil {
+				continue
+			}
+			for _, rule := range datasetPseudoRules(env, env.absPath(path)) {
+				if !seen[rule.Func] && strings.HasPrefix(rule.Func, partialFunc) {
+					seen[rule.Func] = true
+					completions = append(completions, prefix+rule.Func+"\tused by "+rule.Pattern+" in "+path)
+				}
+			}
+		}
+		names := make([]string, 0, len(export.PseudoFuncs))
+		for name := range export.PseudoFuncs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if strings.HasPrefix(name+"(", partialFunc) {
+				completions = append(completions, prefix+name+"(\t"+export.PseudoFuncs[name])
+			}
+		}
+		return completions, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// datasetPseudoRules returns the pseudo rules of the dataset at path, or none if they can not be retrieved
+func datasetPseudoRules(env *Env, path string) []maintenance.PseudoRule {
+	client, err := env.Maintenance()
+	if err != nil {
+		return nil
+	}
+	info, err := client.GetDatasetInfo(context.Background(), path)
+	if err != nil {
+		cobra.CompDebugln("could not fetch dataset info: "+err.Error(), false)
+		return nil
+	}
+	return info.PseudoRules
+}
+
+func newExportCommand(env *Env) *cobra.Command {
+	var req export.Request
+	var pseudoRuleMap map[string]string
+	var preview bool
+	var exportVersion string
+	exportCommand := &cobra.Command{
 		Use:   "export [PATH]",
 		Short: "Export a dataset",
-		Long:  `The export command exports (and optionally depseudonymizes) a specified dataset`,
-		Args:  cobra.MinimumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			req.DatasetPath = args[0]
+		Long: `The export command exports (and optionally depseudonymizes) a specified dataset.
+
+By default the latest version of the dataset is exported. A specific version can be selected either with the
+--version flag or by suffixing the PATH with @VERSION, e.g. /path/to/dataset@latest~1. VERSION is one of:
+
+  latest                      the latest version
+  latest~N                    the Nth version before the latest version
+  1617352341234               epoch milliseconds
+  2021-04-02T08:32:21.234Z    RFC3339 timestamp
+  2021-04-02T08:32:21         timestamp (UTC)
+  2021-04-02                  the latest version at the given date (UTC)`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, version, err := maintenance.SplitVersionPath(args[0])
+			if err != nil {
+				return err
+			}
+			if version != "" && exportVersion != "" {
+				return errors.New("cannot use both --version and a PATH@VERSION suffix")
+			}
+			if version == "" {
+				version = exportVersion
+			}
+			if preview && version != "" {
+				// The schema endpoint only serves the schema of the latest version
+				return errors.New("cannot preview a specific version of a dataset")
+			}
+			path = env.absPath(path)
+			req.DatasetPath = path
+			if req.PseudoRulesDatasetPath != "" {
+				req.PseudoRulesDatasetPath = env.absPath(req.PseudoRulesDatasetPath)
+			}
+
+			if version != "" {
+				client, err := env.Maintenance()
+				if err != nil {
+					return err
+				}
+				spinner := env.spinner("Resolving version " + version + " of " + path)
+				timestamp, err := resolveDatasetVersion(cmd.Context(), client, path, version)
+				spinner.Stop()
+				if err != nil {
+					return err
+				}
+				req.DatasetTimestamp = &timestamp
+			}
+
+			// Sort the patterns so that rules are named deterministically
+			patterns := make([]string, 0, len(pseudoRuleMap))
+			for p := range pseudoRuleMap {
+				patterns = append(patterns, p)
+			}
+			sort.Strings(patterns)
 
 			req.PseudoRules = make([]export.PseudoRule, 0, len(pseudoRuleMap))
-			i := 0
-			for p, f := range pseudoRuleMap {
-				i++
+			for i, p := range patterns {
 				req.PseudoRules = append(req.PseudoRules, export.PseudoRule{
-					Name:    fmt.Sprintf("rule-%d", i),
+					Name:    fmt.Sprintf("rule-%d", i+1),
 					Pattern: p,
-					Func:    f})
+					Func:    pseudoRuleMap[p]})
+			}
+
+			if preview {
+				client, err := env.Maintenance()
+				if err != nil {
+					return err
+				}
+				spinner := env.spinner("Fetching schema for " + req.DatasetPath)
+				schema, err := client.GetDatasetSchema(cmd.Context(), req.DatasetPath)
+				spinner.Stop()
+				if err != nil {
+					return err
+				}
+
+				// Like the export, use the pseudo rules of the dataset at --pseudo-rules-path
+				if req.PseudoRulesDatasetPath != "" {
+					spinner := env.spinner("Fetching pseudo rules of " + req.PseudoRulesDatasetPath)
+					info, err := client.GetDatasetInfo(cmd.Context(), req.PseudoRulesDatasetPath)
+					spinner.Stop()
+					if err != nil {
+						return err
+					}
+					req.PseudoRules = info.PseudoRules
+				}
+
+				preview := export.NewPreview(req, schema.FieldPaths())
+				printExportPreview(preview, env.Stdout)
+				if unmatched := len(preview.Unmatched()); unmatched > 0 {
+					fmt.Fprintf(env.Stderr, "Warning: %d %s did not match any columns\n", unmatched, pluralize("pattern", unmatched))
+				}
+				return nil
+			}
+
+			if req.TargetPassword == "" {
+				return errors.New(`required flag "password" not set`)
 			}
 
 			// translate file type to content type
 			req.TargetContentType = contentTypeMap[req.TargetContentType]
 
-			spinner := newSpinner("This might take some time...")
-			client := export.NewClient(apiURLOf(APINamePseudoSvc), authToken(), viper.GetBool(CFGDebug))
-			res, err := client.Export(req)
-			cobra.CheckErr(err)
+			client, err := env.Export()
+			if err != nil {
+				return err
+			}
+			spinner := env.spinner("This might take some time...")
+			res, err := client.Export(cmd.Context(), req)
 			spinner.Stop()
+			if err != nil {
+				return err
+			}
 
-			fmt.Println(res.TargetURI)
-		},
-		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-			return doAutoComplete(toComplete)
+			fmt.Fprintln(env.Stdout, res.TargetURI)
+			return nil
 		},
+		ValidArgsFunction: completePath(env),
 	}
-}
-
-func init() {
-	exportCommand := newExportCommand()
 	exportCommand.Flags().StringVarP(&req.TargetContentName, "name", "n", "", "optional descriptive name of the contents, used as baseline for the target archive name")
 	exportCommand.Flags().StringArrayVarP(&req.ColumnSelectors, "cols", "c", []string{}, "optional list of glob patterns that can be used to specify a subset of fields to export")
 	exportCommand.Flags().StringVarP(&req.TargetPassword, "password", "p", "", "password used to protect target archive")
-	exportCommand.MarkFlagRequired("password")
-	exportCommand.Flags().StringVarP(&req.TargetContentType, "target-filetype", "t", "json", "the export filetype (json or csv)")
+	req.TargetContentType = "json"
+	exportCommand.Flags().VarP((*fileType)(&req.TargetContentType), "target-filetype", "t",
+		"the export filetype ("+strings.Join(fileTypes(), ", ")+")")
+	exportCommand.RegisterFlagCompletionFunc("target-filetype", completeFileType)
 	exportCommand.Flags().BoolVar(&req.Depseudonymize, "depseudo", false, "depseudonymize data during export")
 	exportCommand.Flags().StringToStringVar(&pseudoRuleMap, "pseudo-rules", map[string]string{}, "explicit pseudo rules to use")
 	exportCommand.Flags().StringVar(&req.PseudoRulesDatasetPath, "pseudo-rules-path", "", "path to retrieve pseudo rules from")
+	exportCommand.RegisterFlagCompletionFunc("pseudo-rules", completePseudoRules(env))
+	exportCommand.RegisterFlagCompletionFunc("pseudo-rules-path", completePath(env))
+	exportCommand.Flags().StringVar(&exportVersion, "version", "", "the dataset version to export (timestamp, latest or latest~N)")
+	exportCommand.Flags().BoolVar(&preview, "preview", false, "show which columns of the latest version the column selectors and pseudo rules match, without exporting")
 
 	// TODO: Add validation rule that fails if both pseudo-rules and pseudo-rules-path flags are specified
 
-	rootCmd.AddCommand(exportCommand)
+	return exportCommand
+}
+
+// printExportPreview prints the selected columns, followed by the fields matched by each column selector and pseudo rule
+func printExportPreview(preview export.Preview, output io.Writer) {
+	colorOutput := colorWriter{out: output}
+	writer := tabwriter.NewWriter(colorOutput, 15, 0, 2, ' ', tabwriter.FilterHTML)
+	defer writer.Flush()
+
+	fmt.Fprintf(writer, "<bold>Columns</> (%d of %d selected)\n", len(preview.Columns), len(preview.Fields))
+	for _, column := range preview.Columns {
+		fmt.Fprintf(writer, "  %s\n", column)
+	}
+
+	if len(preview.Selectors) > 0 {
+		fmt.Fprintln(writer, "\n<bold>Column selectors</>")
+		for _, match := range preview.Selectors {
+			printPatternMatch(writer, match.Pattern, match)
+		}
+	}
+
+	if len(preview.PseudoRules) > 0 {
+		fmt.Fprintln(writer, "\n<bold>Pseudo rules</>")
+		for _, match := range preview.PseudoRules {
+			printPatternMatch(writer, match.Pattern+"\t"+match.Func, match)
+		}
+	}
+}
+
+func printPatternMatch(writer io.Writer, label string, match export.PatternMatch) {
+	if match.IsEmpty() {
+		fmt.Fprintf(writer, "  %s\t<fg=red>(no match)</>\n", label)
+		return
+	}
+
+	// Only print the label on the first line, but keep the columns aligned
+	blank := ""
+	if match.Func != "" {
+		blank = "\t"
+	}
+	for i, field := range match.Fields {
+		if i == 0 {
+			fmt.Fprintf(writer, "  %s\t%s\n", label, field)
+		} else {
+			fmt.Fprintf(writer, "  %s\t%s\n", blank, field)
+		}
+	}
 }
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/acarl005/stripansi"
+	"github.com/andreyvit/diff"
+	"github.com/statisticsnorway/dapla-cli/export"
+)
+
+func TestPrintExportPreview(t *testing.T) {
+	preview := export.NewPreview(export.Request{
+		ColumnSelectors: []string{"person/**", "nosuchfield"},
+		PseudoRules: []export.PseudoRule{
+			{Pattern: "**/fnr", Func: "fpe-fnr(secret1)"},
+		},
+	}, []string{"person/fnr", "person/name", "income"})
+
+	var output bytes.Buffer
+	printExportPreview(preview, &output)
+
+	expected := `
+Columns (2 of 3 selected)
+  person/fnr
+  person/name
+
+Column selectors
+  person/**    person/fnr
+               person/name
+  nosuchfield  (no match)
+
+Pseudo rules
+  **/fnr       fpe-fnr(secret1)  person/fnr
+`
+	if actual, expected := diff.TrimLinesInString(stripansi.Strip(output.String())),
+		diff.TrimLinesInString(expected); actual != expected {
+		t.Errorf("Result not as expected:\n%v", diff.LineDiff(expected, actual))
+	}
+}
+
+func TestFileTypeFlag(t *testing.T) {
+	var value fileType
+
+	for _, valid := range []string{"json", "ndjson", "csv", "parquet", "avro", "xlsx"} {
+		if err := value.Set(valid); err != nil {
+			t.Errorf("Got error %v for file type %s", err, valid)
+		}
+		if contentTypeMap[value.String()] == "" {
+			t.Errorf("Missing content type for file type %s", valid)
+		}
+	}
+
+	if err := value.Set("docx"); err == nil {
+		t.Errorf("Expected error for unknown file type")
+	}
+	if value.String() != "xlsx" {
+		t.Errorf("Expected value to be unchanged after invalid input, but got %s", value.String())
+	}
+}
+
+func TestCompleteFileType(t *testing.T) {
+	completions, _ := completeFileType(nil, nil, "p")
+	if len(completions) != 1 || completions[0] != "parquet\tApache Parquet" {
+		t.Errorf("Expected parquet completion, but got %v", completions)
+	}
+}
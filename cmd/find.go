@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/spf13/cobra"
+	"github.com/statisticsnorway/dapla-cli/find"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+)
+
+func newFindCommand(env *Env) *cobra.Command {
+	findCommand := &cobra.Command{
+		Use:   "find PATH... [EXPRESSION]",
+		Short: "Search for datasets and folders",
+		Long: `The find command walks the datasets and folders under each PATH, and evaluates an expression for each of them,
+much like Unix find. Without an expression the paths of all datasets and folders are printed.
+
+Predicates:
+  -name PATTERN            the last element of the path matches a shell pattern, e.g. 'inntekt*'
+  -path PATTERN            the path matches a glob, where ** matches any number of folders
+  -type dataset|folder     the entry is a dataset or a folder
+  -valuation VALUATION     the dataset has the given valuation
+  -state STATE             the dataset has the given state
+  -created-by USER         the entry was created by the given user
+  -newer TIME              the entry was created after TIME, a timestamp or a duration such as 7d
+  -older TIME              the entry was created before TIME, a timestamp or a duration such as 7d
+  -size [+|-]N[c|K|M|G|T]  the size of all versions of the dataset is more than (+), less than (-) or exactly N
+  -empty                   the dataset has no files, or the folder has no datasets or folders
+
+Operators, tightest binding first:
+  ( EXPR )                 grouping
+  -not EXPR, ! EXPR        negation
+  EXPR -and EXPR           both are true (also -a, or just two expressions next to each other)
+  EXPR -or EXPR            any is true (also -o)
+
+Actions:
+  -print                   print the path followed by a newline (the default if no action is given)
+  -print0                  print the path followed by a NUL character
+  -json                    print the dataset or folder as a JSON line
+  -exec COMMAND ;          run COMMAND with {} replaced by the path, e.g. -exec dapla rm {} ';'
+
+Global flags must be given before the first PATH.`,
+		Example: `  dapla find /skatt -type dataset -name 'inntekt*'
+  dapla find /tmp -type dataset -older 30d -exec dapla rm --permanent {} ';'
+  dapla find /produkt -size +1G -o -empty -json`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var paths []string
+			for len(args) > 0 && !find.IsExpression(args[0]) {
+				paths, args = append(paths, args[0]), args[1:]
+			}
+			if len(paths) == 0 {
+				return errors.New("at least one PATH must be given before the expression")
+			}
+
+			expr, err := find.Parse(args, env.Now())
+			if err != nil {
+				return err
+			}
+
+			client, err := env.Maintenance()
+			if err != nil {
+				return err
+			}
+			return runFind(cmd.Context(), client, env.absPaths(paths), expr, &find.Env{Out: env.Stdout, Exec: execFindCommand(env)})
+		},
+		ValidArgsFunction: completePath(env),
+	}
+	// Stop parsing flags at the first PATH, so that the expression is passed on as arguments
+	findCommand.Flags().SetInterspersed(false)
+	return findCommand
+}
+
+// runFind evaluates the expression for every dataset and folder under the paths
+func runFind(ctx context.Context, client maintenance.API, paths []string, expr find.Expr, env *find.Env) error {
+	for _, path := range paths {
+		err := maintenance.WalkDatasets(ctx, client, path, func(element maintenance.ListDatasetElement) error {
+			_, err := expr.Eval(env, &findEntry{ctx: ctx, client: client, element: element})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findEntry is a dataset or folder found by the find command. The size and emptiness are fetched when first needed.
+type findEntry struct {
+	ctx      context.Context
+	client   maintenance.API
+	element  maintenance.ListDatasetElement
+	versions *maintenance.ListVersionsResponse
+}
+
+func (e *findEntry) Element() maintenance.ListDatasetElement {
+	return e.element
+}
+
+func (e *findEntry) Size() (uint64, error) {
+	if e.element.IsFolder() {
+		return 0, nil
+	}
+	if e.versions == nil {
+		versions, err := e.client.ListVersions(e.ctx, e.element.Path)
+		if err != nil {
+			return 0, err
+		}
+		e.versions = versions
+	}
+	return e.versions.TotalSize(), nil
+}
+
+func (e *findEntry) Empty() (bool, error) {
+	if e.element.IsFolder() {
+		res, err := e.client.ListDatasets(e.ctx, e.element.Path)
+		if err != nil {
+			return false, err
+		}
+		return len(*res) == 0, nil
+	}
+
+	if _, err := e.Size(); err != nil {
+		return false, err
+	}
+	for _, version := range e.versions.Versions {
+		if len(version.Files) > 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// execFindCommand returns the function running the command of an -exec action with the IO streams of env. The
+// dapla command is run with the current executable, so that it behaves the same as the find command itself.
+func execFindCommand(env *Env) func(args []string) (bool, error) {
+	return func(args []string) (bool, error) {
+		name := args[0]
+		if name == "dapla" {
+			if executable, err := os.Executable(); err == nil {
+				name = executable
+			}
+		}
+
+		command := exec.Command(name, args[1:]...)
+		command.Stdin, command.Stdout, command.Stderr = env.Stdin, env.Stdout, env.Stderr
+		err := command.Run()
+		if _, ok := err.(*exec.ExitError); ok {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("could not run %s: %v", args[0], err)
+		}
+		return true, nil
+	}
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/statisticsnorway/dapla-cli/find"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestRunFind(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Get("/api/v1/list//skatt$").
+		Reply(http.StatusOK).
+		BodyString(`[{"path": "/skatt/person", "depth": 1},{"path": "/skatt/tom", "depth": 1},{"path": "/skatt/inntekt", "depth": 0}]`)
+	gock.New("http://server.com").
+		Get("/api/v1/list//skatt/person$").
+		Times(2).
+		Reply(http.StatusOK).
+		BodyString(`[{"path": "/skatt/person/adresse", "depth": 0}]`)
+	gock.New("http://server.com").
+		Get("/api/v1/list//skatt/tom$").
+		Times(2).
+		Reply(http.StatusOK).
+		BodyString(`[]`)
+	gock.New("http://server.com").
+		Get("/api/v1/versions//skatt/person/adresse").
+		Reply(http.StatusOK).
+		BodyString(`{"datasetPath": "/skatt/person/adresse", "versions": [{"timestamp": "2021-01-01T00:00:00Z", "files": []}]}`)
+	gock.New("http://server.com").
+		Get("/api/v1/versions//skatt/inntekt").
+		Reply(http.StatusOK).
+		BodyString(`{"datasetPath": "/skatt/inntekt", "versions": [
+			{"timestamp": "2021-01-01T00:00:00Z", "files": [{"uri": "gs://bucket/skatt/inntekt/v1/file1", "size": 2048}]}
+		]}`)
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	expr, err := find.Parse([]string{"-empty", "-o", "-size", "+1K"}, time.Now())
+	assert.Nil(t, err)
+
+	var output bytes.Buffer
+	client := maintenance.NewClient("http://server.com", "a secret secret")
+	err = runFind(context.Background(), client, []string{"/skatt"}, expr, &find.Env{Out: &output})
+	assert.Nil(t, err)
+	assert.Equal(t, "/skatt/person/adresse\n/skatt/tom\n/skatt/inntekt\n", output.String())
+}
@@ -2,9 +2,9 @@ package cmd
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
-	"os"
 	"strings"
 	"text/tabwriter"
 	"time"
@@ -14,44 +14,50 @@ import (
 	"github.com/statisticsnorway/dapla-cli/maintenance"
 )
 
-var (
-	lsLong bool
-)
-
-func newLsCommand() *cobra.Command {
-	return &cobra.Command{
+func newLsCommand(env *Env) *cobra.Command {
+	var long bool
+	lsCommand := &cobra.Command{
 		Use:   "ls [PATH]...",
 		Short: "List the datasets and folders under a PATH",
 		Long:  `The ls command list the datasets and folders under a given PATH.`,
-		Args:  cobra.MinimumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-
-			var client = maintenance.NewClient(apiURLOf(APINameDataMaintenanceSvc), authToken())
+		Args: func(cmd *cobra.Command, args []string) error {
+			// The working folder of the shell, or the home folder, is listed by default
+			if env.workingDir() != "" {
+				return nil
+			}
+			return cobra.MinimumNArgs(1)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				args = []string{env.workingDir()}
+			}
+			client, err := env.Maintenance()
+			if err != nil {
+				return err
+			}
 
-			// Use newline when not in terminal (piped)
+			// Use newline when not in terminal (piped), and print the datasets as they arrive
 			var printFunction func(datasets *maintenance.ListDatasetResponse, output io.Writer)
-			if fileInfo, _ := os.Stdout.Stat(); (fileInfo.Mode() & os.ModeCharDevice) != 0 {
-				if lsLong {
+			if isTerminal(env.Stdout) {
+				if long {
 					printFunction = printTabularDetails
 				} else {
 					printFunction = printTabular
 				}
-			} else {
-				printFunction = printNewLine
 			}
 
-			for _, path := range args {
-				res, err := client.ListDatasets(path)
+			for _, path := range env.absPaths(args) {
+				if printFunction == nil {
+					if err := streamNewLine(cmd.Context(), client, path, env.Stdout); err != nil {
+						return err
+					}
+					continue
+				}
+
+				res, err := client.ListDatasets(cmd.Context(), path)
 
 				if err != nil {
-					exitCode := 1
-					switch err.(type) {
-					case *maintenance.HTTPError:
-						exitCode = 0
-					default:
-					}
-					fmt.Println(err.Error() + "\n")
-					os.Exit(exitCode)
+					return reportAPIError(env, err)
 				} else if res != nil {
 					// Strip the common prefix. Note that we are mutating the
 					// elements of res and therefore need to use index notation.
@@ -59,35 +65,31 @@ func newLsCommand() *cobra.Command {
 					for i := 0; i < len(*res); i++ {
 						(*res)[i].Path = strings.TrimPrefix((*res)[i].Path, prefix)
 					}
-					printFunction(res, os.Stdout)
+					printFunction(res, env.Stdout)
 				} else {
 					// TODO what to do if no error and response is nil
 				}
 			}
-
+			return nil
 		},
-		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-
-			// TODO make test(s)!
 
-			return doAutoComplete(toComplete)
-		},
+		// TODO make test(s)!
+		ValidArgsFunction: completePath(env),
 	}
+	lsCommand.Flags().BoolVarP(&long, "", "l", false, "use a long listing format")
+	return lsCommand
 }
 
-func init() {
-	lsCommand := newLsCommand()
-	lsCommand.Flags().BoolVarP(&lsLong, "", "l", false, "use a long listing format")
-	rootCmd.AddCommand(lsCommand)
-}
-
-// printNewLine prints the dataset names
-func printNewLine(datasets *maintenance.ListDatasetResponse, output io.Writer) {
+// streamNewLine prints the dataset names under path, relative to path, as they are received
+func streamNewLine(ctx context.Context, client maintenance.API, path string, output io.Writer) error {
 	writer := bufio.NewWriter(output)
 	defer writer.Flush()
-	for _, dataset := range *datasets {
-		fmt.Fprintln(writer, dataset.Path)
-	}
+
+	var prefix = strings.TrimSuffix(path, "/") + "/"
+	return client.IterateDatasets(ctx, path, func(element maintenance.ListDatasetElement) error {
+		_, err := fmt.Fprintln(writer, strings.TrimPrefix(element.Path, prefix))
+		return err
+	})
 }
 
 type colorWriter struct {
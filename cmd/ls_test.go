@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"bytes"
+	"context"
+	"net/http"
 	"strings"
 	"testing"
 	"time"
@@ -9,31 +11,32 @@ import (
 	"github.com/acarl005/stripansi"
 	"github.com/andreyvit/diff"
 	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"gopkg.in/h2non/gock.v1"
 )
 
-func TestPrintNewLine(t *testing.T) {
+func TestStreamNewLine(t *testing.T) {
+	defer gock.Off()
 
 	tests := []struct {
-		response       maintenance.ListDatasetResponse
+		path           string
+		response       string
 		expectedOutput string
 	}{
-		{maintenance.ListDatasetResponse{
-			maintenance.ListDatasetElement{Path: "/foo/bar"},
-			maintenance.ListDatasetElement{Path: "/foo/baz"},
-		},
-			"/foo/bar\n/foo/baz",
-		},
-		{maintenance.ListDatasetResponse{
-			maintenance.ListDatasetElement{Path: "/foo2/bar"},
-			maintenance.ListDatasetElement{Path: "/foo2/baz"},
-		},
-			"/foo2/bar\n/foo2/baz",
-		},
+		{"/foo", `[{"path": "/foo/bar"}, {"path": "/foo/baz"}]`, "bar\nbaz"},
+		{"/foo2/", `{"datasets": [{"path": "/foo2/bar"}, {"path": "/foo2/baz"}]}`, "bar\nbaz"},
 	}
 
+	client := maintenance.NewClient("http://server.com", "a secret secret")
 	for _, values := range tests {
+		gock.New("http://server.com").
+			Get("/api/v1/list/" + values.path).
+			Reply(http.StatusOK).
+			BodyString(values.response)
+
 		var output bytes.Buffer
-		printNewLine(&values.response, &output)
+		if err := streamNewLine(context.Background(), client, values.path, &output); err != nil {
+			t.Fatalf("Got error %v", err)
+		}
 
 		if actual, expected := strings.TrimSpace(output.String()),
 			strings.TrimSpace(values.expectedOutput); actual != expected {
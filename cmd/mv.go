@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+)
+
+// conflictPolicy is a flag value that only accepts the conflict policies supported by the data-maintenance API
+type conflictPolicy maintenance.ConflictPolicy
+
+func (c *conflictPolicy) String() string {
+	return string(*c)
+}
+
+func (c *conflictPolicy) Set(value string) error {
+	switch maintenance.ConflictPolicy(value) {
+	case maintenance.ConflictFail, maintenance.ConflictSkip, maintenance.ConflictOverwrite:
+		*c = conflictPolicy(value)
+		return nil
+	}
+	return fmt.Errorf("must be one of %s, %s, %s", maintenance.ConflictFail, maintenance.ConflictSkip, maintenance.ConflictOverwrite)
+}
+
+func (c *conflictPolicy) Type() string {
+	return "policy"
+}
+
+func completeConflictPolicy(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return []string{
+		string(maintenance.ConflictFail),
+		string(maintenance.ConflictSkip),
+		string(maintenance.ConflictOverwrite),
+	}, cobra.ShellCompDirectiveNoFileComp
+}
+
+func newMvCommand(env *Env) *cobra.Command {
+	var recursive, dryRun, override bool
+	var output outputFormat
+	conflict := conflictPolicy(maintenance.ConflictFail)
+	mvCommand := &cobra.Command{
+		Use:   "mv [SOURCE] [DESTINATION]",
+		Short: "Move or rename dataset(s)",
+		Long: `The mv command moves (renames) the dataset at SOURCE to DESTINATION, including all its versions.
+
+Use --recursive to move all datasets under the folder SOURCE to the folder DESTINATION, keeping the folder
+structure. The --conflict flag decides what happens if a destination dataset already exists: fail (the
+default) stops the move, skip leaves both datasets as they are, and overwrite replaces the destination.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := env.Maintenance()
+			if err != nil {
+				return err
+			}
+
+			src, dst := env.absPath(args[0]), env.absPath(args[1])
+			moves := [][2]string{{src, dst}}
+			if recursive {
+				moves, err = planFolderTargets(cmd.Context(), client, src, dst)
+				if err != nil {
+					return err
+				}
+			}
+
+			protected, err := newProtection(cmd.Context(), env, client, override)
+			if err != nil {
+				return err
+			}
+			for _, move := range moves {
+				if err := protected.check(move[0]); err != nil {
+					return err
+				}
+				if conflict == conflictPolicy(maintenance.ConflictOverwrite) {
+					if err := protected.check(move[1]); err != nil {
+						return err
+					}
+				}
+			}
+
+			results := []*maintenance.MoveDatasetResponse{}
+			for _, move := range moves {
+				spinner := env.spinner("Moving dataset " + move[0])
+				var res *maintenance.MoveDatasetResponse
+				res, err = client.MoveDataset(cmd.Context(), move[0], move[1], maintenance.ConflictPolicy(conflict), dryRun)
+				spinner.Stop()
+				if err != nil {
+					err = fmt.Errorf("could not move %s to %s: %v", move[0], move[1], err)
+					break
+				}
+				results = append(results, res)
+			}
+
+			if output == outputJSON {
+				if err := printJSON(struct {
+					Moves  []*maintenance.MoveDatasetResponse `json:"moves"`
+					DryRun bool                               `json:"dryRun"`
+				}{results, dryRun}, env.Stdout); err != nil {
+					return err
+				}
+			} else {
+				printMoveResults(results, env.Stdout, dryRun)
+			}
+			return err
+		},
+		ValidArgsFunction: completePath(env),
+	}
+	mvCommand.Flags().BoolVarP(&recursive, "recursive", "", false, "move all datasets under a folder")
+	mvCommand.Flags().BoolVarP(&dryRun, "dry-run", "", false, "dry run")
+	mvCommand.Flags().Var(&conflict, "conflict", "what to do if a destination already exists (fail, skip or overwrite)")
+	mvCommand.RegisterFlagCompletionFunc("conflict", completeConflictPolicy)
+	addOutputFlag(mvCommand, &output)
+	addOverrideProtectionFlag(mvCommand, &override)
+	return mvCommand
+}
+
+// planFolderTargets returns the source and destination path of every dataset under the folder src, when the folder
+// is moved or copied to the folder dst. The folders are made absolute, so that they match the paths returned by the
+// server, and an error is returned if the server lists a dataset that is not under src.
+func planFolderTargets(ctx context.Context, client maintenance.API, src string, dst string) ([][2]string, error) {
+	src = path.Clean("/" + src)
+	dst = path.Clean("/" + dst)
+	prefix := strings.TrimSuffix(src, "/") + "/"
+
+	var moves [][2]string
+	err := maintenance.WalkDatasets(ctx, client, src, func(element maintenance.ListDatasetElement) error {
+		if !element.IsDataset() {
+			return nil
+		}
+		if !strings.HasPrefix(element.Path, prefix) {
+			return fmt.Errorf("dataset %s is not under %s", element.Path, src)
+		}
+		moves = append(moves, [2]string{element.Path, path.Join(dst, strings.TrimPrefix(element.Path, prefix))})
+		return nil
+	})
+	if err == nil && len(moves) == 0 {
+		err = fmt.Errorf("could not find any datasets under %s", src)
+	}
+	return moves, err
+}
+
+// Output:
+// > dapla mv --recursive --conflict skip /foo /bar
+//  moved    /foo/a -> /bar/a
+//  skipped  /foo/b -> /bar/b (destination exists)
+//  Moved 1 dataset, skipped 1
+func printMoveResults(results []*maintenance.MoveDatasetResponse, output io.Writer, dryRun bool) {
+	writer := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+
+	moved, skipped := 0, 0
+	for _, res := range results {
+		if res.Status == maintenance.MoveStatusSkipped {
+			skipped++
+			fmt.Fprintf(writer, "%s\t%s -> %s (destination exists)\n", res.Status, res.Source, res.Destination)
+		} else {
+			moved++
+			fmt.Fprintf(writer, "%s\t%s -> %s\n", res.Status, res.Source, res.Destination)
+		}
+	}
+	writer.Flush()
+
+	fmt.Fprintf(output, "Moved %d %s, skipped %d\n", moved, pluralize("dataset", moved), skipped)
+	if dryRun {
+		fmt.Fprintln(output, "The dry-run flag was set. NOTHING WAS MOVED.")
+	}
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/andreyvit/diff"
+	"github.com/google/go-cmp/cmp"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestPlanFolderMove(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Get("/api/v1/list//foo$").
+		Reply(http.StatusOK).
+		BodyString(`[{"path": "/foo/a", "depth": 0},{"path": "/foo/sub", "depth": 1}]`)
+	gock.New("http://server.com").
+		Get("/api/v1/list//foo/sub$").
+		Reply(http.StatusOK).
+		BodyString(`[{"path": "/foo/sub/b", "depth": 0}]`)
+
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	client := maintenance.NewClient("http://server.com", "a secret secret")
+	moves, err := planFolderTargets(context.Background(), client, "/foo/", "/bar")
+	if err != nil {
+		t.Fatalf("Got error %v", err)
+	}
+
+	expected := [][2]string{{"/foo/a", "/bar/a"}, {"/foo/sub/b", "/bar/sub/b"}}
+	if !cmp.Equal(expected, moves) {
+		t.Errorf("Expected %v, but got %v", expected, moves)
+	}
+}
+
+func TestPlanFolderMove_RelativeSource(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Get("/api/v1/list//foo$").
+		Reply(http.StatusOK).
+		BodyString(`[{"path": "/foo/a", "depth": 0}]`)
+
+	client := maintenance.NewClient("http://server.com", "a secret secret")
+	moves, err := planFolderTargets(context.Background(), client, "foo", "bar")
+	if err != nil {
+		t.Fatalf("Got error %v", err)
+	}
+
+	expected := [][2]string{{"/foo/a", "/bar/a"}}
+	if !cmp.Equal(expected, moves) {
+		t.Errorf("Expected %v, but got %v", expected, moves)
+	}
+}
+
+func TestPlanFolderMove_NotUnderSource(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Get("/api/v1/list//foo$").
+		Reply(http.StatusOK).
+		BodyString(`[{"path": "/other/a", "depth": 0}]`)
+
+	client := maintenance.NewClient("http://server.com", "a secret secret")
+	_, err := planFolderTargets(context.Background(), client, "/foo", "/bar")
+	if err == nil || err.Error() != "dataset /other/a is not under /foo" {
+		t.Errorf("Expected error, but got %v", err)
+	}
+}
+
+func TestPrintMoveResults(t *testing.T) {
+	var output bytes.Buffer
+	printMoveResults([]*maintenance.MoveDatasetResponse{
+		{Source: "/foo/a", Destination: "/bar/a", Status: maintenance.MoveStatusMoved},
+		{Source: "/foo/b", Destination: "/bar/b", Status: maintenance.MoveStatusSkipped},
+	}, &output, true)
+
+	expected := "moved    /foo/a -> /bar/a\n" +
+		"skipped  /foo/b -> /bar/b (destination exists)\n" +
+		"Moved 1 dataset, skipped 1\n" +
+		"The dry-run flag was set. NOTHING WAS MOVED."
+	if actual := strings.TrimSpace(output.String()); actual != expected {
+		t.Errorf("Result not as expected:\n%v", diff.LineDiff(expected, actual))
+	}
+}
+
+func TestConflictPolicyFlag(t *testing.T) {
+	var policy conflictPolicy
+	if err := policy.Set("overwrite"); err != nil || maintenance.ConflictPolicy(policy) != maintenance.ConflictOverwrite {
+		t.Errorf("Expected overwrite, but got %v (%v)", policy, err)
+	}
+	if err := policy.Set("merge"); err == nil {
+		t.Errorf("Expected error for unknown conflict policy")
+	}
+}
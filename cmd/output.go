@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// Output formats supported by the --output flag
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// outputFormat is a flag value that selects how a command prints its results
+type outputFormat string
+
+func (o *outputFormat) String() string {
+	return string(*o)
+}
+
+func (o *outputFormat) Set(value string) error {
+	if value != outputText && value != outputJSON {
+		return fmt.Errorf("must be one of %s, %s", outputText, outputJSON)
+	}
+	*o = outputFormat(value)
+	return nil
+}
+
+func (o *outputFormat) Type() string {
+	return "format"
+}
+
+// addOutputFlag adds the --output flag to a command
+func addOutputFlag(cmd *cobra.Command, format *outputFormat) {
+	*format = outputText
+	cmd.Flags().VarP(format, "output", "o", "output format (text or json)")
+	cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{outputText, outputJSON}, cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// printJSON prints a value as indented JSON
+func printJSON(value interface{}, output io.Writer) error {
+	encoder := json.NewEncoder(output)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// formatSize formats a size in bytes. If human is true, the size is formatted using binary unit prefixes
+// (K, M, G, ...), with one decimal for values below 10.
+func formatSize(size uint64, human bool) string {
+	if !human {
+		return strconv.FormatUint(size, 10)
+	}
+	if size < 1024 {
+		return fmt.Sprintf("%dB", size)
+	}
+
+	value := float64(size)
+	unit := -1
+	for value >= 1024 && unit < len(sizeUnits)-1 {
+		value /= 1024
+		unit++
+	}
+	if value < 10 {
+		return fmt.Sprintf("%.1f%s", value, sizeUnits[unit])
+	}
+	return fmt.Sprintf("%.0f%s", value, sizeUnits[unit])
+}
+
+var sizeUnits = []string{"K", "M", "G", "T", "P", "E"}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		size     uint64
+		human    bool
+		expected string
+	}{
+		{1536, false, "1536"},
+		{0, true, "0B"},
+		{1023, true, "1023B"},
+		{1024, true, "1.0K"},
+		{1536, true, "1.5K"},
+		{10 * 1024, true, "10K"},
+		{123456789, true, "118M"},
+		{5 * 1024 * 1024 * 1024, true, "5.0G"},
+	}
+
+	for _, test := range tests {
+		if actual := formatSize(test.size, test.human); actual != test.expected {
+			t.Errorf("Expected %d to be formatted as %s, but got %s", test.size, test.expected, actual)
+		}
+	}
+}
+
+func TestOutputFormatFlag(t *testing.T) {
+	var format outputFormat
+	if err := format.Set("json"); err != nil || format != outputJSON {
+		t.Errorf("Expected json, but got %v (%v)", format, err)
+	}
+	if err := format.Set("yaml"); err == nil {
+		t.Errorf("Expected error for unknown output format")
+	}
+}
@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/statisticsnorway/dapla-cli/plugin"
+)
+
+// pluginAnnotation marks the commands running plugins, and holds the path of the plugin
+const pluginAnnotation = "dapla-plugin"
+
+// pluginCompleteTimeout is how long plugins may take to complete their arguments
+const pluginCompleteTimeout = 5 * time.Second
+
+func newPluginCommand(env *Env) *cobra.Command {
+	pluginCommand := &cobra.Command{
+		Use:   "plugin",
+		Short: "Manage plugins",
+		Long: `Plugins are executables named dapla-NAME on the PATH, which are run as the dapla NAME command, e.g.
+dapla-quality is run by "dapla quality". The arguments after NAME are passed on to the plugin as they are, while
+global flags given before NAME apply as usual, e.g. "dapla --jupyter quality --strict /skatt".
+
+Plugins receive the resolved configuration in environment variables:
+
+  DAPLA_API_<NAME>     the URL of each API, e.g. DAPLA_API_DATA_MAINTENANCE and DAPLA_API_DAPLA_PSEUDO_SERVICE
+  DAPLA_AUTHTOKEN      the auth token, if one could be retrieved
+  DAPLA_WORKING_DIR    the working folder of the dapla shell, or the home folder, if any
+  DAPLA_DEBUG          true if --debug is given
+  DAPLA_VERSION        the version of dapla
+  DAPLA_CONTEXT        all of the above as JSON
+
+Plugins that are written with cobra, or otherwise answer the hidden __complete command like cobra does, have their
+arguments completed by TAB. DAPLA_AUTHTOKEN is not set when completing. Plugins can not replace the built-in commands.`,
+	}
+	pluginCommand.AddCommand(newPluginListCommand(env), newPluginExecCommand(env))
+	return pluginCommand
+}
+
+func newPluginListCommand(env *Env) *cobra.Command {
+	var output outputFormat
+	listCommand := &cobra.Command{
+		Use:   "list",
+		Short: "List the plugins on the PATH",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plugins := plugin.Find(env.PluginPath)
+			builtins := builtinCommands(cmd.Root())
+
+			if output == outputJSON {
+				return printJSON(plugins, env.Stdout)
+			}
+			if len(plugins) == 0 {
+				fmt.Fprintln(env.Stderr, "No plugins found on the PATH")
+				return nil
+			}
+			writer := tabwriter.NewWriter(env.Stdout, 0, 0, 2, ' ', 0)
+			for _, p := range plugins {
+				fmt.Fprintf(writer, "%s\t%s\n", p.Name, p.Path)
+			}
+			writer.Flush()
+			for _, p := range plugins {
+				if builtins[p.Name] {
+					fmt.Fprintf(env.Stderr, "Warning: %s is not run, since it has the name of the %s command\n", p.Path, p.Name)
+				}
+				for _, shadowed := range p.Shadowed {
+					fmt.Fprintf(env.Stderr, "Warning: %s is not run, since it is shadowed by %s\n", shadowed, p.Path)
+				}
+			}
+			return nil
+		},
+	}
+	addOutputFlag(listCommand, &output)
+	return listCommand
+}
+
+// newPluginExecCommand creates the hidden command that runs plugins, see splitPluginArgs
+func newPluginExecCommand(env *Env) *cobra.Command {
+	return &cobra.Command{
+		Use:    "exec NAME [-- ARGS...]",
+		Short:  "Run a plugin",
+		Hidden: true,
+		Args:   cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, p := range plugin.Find(env.PluginPath) {
+				if p.Name == args[0] {
+					return runPlugin(cmd.Context(), env, p, args[1:])
+				}
+			}
+			return fmt.Errorf("plugin %s%s not found on the PATH", plugin.Prefix, args[0])
+		},
+	}
+}
+
+// addPluginCommands adds a command running each plugin on the PATH of env to rootCmd, except the plugins with the
+// name of a built-in command. The commands are listed in the help, and complete their arguments by asking the
+// plugins.
+func addPluginCommands(rootCmd *cobra.Command, env *Env) {
+	if env.PluginPath == "" {
+		return
+	}
+	builtins := builtinCommands(rootCmd)
+	for _, p := range plugin.Find(env.PluginPath) {
+		if builtins[p.Name] {
+			continue
+		}
+		p := p
+		rootCmd.AddCommand(&cobra.Command{
+			Use:                p.Name,
+			Short:              "Run the " + plugin.Prefix + p.Name + " plugin",
+			Annotations:        map[string]string{pluginAnnotation: p.Path},
+			DisableFlagParsing: true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runPlugin(cmd.Context(), env, p, args)
+			},
+			ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+				ctx, cancel := context.WithTimeout(context.Background(), pluginCompleteTimeout)
+				defer cancel()
+				// The auth token is only given to plugins when they are run, since retrieving it may take a request
+				// to jupyter on every TAB
+				completions, directive, err := p.Complete(ctx, args, toComplete, pluginContext(env, false))
+				if err != nil {
+					cobra.CompDebugln(err.Error(), false)
+					return nil, cobra.ShellCompDirectiveDefault
+				}
+				return completions, cobra.ShellCompDirective(directive)
+			},
+		})
+	}
+}
+
+// builtinCommands returns the names and aliases of the commands of rootCmd, which plugins can not replace
+func builtinCommands(rootCmd *cobra.Command) map[string]bool {
+	builtins := map[string]bool{"help": true}
+	for _, command := range rootCmd.Commands() {
+		if command.Annotations[pluginAnnotation] != "" {
+			continue
+		}
+		builtins[command.Name()] = true
+		for _, alias := range command.Aliases {
+			builtins[alias] = true
+		}
+	}
+	return builtins
+}
+
+// splitPluginArgs splits the args of the dapla command into the global flags before the name of a plugin, and the
+// arguments after it, which all belong to the plugin, even if they look like flags. Returns false if args do not run a
+// plugin.
+func splitPluginArgs(rootCmd *cobra.Command, args []string) (flags []string, name string, pluginArgs []string, ok bool) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return nil, "", nil, false
+		case arg == "-" || !strings.HasPrefix(arg, "-"):
+			for _, command := range rootCmd.Commands() {
+				if command.Name() == arg && command.Annotations[pluginAnnotation] != "" {
+					return args[:i], arg, args[i+1:], true
+				}
+			}
+			return nil, "", nil, false
+		case strings.Contains(arg, "="):
+			// The value is part of the flag, e.g. --config=dapla.yml
+		case strings.HasPrefix(arg, "--"):
+			if takesValue(rootCmd.PersistentFlags().Lookup(arg[2:])) || takesValue(rootCmd.Flags().Lookup(arg[2:])) {
+				i++
+			}
+		case len(arg) == 2:
+			if takesValue(rootCmd.PersistentFlags().ShorthandLookup(arg[1:])) {
+				i++
+			}
+		}
+	}
+	return nil, "", nil, false
+}
+
+// runPlugin runs the plugin p with args and the streams of env, and returns an exitError with the exit code of the
+// plugin if it fails
+func runPlugin(ctx context.Context, env *Env, p plugin.Plugin, args []string) error {
+	// The input of the dapla shell may be buffered, but the terminal can be handed to the plugin as it is
+	var stdin io.Reader = env.prompts()
+	if file, ok := env.Stdin.(*os.File); ok {
+		stdin = file
+	}
+	code, err := p.Run(ctx, args, pluginContext(env, true), stdin, env.Stdout, env.Stderr)
+	if err != nil {
+		return fmt.Errorf("could not run %s: %v", p.Path, err)
+	}
+	if code != 0 {
+		return &exitError{code}
+	}
+	return nil
+}
+
+// pluginContext returns the configuration passed on to plugins, with the auth token if withToken is true. APIs whose
+// URLs can not be resolved, and the auth token if it can not be retrieved, are left out, since the plugin may not need
+// them.
+func pluginContext(env *Env, withToken bool) plugin.Context {
+	apis := map[string]string{}
+	for name := range env.Config.GetStringMapString(CFGAPIs) {
+		if apiURL, err := apiURLOrError(env.Config, name); err == nil {
+			apis[name] = apiURL
+		}
+	}
+	var token string
+	if withToken {
+		var err error
+		token, err = authTokenOrError(env.Config)
+		if err != nil && env.Config.GetBool(CFGDebug) {
+			fmt.Fprintln(env.Stderr, "No auth token for the plugin:", err)
+		}
+	}
+	return plugin.Context{
+		APIs:       apis,
+		AuthToken:  token,
+		WorkingDir: env.workingDir(),
+		Debug:      env.Config.GetBool(CFGDebug),
+		Version:    Version,
+	}
+}
+
+func takesValue(flag *pflag.Flag) bool {
+	return flag != nil && flag.NoOptDefVal == ""
+}
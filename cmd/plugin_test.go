@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newPluginTestEnv returns a test Env with a plugin dir holding the dapla-greet plugin, which prints its arguments
+// and the context it receives, and answers completion requests, and the dapla-ls plugin, which is never run
+func newPluginTestEnv(t *testing.T, input string) (*Env, string, *bytes.Buffer, *bytes.Buffer) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	dir := t.TempDir()
+	for name, script := range map[string]string{
+		"greet": `if [ "$1" = __complete ]; then echo hello; echo "hei$DAPLA_AUTHTOKEN"; echo :4; exit; fi
+echo "args: $*"
+echo "api: $DAPLA_API_DATA_MAINTENANCE"
+echo "token: $DAPLA_AUTHTOKEN"
+echo "dir: $DAPLA_WORKING_DIR"
+echo "debug: $DAPLA_DEBUG"
+[ "$1" = fail ] && exit 3
+exit 0`,
+		"ls": "echo not me",
+	} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "dapla-"+name), []byte("#!/bin/sh\n"+script+"\n"), 0755))
+	}
+	env, stdout, stderr := newTestEnv(newTestAPI(), input)
+	env.PluginPath = dir
+	return env, dir, stdout, stderr
+}
+
+func TestRunPlugin(t *testing.T) {
+	env, _, stdout, stderr := newPluginTestEnv(t, "")
+
+	code := Run(context.Background(), env, []string{
+		"--authtoken", "token", "--apis", "data-maintenance=http://maintenance", "-d", "greet", "--debug", "-x", "--", "world",
+	})
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, "args: --debug -x -- world\napi: http://maintenance\ntoken: token\ndir: \ndebug: true\n", stdout.String())
+
+	env, _, _, _ = newPluginTestEnv(t, "")
+	assert.Equal(t, 3, Run(context.Background(), env, []string{"greet", "fail"}))
+
+	// Plugins with the name of a built-in command are not run
+	env, _, stdout, _ = newPluginTestEnv(t, "")
+	assert.Equal(t, 0, Run(context.Background(), env, []string{"ls", "/skatt"}))
+	assert.Equal(t, "2020\nformue\ninntekt\n", stdout.String())
+}
+
+func TestRunPlugin_Shell(t *testing.T) {
+	env, _, stdout, _ := newPluginTestEnv(t, "cd /skatt\ngreet\n")
+
+	assert.Equal(t, 0, Run(context.Background(), env, []string{"shell"}))
+	assert.Contains(t, stdout.String(), "dir: /skatt\n")
+}
+
+func TestRunPluginList(t *testing.T) {
+	env, dir, stdout, stderr := newPluginTestEnv(t, "")
+
+	assert.Equal(t, 0, Run(context.Background(), env, []string{"plugin", "list"}))
+	assert.Equal(t, "greet  "+filepath.Join(dir, "dapla-greet")+"\nls     "+filepath.Join(dir, "dapla-ls")+"\n", stdout.String())
+	assert.Equal(t, "Warning: "+filepath.Join(dir, "dapla-ls")+" is not run, since it has the name of the ls command\n", stderr.String())
+}
+
+func TestCompletePlugin(t *testing.T) {
+	env, _, stdout, _ := newPluginTestEnv(t, "")
+
+	assert.Equal(t, 0, Run(context.Background(), env, []string{"__complete", "gr"}))
+	assert.Contains(t, stdout.String(), "greet\tRun the dapla-greet plugin\n")
+
+	// Plugins are not given the auth token when completing
+	env, _, stdout, _ = newPluginTestEnv(t, "")
+	env.Config.Set(CFGAuthToken, "token")
+	assert.Equal(t, 0, Run(context.Background(), env, []string{"__complete", "greet", "h"}))
+	assert.Equal(t, "hello\nhei\n:4\n", stdout.String())
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/statisticsnorway/dapla-cli/glob"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+)
+
+// addOverrideProtectionFlag adds the --override-protection flag to a destructive command
+func addOverrideProtectionFlag(cmd *cobra.Command, override *bool) {
+	cmd.Flags().BoolVar(override, "override-protection", false,
+		"allow protected paths, after confirming each path by typing it")
+}
+
+// protection decides whether destructive commands may touch a dataset path. Paths matching one of the protected
+// path globs are refused, unless the protection is overridden and the user confirms by typing the path.
+type protection struct {
+	patterns  []string
+	override  bool
+	confirmed map[string]bool
+	input     *bufio.Reader
+	prompt    io.Writer
+}
+
+// newProtection returns the protection made up of the globs in the protected-paths config and the globs supplied
+// by the server. Servers that do not supply any protected paths are ignored. Confirmations are prompted for on the
+// stderr of env.
+func newProtection(ctx context.Context, env *Env, client maintenance.API, override bool) (*protection, error) {
+	patterns := env.Config.GetStringSlice(CFGProtectedPaths)
+	serverPatterns, err := client.ListProtectedPaths(ctx)
+	if httpErr, ok := err.(*maintenance.HTTPError); ok && httpErr.StatusCode() == http.StatusNotFound {
+		err = nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch protected paths: %v", err)
+	}
+	patterns = append(patterns, serverPatterns...)
+
+	for _, pattern := range patterns {
+		if err := glob.Validate(pattern); err != nil {
+			return nil, fmt.Errorf("invalid protected path %q: %v", pattern, err)
+		}
+	}
+	return &protection{
+		patterns:  patterns,
+		override:  override,
+		confirmed: map[string]bool{},
+		input:     env.prompts(),
+		prompt:    env.Stderr,
+	}, nil
+}
+
+// match returns the first protected path glob matching path, or an empty string if the path is not protected
+func (p *protection) match(path string) string {
+	for _, pattern := range p.patterns {
+		if glob.Match(pattern, path) {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// skip reports and returns true if path is protected and the protection is not overridden. It is used by
+// recursive commands to leave protected datasets alone, while carrying on with the rest.
+func (p *protection) skip(path string) bool {
+	pattern := p.match(path)
+	if pattern == "" || p.override {
+		return false
+	}
+	fmt.Fprintf(p.prompt, "Skipping dataset %s, protected by %s\n", path, pattern)
+	return true
+}
+
+// check returns an error if path is protected, unless the protection is overridden and the user confirms by
+// typing the path. A path is only confirmed once.
+func (p *protection) check(path string) error {
+	pattern := p.match(path)
+	if pattern == "" || p.confirmed[path] {
+		return nil
+	}
+	if !p.override {
+		return fmt.Errorf("%s is protected by %s (use --override-protection to change it anyway)", path, pattern)
+	}
+
+	fmt.Fprintf(p.prompt, "%s is protected by %s. Type the path to confirm: ", path, pattern)
+	line, err := p.input.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if strings.TrimSpace(line) != path {
+		return fmt.Errorf("%s is protected by %s and was not confirmed", path, pattern)
+	}
+	p.confirmed[path] = true
+	return nil
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func newTestProtection(override bool, input string) (*protection, *bytes.Buffer) {
+	var prompt bytes.Buffer
+	return &protection{
+		patterns:  []string{"/produkt/**", "/kilde/**"},
+		override:  override,
+		confirmed: map[string]bool{},
+		input:     bufio.NewReader(strings.NewReader(input)),
+		prompt:    &prompt,
+	}, &prompt
+}
+
+func TestProtection_Check(t *testing.T) {
+	protected, _ := newTestProtection(false, "")
+	assert.Nil(t, protected.check("/tmp/foo"))
+	assert.EqualError(t, protected.check("/produkt/foo"),
+		"/produkt/foo is protected by /produkt/** (use --override-protection to change it anyway)")
+	assert.True(t, protected.skip("/kilde/bar"))
+	assert.False(t, protected.skip("/tmp/bar"))
+}
+
+func TestProtection_CheckOverride(t *testing.T) {
+	protected, prompt := newTestProtection(true, "/produkt/foo\n/kilde/wrong\n")
+	assert.Nil(t, protected.check("/produkt/foo"))
+	assert.Equal(t, "/produkt/foo is protected by /produkt/**. Type the path to confirm: ", prompt.String())
+
+	// A confirmed path is not prompted for again
+	assert.Nil(t, protected.check("/produkt/foo"))
+
+	assert.EqualError(t, protected.check("/kilde/bar"), "/kilde/bar is protected by /kilde/** and was not confirmed")
+	assert.False(t, protected.skip("/kilde/bar"))
+}
+
+func TestNewProtection(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Get("/api/v1/protected").
+		Reply(http.StatusOK).
+		BodyString(`["/kilde/**"]`)
+	gock.New("http://server.com").
+		Get("/api/v1/protected").
+		Reply(http.StatusNotFound)
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	client := maintenance.NewClient("http://server.com", "a secret secret")
+	env, _, _ := newTestEnv(client, "")
+	env.Config.Set(CFGProtectedPaths, []string{"/produkt/**"})
+
+	protected, err := newProtection(context.Background(), env, client, false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/produkt/**", "/kilde/**"}, protected.patterns)
+
+	protected, err = newProtection(context.Background(), env, client, false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/produkt/**"}, protected.patterns)
+
+	_, err = newProtection(context.Background(), env, client, false)
+	assert.Contains(t, err.Error(), "could not fetch protected paths")
+}
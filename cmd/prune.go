@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"github.com/statisticsnorway/dapla-cli/retention"
+)
+
+func newPruneCommand(env *Env) *cobra.Command {
+	var keepLast int
+	var keepWithin string
+	var recursive, dryRun, override bool
+	pruneCommand := &cobra.Command{
+		Use:   "prune [PATH]",
+		Short: "Delete old versions of dataset(s)",
+		Long: `The prune command deletes the versions of a dataset that are not kept by the given retention rules.
+
+A version is kept if it is kept by any of the rules. The latest version of a dataset is always kept. Use
+--recursive to prune all datasets under a folder, and --dry-run to see which versions would be deleted.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rule := retention.Rule{KeepLast: keepLast}
+			if keepWithin != "" {
+				duration, err := retention.ParseDuration(keepWithin)
+				if err != nil {
+					return err
+				}
+				rule.KeepWithin = duration
+			}
+			if rule.IsEmpty() {
+				return errors.New("at least one of --keep-last or --keep-within must be specified")
+			}
+
+			client, err := env.Maintenance()
+			if err != nil {
+				return err
+			}
+			protected, err := newProtection(cmd.Context(), env, client, override)
+			if err != nil {
+				return err
+			}
+
+			p := pruner{ctx: cmd.Context(), env: env, client: client, protected: protected, rule: rule, dryRun: dryRun}
+			if recursive {
+				err = p.pruneRecursively(env.absPath(args[0]))
+			} else {
+				err = p.pruneDataset(env.absPath(args[0]))
+			}
+			printPruneSummary(p.summary, env.Stdout, dryRun)
+			return err
+		},
+		ValidArgsFunction: completePath(env),
+	}
+	pruneCommand.Flags().IntVar(&keepLast, "keep-last", 0, "keep the N latest versions")
+	pruneCommand.Flags().StringVar(&keepWithin, "keep-within", "", "keep versions newer than a duration, e.g. 90d, 2w or 36h")
+	pruneCommand.Flags().BoolVarP(&recursive, "recursive", "", false, "prune all datasets under PATH recursively")
+	pruneCommand.Flags().BoolVarP(&dryRun, "dry-run", "", false, "dry run")
+	addOverrideProtectionFlag(pruneCommand, &override)
+	return pruneCommand
+}
+
+// pruneSummary holds the accumulated results of pruning one or more datasets
+type pruneSummary struct {
+	datasets int
+	versions int
+	size     uint64
+}
+
+// pruner deletes the versions of datasets that are not kept by a retention rule, and accumulates the results in
+// its summary
+type pruner struct {
+	ctx       context.Context
+	env       *Env
+	client    maintenance.API
+	protected *protection
+	rule      retention.Rule
+	dryRun    bool
+	summary   pruneSummary
+}
+
+func (p *pruner) pruneRecursively(path string) error {
+	return maintenance.WalkDatasets(p.ctx, p.client, path, func(element maintenance.ListDatasetElement) error {
+		if element.IsDataset() && !p.protected.skip(element.Path) {
+			return p.pruneDataset(element.Path)
+		}
+		return nil
+	})
+}
+
+func (p *pruner) pruneDataset(path string) error {
+	if err := p.protected.check(path); err != nil {
+		return err
+	}
+
+	versions, err := p.client.ListVersions(p.ctx, path)
+	if err != nil {
+		return err
+	}
+
+	expired := p.rule.Expired(versions.Timestamps(), p.env.Now())
+	if len(expired) == 0 {
+		fmt.Fprintf(p.env.Stdout, "Dataset %s (%d %s): nothing to prune\n", path, len(versions.Versions),
+			pluralize("version", len(versions.Versions)))
+		return nil
+	}
+
+	spinner := p.env.spinner("Pruning dataset " + path)
+	res, err := p.client.DeleteDatasetVersions(p.ctx, path, expired, p.dryRun)
+	spinner.Stop()
+	// The server may report deleted versions along with an error, which are gone all the same
+	if res != nil {
+		printPruneResponse(res, len(versions.Versions), p.env.Stdout)
+		p.summary.datasets++
+		p.summary.versions += len(res.DatasetVersion)
+		p.summary.size += res.TotalSize
+	}
+	return err
+}
+
+// Output:
+// > dapla prune --keep-last 2 /foo/bar
+//  Dataset /foo/bar: pruned 2 of 4 versions (3B)
+//  	2000-01-01T00:00:00Z (2 files)
+//  	2000-01-02T00:00:00Z (1 file)
+//  Pruned 2 versions in 1 dataset, 3B reclaimed
+func printPruneResponse(res *maintenance.DeleteDatasetResponse, totalVersions int, output io.Writer) {
+	writer := bufio.NewWriter(output)
+	defer writer.Flush()
+
+	fmt.Fprintf(writer, "Dataset %s: pruned %d of %d %s (%s)\n",
+		res.DatasetPath, len(res.DatasetVersion), totalVersions, pluralize("version", totalVersions),
+		formatSize(res.TotalSize, true))
+	for _, version := range res.DatasetVersion {
+		fmt.Fprintf(writer, "\t%s (%d %s)\n", version.Timestamp.Format(time.RFC3339Nano),
+			len(version.DeletedFiles), pluralize("file", len(version.DeletedFiles)))
+	}
+}
+
+func printPruneSummary(summary pruneSummary, output io.Writer, dryRun bool) {
+	writer := bufio.NewWriter(output)
+	defer writer.Flush()
+
+	fmt.Fprintf(writer, "Pruned %d %s in %d %s, %s reclaimed\n",
+		summary.versions, pluralize("version", summary.versions),
+		summary.datasets, pluralize("dataset", summary.datasets),
+		formatSize(summary.size, true))
+	if dryRun {
+		fmt.Fprintf(writer, "The dry-run flag was set. NO FILES WERE DELETED.\n")
+	}
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andreyvit/diff"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"github.com/statisticsnorway/dapla-cli/retention"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintPruneResponse(t *testing.T) {
+	res := maintenance.DeleteDatasetResponse{
+		DatasetPath: "/foo/bar",
+		TotalSize:   3,
+		DatasetVersion: []maintenance.DatasetVersion{
+			{
+				Timestamp: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+				DeletedFiles: []maintenance.DatasetFile{
+					{URI: "gs://bucket/prefix/foo/bar/v1/file1", Size: 1},
+					{URI: "gs://bucket/prefix/foo/bar/v1/file2", Size: 1},
+				},
+			},
+			{
+				Timestamp: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
+				DeletedFiles: []maintenance.DatasetFile{
+					{URI: "gs://bucket/prefix/foo/bar/v2/file1", Size: 1},
+				},
+			},
+		},
+	}
+
+	var output bytes.Buffer
+	printPruneResponse(&res, 4, &output)
+	printPruneSummary(pruneSummary{datasets: 1, versions: 2, size: 3}, &output, true)
+
+	expected := "Dataset /foo/bar: pruned 2 of 4 versions (3B)\n" +
+		"\t2000-01-01T00:00:00Z (2 files)\n" +
+		"\t2000-01-02T00:00:00Z (1 file)\n" +
+		"Pruned 2 versions in 1 dataset, 3B reclaimed\n" +
+		"The dry-run flag was set. NO FILES WERE DELETED."
+
+	if actual, expected := strings.TrimSpace(output.String()), strings.TrimSpace(expected); actual != expected {
+		t.Errorf("Result not as expected:\n%v", diff.LineDiff(expected, actual))
+	}
+}
+
+func TestPruneDataset(t *testing.T) {
+	api := &fakeAPI{datasets: map[string][]maintenance.Version{"/foo/bar": {
+		{Timestamp: time.Date(2000, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{Timestamp: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{Timestamp: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}}}
+	env, stdout, _ := newTestEnv(api, "")
+
+	p := pruner{ctx: context.Background(), env: env, client: api, protected: &protection{}, rule: retention.Rule{KeepLast: 1}}
+	assert.Nil(t, p.pruneDataset("/foo/bar"))
+	assert.Equal(t, []string{"delete versions /foo/bar"}, api.calls)
+	assert.Equal(t, pruneSummary{datasets: 1, versions: 2}, p.summary)
+	assert.Contains(t, stdout.String(), "Dataset /foo/bar: pruned 2 of 3 versions (0B)\n")
+}
+
+// partialDeleteAPI reports an error along with the deleted versions, like when the server deletes versions that were
+// not requested
+type partialDeleteAPI struct {
+	*fakeAPI
+}
+
+func (f partialDeleteAPI) DeleteDatasetVersions(ctx context.Context, path string, timestamps []time.Time, dryRun bool) (*maintenance.DeleteDatasetResponse, error) {
+	res, _ := f.fakeAPI.DeleteDatasetVersions(ctx, path, timestamps, dryRun)
+	return res, errors.New("unexpected version")
+}
+
+func TestPruneDataset_PartialDelete(t *testing.T) {
+	api := partialDeleteAPI{&fakeAPI{datasets: map[string][]maintenance.Version{"/foo/bar": {
+		{Timestamp: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{Timestamp: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}}}}
+	env, stdout, _ := newTestEnv(api, "")
+
+	p := pruner{ctx: context.Background(), env: env, client: api, protected: &protection{}, rule: retention.Rule{KeepLast: 1}}
+	assert.EqualError(t, p.pruneDataset("/foo/bar"), "unexpected version")
+	assert.Equal(t, pruneSummary{datasets: 1, versions: 1}, p.summary)
+	assert.Contains(t, stdout.String(), "Dataset /foo/bar: pruned 1 of 2 versions")
+}
@@ -0,0 +1,306 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"github.com/statisticsnorway/dapla-cli/retention"
+)
+
+func newRetentionCommand(env *Env) *cobra.Command {
+	var policyFile string
+	retentionCommand := &cobra.Command{
+		Use:   "retention",
+		Short: "Apply a retention policy to datasets",
+		Long: `The retention commands delete old dataset versions according to a retention policy file.
+
+The policy maps dataset path globs to retention rules. The rules are evaluated in order, and the first rule
+with a matching path applies to a dataset. Datasets not matched by any rule are left untouched. The latest
+version of a dataset is always kept. Example policy:
+
+  rules:
+    - path: /produkt/**
+      keep-all: true
+    - path: /tmp/**
+      keep-within: 7d
+    - path: /raw/**
+      keep-last: 3`,
+	}
+	retentionCommand.PersistentFlags().StringVarP(&policyFile, "policy", "f", "", "retention policy file (YAML)")
+	retentionCommand.MarkPersistentFlagRequired("policy")
+	retentionCommand.AddCommand(
+		newRetentionPlanCommand(env, &policyFile),
+		newRetentionApplyCommand(env, &policyFile),
+	)
+	return retentionCommand
+}
+
+func newRetentionPlanCommand(env *Env, policyFile *string) *cobra.Command {
+	var output outputFormat
+	planCommand := &cobra.Command{
+		Use:   "plan",
+		Short: "Show the dataset versions a retention policy would delete",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plan, err := loadRetentionPlan(cmd.Context(), env, *policyFile)
+			if err != nil {
+				return err
+			}
+
+			if output == outputJSON {
+				return printJSON(plan, env.Stdout)
+			}
+			printRetentionPlan(plan, env.Stdout)
+			return nil
+		},
+	}
+	addOutputFlag(planCommand, &output)
+	return planCommand
+}
+
+func newRetentionApplyCommand(env *Env, policyFile *string) *cobra.Command {
+	var auditLogFile string
+	applyCommand := &cobra.Command{
+		Use:   "apply",
+		Short: "Delete the dataset versions a retention policy does not keep",
+		Long: `The apply command deletes the dataset versions that are not kept by the retention policy. Each deleted
+version is reported on stdout, and optionally appended as a JSON line to an audit log file. Failing datasets
+are reported and skipped, and apply exits with a non-zero status if any deletions failed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var auditLog io.Writer
+			if auditLogFile != "" {
+				file, err := os.OpenFile(auditLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				auditLog = file
+			}
+
+			plan, err := loadRetentionPlan(cmd.Context(), env, *policyFile)
+			if err != nil {
+				return err
+			}
+
+			client, err := env.Maintenance()
+			if err != nil {
+				return err
+			}
+			if failed := applyRetention(cmd.Context(), client, plan, env.Stdout, auditLog, env.Now); failed > 0 {
+				return fmt.Errorf("failed to prune %d %s", failed, pluralize("dataset", failed))
+			}
+			return nil
+		},
+	}
+	applyCommand.Flags().StringVar(&auditLogFile, "audit-log", "", "append a JSON line for each deleted version to this file")
+	return applyCommand
+}
+
+// loadRetentionPlan loads the retention policy in policyFile, and plans which versions to delete
+func loadRetentionPlan(ctx context.Context, env *Env, policyFile string) ([]plannedDeletion, error) {
+	policy, err := retention.LoadPolicy(policyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := env.Maintenance()
+	if err != nil {
+		return nil, err
+	}
+	protected, err := newProtection(ctx, env, client, false)
+	if err != nil {
+		return nil, err
+	}
+
+	spinner := env.spinner("Planning retention")
+	defer spinner.Stop()
+	return planRetention(ctx, client, policy, protected, env.Now(), env.Stderr)
+}
+
+// plannedDeletion holds the versions of a dataset that are not kept by a retention policy
+type plannedDeletion struct {
+	DatasetPath string                `json:"datasetPath"`
+	Rule        string                `json:"rule"`
+	Kept        int                   `json:"kept"`
+	Versions    []maintenance.Version `json:"versions"`
+}
+
+// Size returns the total size of the planned deleted versions
+func (d plannedDeletion) Size() uint64 {
+	var size uint64
+	for _, version := range d.Versions {
+		size += version.Size()
+	}
+	return size
+}
+
+// Timestamps returns the timestamps of the planned deleted versions
+func (d plannedDeletion) Timestamps() []time.Time {
+	timestamps := make([]time.Time, 0, len(d.Versions))
+	for _, version := range d.Versions {
+		timestamps = append(timestamps, version.Timestamp)
+	}
+	return timestamps
+}
+
+// planRetention walks the folders that the policy applies to, and returns the versions of each dataset
+// that are not kept by the policy. Protected datasets are left out of the plan. A root of the policy that is a
+// dataset is planned on its own, and roots that do not exist are reported on warnings and skipped.
+func planRetention(ctx context.Context, client maintenance.API, policy *retention.Policy, protected *protection, now time.Time, warnings io.Writer) ([]plannedDeletion, error) {
+	plan := []plannedDeletion{}
+	planDataset := func(element maintenance.ListDatasetElement) error {
+		policyRule := policy.RuleFor(element.Path)
+		if element.IsFolder() || policyRule == nil || policyRule.KeepAll || protected.skip(element.Path) {
+			return nil
+		}
+
+		versions, err := client.ListVersions(ctx, element.Path)
+		if err != nil {
+			return err
+		}
+
+		expired := policyRule.Rule().Expired(versions.Timestamps(), now)
+		if len(expired) == 0 {
+			return nil
+		}
+
+		deletion := plannedDeletion{
+			DatasetPath: element.Path,
+			Rule:        policyRule.String(),
+			Kept:        len(versions.Versions) - len(expired),
+		}
+		for _, timestamp := range expired {
+			deletion.Versions = append(deletion.Versions, *versions.Version(timestamp))
+		}
+		plan = append(plan, deletion)
+		return nil
+	}
+
+	for _, root := range policy.Roots() {
+		element, err := retentionRoot(ctx, client, root)
+		if err != nil {
+			return nil, err
+		}
+		if element == nil {
+			fmt.Fprintf(warnings, "Warning: %s does not exist, skipping the rules under it\n", root)
+			continue
+		}
+		if element.IsDataset() {
+			err = planDataset(*element)
+		} else {
+			err = maintenance.WalkDatasets(ctx, client, root, planDataset)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+// retentionRoot returns the dataset or folder at root, found by listing its parent, or nil if it does not exist
+func retentionRoot(ctx context.Context, client maintenance.API, root string) (*maintenance.ListDatasetElement, error) {
+	if root == "/" {
+		return &maintenance.ListDatasetElement{Path: root, Depth: 1}, nil
+	}
+	res, err := client.ListDatasets(ctx, path.Dir(root))
+	if httpErr, ok := err.(*maintenance.HTTPError); ok && httpErr.StatusCode() == http.StatusNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for _, element := range *res {
+		if element.Path == root {
+			return &element, nil
+		}
+	}
+	return nil, nil
+}
+
+// auditRecord describes a single version deleted by retention apply
+type auditRecord struct {
+	Time        time.Time `json:"time"`
+	Action      string    `json:"action"`
+	DatasetPath string    `json:"datasetPath"`
+	Version     time.Time `json:"version"`
+	Files       int       `json:"files"`
+	Size        uint64    `json:"size"`
+	Rule        string    `json:"rule"`
+}
+
+// applyRetention deletes the planned versions, writing an audit line for each deleted version to output and (if
+// not nil) a JSON line to auditLog, timestamped by now. Datasets that fail are reported and skipped, after auditing
+// the versions the server reports as deleted anyway. Returns the number of failures.
+func applyRetention(ctx context.Context, client maintenance.API, plan []plannedDeletion, output io.Writer, auditLog io.Writer, now func() time.Time) int {
+	failed := 0
+	for _, deletion := range plan {
+		res, err := client.DeleteDatasetVersions(ctx, deletion.DatasetPath, deletion.Timestamps(), false)
+		if res != nil {
+			auditDeletion(deletion, res, output, auditLog, now)
+		}
+		if err != nil {
+			fmt.Fprintf(output, "%s FAILED %s: %v\n", now().UTC().Format(time.RFC3339), deletion.DatasetPath, err)
+			failed++
+		}
+	}
+	return failed
+}
+
+// auditDeletion writes an audit line for each version in res, which was deleted by the planned deletion, to output
+// and (if not nil) a JSON line to auditLog
+func auditDeletion(deletion plannedDeletion, res *maintenance.DeleteDatasetResponse, output io.Writer, auditLog io.Writer, now func() time.Time) {
+	for _, version := range res.DatasetVersion {
+		record := auditRecord{
+			Time:        now().UTC(),
+			Action:      "delete-version",
+			DatasetPath: deletion.DatasetPath,
+			Version:     version.Timestamp,
+			Files:       len(version.DeletedFiles),
+			Size:        version.Size(),
+			Rule:        deletion.Rule,
+		}
+
+		fmt.Fprintf(output, "%s DELETED %s@%d files=%d size=%d rule=%q\n",
+			record.Time.Format(time.RFC3339), record.DatasetPath, maintenance.EpochMillis(record.Version),
+			record.Files, record.Size, record.Rule)
+		if auditLog != nil {
+			line, _ := json.Marshal(record)
+			fmt.Fprintln(auditLog, string(line))
+		}
+	}
+}
+
+// printRetentionPlan prints the planned deletions in tabular format, followed by a summary
+func printRetentionPlan(plan []plannedDeletion, output io.Writer) {
+	colorOutput := colorWriter{out: output}
+	writer := tabwriter.NewWriter(colorOutput, 15, 0, 2, ' ', tabwriter.FilterHTML)
+	defer writer.Flush()
+
+	fmt.Fprintln(writer,
+		"<bold>Dataset</>\t"+
+			"<bold>Rule</>\t"+
+			"<bold>Delete</>\t"+
+			"<bold>Keep</>\t"+
+			"<bold>Size</>\t")
+
+	var versions int
+	var size uint64
+	for _, deletion := range plan {
+		fmt.Fprintf(writer, "%s\t%s\t%d\t%d\t%s\t\n",
+			deletion.DatasetPath, deletion.Rule, len(deletion.Versions), deletion.Kept, formatSize(deletion.Size(), true))
+		versions += len(deletion.Versions)
+		size += deletion.Size()
+	}
+
+	fmt.Fprintf(writer, "\n%d %s in %d %s would be deleted, %s reclaimed\n",
+		versions, pluralize("version", versions), len(plan), pluralize("dataset", len(plan)), formatSize(size, true))
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/statisticsnorway/dapla-cli/devserver"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"github.com/statisticsnorway/dapla-cli/retention"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestPlanAndApplyRetention(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Get("/api/v1/list//$").
+		Reply(http.StatusOK).
+		BodyString(`[{"path": "/tmp", "depth": 1}]`)
+	gock.New("http://server.com").
+		Get("/api/v1/list//tmp").
+		Reply(http.StatusOK).
+		BodyString(`[{"path": "/tmp/foo", "depth": 0},{"path": "/tmp/keep", "depth": 0}]`)
+	gock.New("http://server.com").
+		Get("/api/v1/versions//tmp/foo").
+		Reply(http.StatusOK).
+		BodyString(`{"datasetPath": "/tmp/foo", "versions": [
+			{"timestamp": "2021-01-01T00:00:00Z", "files": [{"uri": "gs://bucket/tmp/foo/v1/file1", "size": 5}]},
+			{"timestamp": "2021-04-30T00:00:00Z", "files": [{"uri": "gs://bucket/tmp/foo/v2/file1", "size": 7}]}
+		]}`)
+	gock.New("http://server.com").
+		Delete("/api/v1/versions//tmp/foo").
+		MatchParam("dry-run", "false").
+		MatchParam("versions", "^1609459200000$").
+		Reply(http.StatusOK).
+		BodyString(`{"datasetPath": "/tmp/foo", "totalSize": 5, "deletedVersions": [
+			{"timestamp": "2021-01-01T00:00:00Z", "deletedFiles": [{"uri": "gs://bucket/tmp/foo/v1/file1", "size": 5}]}
+		]}`)
+
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	policy, err := retention.ParsePolicy([]byte(`
+rules:
+  - path: /tmp/keep
+    keep-all: true
+  - path: /tmp/**
+    keep-within: 7d
+`))
+	assert.Nil(t, err)
+
+	client := maintenance.NewClient("http://server.com", "a secret secret")
+	plan, err := planRetention(context.Background(), client, policy, &protection{}, time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC), &bytes.Buffer{})
+	assert.Nil(t, err)
+	assert.Len(t, plan, 1)
+	assert.Equal(t, "/tmp/foo", plan[0].DatasetPath)
+	assert.Equal(t, 1, plan[0].Kept)
+	assert.Equal(t, uint64(5), plan[0].Size())
+
+	var planOutput bytes.Buffer
+	printRetentionPlan(plan, &planOutput)
+	assert.Contains(t, planOutput.String(), "1 version in 1 dataset would be deleted, 5B reclaimed")
+
+	var output, auditLog bytes.Buffer
+	failed := applyRetention(context.Background(), client, plan, &output, &auditLog, func() time.Time { return testNow })
+	assert.Equal(t, 0, failed)
+	assert.Contains(t, output.String(), `DELETED /tmp/foo@1609459200000 files=1 size=5 rule="/tmp/** keep-within 7d"`)
+
+	var record auditRecord
+	assert.Nil(t, json.Unmarshal([]byte(strings.TrimSpace(auditLog.String())), &record))
+	assert.Equal(t, "delete-version", record.Action)
+	assert.Equal(t, uint64(5), record.Size)
+}
+
+func TestApplyRetention_UnrequestedVersions(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Delete("/api/v1/versions//tmp/foo").
+		Reply(http.StatusOK).
+		BodyString(`{"datasetPath": "/tmp/foo", "totalSize": 12, "deletedVersions": [
+			{"timestamp": "2021-01-01T00:00:00Z", "deletedFiles": [{"uri": "gs://bucket/tmp/foo/v1/file1", "size": 5}]},
+			{"timestamp": "2021-04-30T00:00:00Z", "deletedFiles": [{"uri": "gs://bucket/tmp/foo/v2/file1", "size": 7}]}
+		]}`)
+
+	plan := []plannedDeletion{{
+		DatasetPath: "/tmp/foo",
+		Rule:        "/tmp/** keep-within 7d",
+		Versions:    []maintenance.Version{{Timestamp: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}},
+	}}
+	client := maintenance.NewClient("http://server.com", "a secret secret")
+
+	// The versions that were deleted are audited, even the one that was not requested
+	var output, auditLog bytes.Buffer
+	failed := applyRetention(context.Background(), client, plan, &output, &auditLog, func() time.Time { return testNow })
+	assert.Equal(t, 1, failed)
+	assert.Contains(t, output.String(), "DELETED /tmp/foo@1609459200000 files=1 size=5")
+	assert.Contains(t, output.String(), "DELETED /tmp/foo@1619740800000 files=1 size=7")
+	assert.Contains(t, output.String(), "FAILED /tmp/foo: the server reported version 1619740800000")
+	assert.Equal(t, 2, strings.Count(auditLog.String(), "\n"))
+}
+
+func TestPlanRetention_DatasetAndMissingRoots(t *testing.T) {
+	server := httptest.NewServer(devserver.New(devserver.SeedCatalog()).MaintenanceHandler())
+	defer server.Close()
+
+	policy, err := retention.ParsePolicy([]byte(`
+rules:
+  - path: /nope/**
+    keep-last: 1
+  - path: /raw/skatt/hendelser
+    keep-last: 3
+`))
+	assert.Nil(t, err)
+
+	var warnings bytes.Buffer
+	plan, err := planRetention(context.Background(), maintenance.New(server.URL), policy, &protection{}, testNow, &warnings)
+	assert.Nil(t, err)
+	assert.Len(t, plan, 1)
+	assert.Equal(t, "/raw/skatt/hendelser", plan[0].DatasetPath)
+	assert.Equal(t, 3, plan[0].Kept)
+	assert.Len(t, plan[0].Versions, 1)
+	assert.Equal(t, "Warning: /nope does not exist, skipping the rules under it\n", warnings.String())
+}
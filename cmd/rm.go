@@ -1,170 +1,304 @@
 package cmd
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"io"
-	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"github.com/statisticsnorway/dapla-cli/retention"
 )
 
-var (
-	rmDryRun    bool
-	rmRecursive bool
-)
-
-func init() {
-	rmCommand := newRmCommand()
-	rmCommand.Flags().BoolVarP(&rmRecursive, "recursive", "", false, "delete recursively")
-	rmCommand.Flags().BoolVarP(&rmDryRun, "dry-run", "", false, "dry run")
-	rootCmd.AddCommand(rmCommand)
-}
-
-func newRmCommand() *cobra.Command {
-	return &cobra.Command{
+func newRmCommand(env *Env) *cobra.Command {
+	var recursive, dryRun, permanent, override bool
+	var output outputFormat
+	rmCommand := &cobra.Command{
 		Use:   "rm [PATH]...",
 		Short: "Delete dataset(s)",
-		Long:  `The rm command deletes all the version of a given dataset`,
-		Args:  cobra.MinimumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-
-			path := args[0]
-			if rmRecursive {
-				deleteRecursively(path, rmDryRun)
-			} else {
-				doDelete(path, rmDryRun)
+		Long: `The rm command deletes all the version of a given dataset.
+
+By default deleted datasets are moved to the trash of the current user, from where they can be restored (see
+the restore command) until they expire. Use --permanent to delete datasets permanently right away.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := env.Maintenance()
+			if err != nil {
+				return err
+			}
+			protected, err := newProtection(cmd.Context(), env, client, override)
+			if err != nil {
+				return err
 			}
-		},
-		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 
-			return doAutoComplete(toComplete)
+			r := remover{
+				ctx:       cmd.Context(),
+				env:       env,
+				client:    client,
+				protected: protected,
+				output:    output,
+				summary: deleteSummary{
+					DryRun:    dryRun,
+					Permanent: permanent,
+					Datasets:  []*maintenance.DeleteDatasetResponse{},
+					Trashed:   []*maintenance.TrashedDataset{},
+				},
+			}
+			if !permanent {
+				r.expiry, err = retention.ParseDuration(env.Config.GetString(CFGTrashExpiry))
+				if err != nil {
+					return err
+				}
+			}
+			for _, path := range env.absPaths(args) {
+				if recursive {
+					err = r.deleteRecursively(path)
+				} else {
+					err = r.delete(path)
+				}
+				if err != nil {
+					return err
+				}
+			}
 
+			if output == outputJSON {
+				return printJSON(r.summary.withTotals(), env.Stdout)
+			}
+			printDeleteSummary(&r.summary, env.Stdout)
+			return nil
 		},
+		ValidArgsFunction: completePath(env),
+	}
+	rmCommand.Flags().BoolVarP(&recursive, "recursive", "", false, "delete recursively")
+	rmCommand.Flags().BoolVarP(&dryRun, "dry-run", "", false, "dry run")
+	rmCommand.Flags().BoolVarP(&permanent, "permanent", "", false, "delete permanently instead of moving to the trash")
+	rmCommand.Flags().String("trash-expiry", "30d", "how long deleted datasets are kept in the trash, e.g. 30d or 2w")
+	env.Config.BindPFlag(CFGTrashExpiry, rmCommand.Flags().Lookup("trash-expiry"))
+	addOutputFlag(rmCommand, &output)
+	addOverrideProtectionFlag(rmCommand, &override)
+	return rmCommand
+}
+
+// deleteSummary accumulates the results of deleting one or more datasets. Permanently deleted datasets are
+// held in Datasets, while datasets moved to the trash are held in Trashed.
+type deleteSummary struct {
+	Datasets      []*maintenance.DeleteDatasetResponse `json:"datasets"`
+	Trashed       []*maintenance.TrashedDataset        `json:"trashed"`
+	TotalVersions int                                  `json:"totalVersions"`
+	TotalFiles    int                                  `json:"totalFiles"`
+	TotalSize     uint64                               `json:"totalSize"`
+	DryRun        bool                                 `json:"dryRun"`
+	Permanent     bool                                 `json:"permanent"`
+}
+
+// withTotals returns the summary with the totals calculated from the deleted datasets
+func (s deleteSummary) withTotals() deleteSummary {
+	s.TotalVersions, s.TotalFiles, s.TotalSize = 0, 0, 0
+	for _, dataset := range s.Datasets {
+		s.TotalVersions += len(dataset.DatasetVersion)
+		s.TotalFiles += dataset.GetNumberOfFiles()
+		s.TotalSize += dataset.TotalSize
+	}
+	for _, dataset := range s.Trashed {
+		s.TotalVersions += dataset.Versions
+		s.TotalSize += dataset.TotalSize
 	}
+	return s
+}
+
+// remover deletes the datasets given to the rm command, and accumulates the results in its summary
+type remover struct {
+	ctx       context.Context
+	env       *Env
+	client    maintenance.API
+	protected *protection
+	output    outputFormat
+	expiry    time.Duration
+	summary   deleteSummary
 }
 
-func doDelete(path string, dryRun bool) {
+func (r *remover) delete(path string) error {
+	if err := r.protected.check(path); err != nil {
+		return err
+	}
+	if !r.summary.Permanent {
+		return r.trash(path)
+	}
+
 	// Create and start spinner
-	spinner := newSpinner("Deleting dataset " + path)
-	var client = maintenance.NewClient(apiURLOf(APINameDataMaintenanceSvc), authToken())
-	res, err := client.DeleteDatasets(path, dryRun)
+	spinner := r.env.spinner("Deleting dataset " + path)
+	res, err := r.client.DeleteDatasets(r.ctx, path, r.summary.DryRun)
 	spinner.Stop()
 
 	if err != nil {
-		exitCode := 1
-		switch err.(type) {
-		case *maintenance.HTTPError:
-			exitCode = 0
-		default:
-		}
-		fmt.Println(err.Error() + "\n")
-		os.Exit(exitCode)
-	} else {
-		printDeleteResponse(res, os.Stdout, dryRun)
+		return r.fail(err)
+	}
+	r.summary.Datasets = append(r.summary.Datasets, res)
+	if r.output != outputJSON {
+		printDeleteResponse(res, r.env.Stdout, r.env.Config.GetBool(CFGDebug))
 	}
+	return nil
 }
 
-func deleteRecursively(path string, dryRun bool) {
-	var client = maintenance.NewClient(apiURLOf(APINameDataMaintenanceSvc), authToken())
-	res, err := client.ListDatasets(path)
+func (r *remover) trash(path string) error {
+	spinner := r.env.spinner("Moving dataset " + path + " to trash")
+	res, err := r.client.TrashDataset(r.ctx, path, r.expiry, r.summary.DryRun)
+	spinner.Stop()
 	if err != nil {
-		exitCode := 1
-		switch err.(type) {
-		case *maintenance.HTTPError:
-			exitCode = 0
-		default:
-		}
-		fmt.Println(err.Error() + "\n")
-		os.Exit(exitCode)
-	} else if res != nil {
-		printBeforeDelete(res, dryRun)
-	} else {
-		// no error and response is nil
-		writer := bufio.NewWriter(os.Stdout)
-		fmt.Fprintf(writer, "Could not find any datasets to delete.\n\r")
+		return r.fail(err)
+	}
+
+	r.summary.Trashed = append(r.summary.Trashed, res)
+	if r.output != outputJSON {
+		printTrashResponse(res, r.env.Stdout)
 	}
+	return nil
+}
 
+// fail reports an API error the same way whether datasets are trashed or deleted permanently. With --output json the
+// error goes to stderr and the command exits with a non-zero code, so that stdout only ever holds JSON and scripts
+// can detect the failure.
+func (r *remover) fail(err error) error {
+	if r.output == outputJSON {
+		return err
+	}
+	return reportAPIError(r.env, err)
 }
 
-func printBeforeDelete(datasetsAndFolders *maintenance.ListDatasetResponse, dryRun bool) {
-	for _, item := range *datasetsAndFolders {
+func (r *remover) deleteRecursively(path string) error {
+	res, err := r.client.ListDatasets(r.ctx, path)
+	if err != nil {
+		return r.fail(err)
+	} else if res == nil {
+		// no error and response is nil
+		fmt.Fprintln(r.env.Stderr, "Could not find any datasets to delete.")
+		return nil
+	}
+
+	for _, item := range *res {
 		if item.IsDataset() {
-			deleteWithPrompt(item.Path, dryRun)
+			err = r.deleteWithPrompt(item.Path)
 		} else {
-			deleteRecursively(item.Path, dryRun)
+			err = r.deleteRecursively(item.Path)
+		}
+		if err != nil {
+			return err
 		}
 	}
+	return nil
 }
 
-func deleteWithPrompt(path string, dryRun bool) {
-	fmt.Print("Delete dataset ", path, "? ")
-	reader := bufio.NewReader(os.Stdin)
-	char, _, err := reader.ReadRune()
+// deleteWithPrompt asks the user for confirmation before deleting a dataset. The prompt is written to stderr, so
+// that it does not interfere with the output of the command. Protected datasets are skipped, unless the
+// protection is overridden.
+func (r *remover) deleteWithPrompt(path string) error {
+	if r.protected.skip(path) {
+		return nil
+	}
 
-	if err != nil {
-		fmt.Println(err)
+	if r.summary.Permanent {
+		fmt.Fprint(r.env.Stderr, "Permanently delete dataset ", path, "? ")
+	} else {
+		fmt.Fprint(r.env.Stderr, "Move dataset ", path, " to trash? ")
+	}
+	answer, err := r.env.prompts().ReadString('\n')
+	if err != nil && err != io.EOF {
+		fmt.Fprintln(r.env.Stderr, err)
 	}
 
-	switch char {
-	case 'y', 'Y':
-		doDelete(path, dryRun)
-		break
+	switch strings.TrimSpace(answer) {
+	case "y", "Y", "yes":
+		return r.delete(path)
 	default:
-		fmt.Println("... skipped")
+		fmt.Fprintln(r.env.Stderr, "... skipped")
+		return nil
 	}
 }
 
 // Output:
-// > dapla rm /foo/bar
-//  Dataset /foo/bar (42 versions) successfully deleted
-//
-// > dapla rm --debug /foo/bar
-//  /foo/bar [versiontimestamp]
-//  	gs://bucket/random/prefix/file1 123
-//  	gs://bucket/random/prefix/file2 123
-//  	gs://bucket/random/prefix/file3 123
-//  	gs://bucket/random/prefix/file4 123
-//  /foo/bar [versiontimestamp2]
-//  	gs://bucket2/some/other/path/prefix/file1 123
-//  	gs://bucket2/some/other/path/prefix/file2 123
-//  	gs://bucket2/some/other/path/prefix/file2 123
-//  	gs://bucket2/some/other/path/prefix/file2 123
-//  Number of deleted files: 1234
-//  Total size: 123456789 KiB
-//  Dataset /foo/bar (42 versions) successfully deleted
+// > dapla rm --permanent /foo/bar
+//  Dataset /foo/bar (2 versions) successfully deleted
+//    Version                      Files  Size
+//    2000-01-01T00:00:00.123456Z  2      3B
+//    3000-01-01T00:00:00.123456Z  2      12B
+//    Total                        4      15B
 //
-// > dapla rm --dry-run /foo/bar
-//  Dataset /foo/bar (42 versions) successfully deleted
-// The dry-run flag was set. NO FILES WERE DELETED.
-func printDeleteResponse(deleteResponse *maintenance.DeleteDatasetResponse, output io.Writer, dryRun bool) {
-	writer := bufio.NewWriter(output)
+// > dapla rm --permanent --debug /foo/bar
+//  Dataset /foo/bar (2 versions) successfully deleted
+//    Version                      Files  Size
+//    2000-01-01T00:00:00.123456Z  2      3B
+//      gs://bucket/prefix/foo/bar/v1/file1  1B
+//      gs://bucket/prefix/foo/bar/v1/file2  2B
+//    3000-01-01T00:00:00.123456Z  2      12B
+//      gs://bucket/prefix/foo/bar/v2/file1  4B
+//      gs://bucket/prefix/foo/bar/v2/file2  8B
+//    Total                        4      15B
+func printDeleteResponse(deleteResponse *maintenance.DeleteDatasetResponse, output io.Writer, debug bool) {
+	fmt.Fprintf(output, "Dataset %s (%d %s) successfully deleted\n",
+		deleteResponse.DatasetPath,
+		len(deleteResponse.DatasetVersion),
+		pluralize("version", len(deleteResponse.DatasetVersion)))
+
+	writer := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
 	defer writer.Flush()
 
-	if viper.GetBool(CFGDebug) {
-		for _, datasetVersion := range deleteResponse.DatasetVersion {
-			fmt.Fprintf(writer, "Version: %s\n", datasetVersion.Timestamp)
+	fmt.Fprintln(writer, "  Version\tFiles\tSize")
+	for _, datasetVersion := range deleteResponse.DatasetVersion {
+		fmt.Fprintf(writer, "  %s\t%d\t%s\n",
+			datasetVersion.Timestamp.Format(time.RFC3339Nano),
+			len(datasetVersion.DeletedFiles),
+			formatSize(datasetVersion.Size(), true))
+		if debug {
 			for _, deletedFile := range datasetVersion.DeletedFiles {
-				fmt.Fprintf(writer, "\t%s\n", deletedFile.URI)
+				fmt.Fprintf(writer, "    %s\t\t%s\n", deletedFile.URI, formatSize(deletedFile.Size, true))
 			}
 		}
-		fmt.Fprintf(writer, "\nnumber of deleted files: %d\n", deleteResponse.GetNumberOfFiles())
-		fmt.Fprintf(writer, "total size of deleted files: %d\n", deleteResponse.TotalSize)
 	}
-	fmt.Fprintf(writer, "Dataset %s (%d %s) successfully deleted\n\r",
-		deleteResponse.DatasetPath,
-		len(deleteResponse.DatasetVersion),
-		pluralize("version", len(deleteResponse.DatasetVersion)))
+	fmt.Fprintf(writer, "  Total\t%d\t%s\n", deleteResponse.GetNumberOfFiles(), formatSize(deleteResponse.TotalSize, true))
+}
+
+// Output:
+// > dapla rm /foo/bar
+//  Dataset /foo/bar (2 versions, 15B) moved to trash, expires 2021-05-31T00:00:00Z
+func printTrashResponse(trashed *maintenance.TrashedDataset, output io.Writer) {
+	fmt.Fprintf(output, "Dataset %s (%d %s, %s) moved to trash, expires %s\n",
+		trashed.DatasetPath,
+		trashed.Versions, pluralize("version", trashed.Versions),
+		formatSize(trashed.TotalSize, true),
+		trashed.ExpiresAt.Format(time.RFC3339))
+}
+
+// Output:
+// > dapla rm --permanent --recursive --dry-run /foo
+//  ...
+//  Deleted 3 datasets (7 versions, 12 files, 1.2G)
+//  The dry-run flag was set. NO FILES WERE DELETED.
+func printDeleteSummary(summary *deleteSummary, output io.Writer) {
+	totals := summary.withTotals()
+	if len(totals.Datasets) > 1 {
+		fmt.Fprintf(output, "Deleted %d datasets (%d %s, %d %s, %s)\n",
+			len(totals.Datasets),
+			totals.TotalVersions, pluralize("version", totals.TotalVersions),
+			totals.TotalFiles, pluralize("file", totals.TotalFiles),
+			formatSize(totals.TotalSize, true))
+	}
+	if len(totals.Trashed) > 1 {
+		fmt.Fprintf(output, "Moved %d datasets to trash (%d %s, %s)\n",
+			len(totals.Trashed),
+			totals.TotalVersions, pluralize("version", totals.TotalVersions),
+			formatSize(totals.TotalSize, true))
+	}
 
-	if dryRun {
-		fmt.Fprintf(writer, "The dry-run flag was set. NO FILES WERE DELETED.\n\r")
+	if summary.DryRun {
+		fmt.Fprintln(output, "The dry-run flag was set. NO FILES WERE DELETED.")
 	}
 }
 
 func pluralize(text string, n int) string {
-	if n > 1 {
+	if n != 1 {
 		return text + "s"
 	}
 	return text
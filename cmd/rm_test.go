@@ -8,18 +8,18 @@ import (
 	"time"
 
 	"github.com/andreyvit/diff"
-	"github.com/spf13/viper"
+	"github.com/statisticsnorway/dapla-cli/devserver"
 	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestExecuteRM(t *testing.T) {
 
 	tests := []struct {
-		response                     maintenance.DeleteDatasetResponse
-		expectedOutput               string
-		expectedOutputDebug          string
-		expectedOutputDryRun         string
-		expectedOutputDebugAndDryRun string
+		response             maintenance.DeleteDatasetResponse
+		expectedOutput       string
+		expectedOutputDebug  string
+		expectedOutputDryRun string
 	}{
 		{response: maintenance.DeleteDatasetResponse{
 			DatasetPath: "/foo/bar",
@@ -42,28 +42,21 @@ func TestExecuteRM(t *testing.T) {
 			},
 		},
 
-			expectedOutput: "Dataset /foo/bar (2 versions) successfully deleted",
-			expectedOutputDebug: "Version: 2000-01-01 00:00:00.123456 +0000 UTC\n" +
-				"\tgs://bucket/prefix/foo/bar/v1/file1\n" +
-				"\tgs://bucket/prefix/foo/bar/v1/file2\n" +
-				"Version: 3000-01-01 00:00:00.123456 +0000 UTC\n" +
-				"\tgs://bucket/prefix/foo/bar/v2/file1\n" +
-				"\tgs://bucket/prefix/foo/bar/v2/file2\n\n" +
-				"number of deleted files: 4\n" +
-				"total size of deleted files: 15\n" +
-				"Dataset /foo/bar (2 versions) successfully deleted",
-			expectedOutputDryRun: "Dataset /foo/bar (2 versions) successfully deleted\n\r" +
-				"The dry-run flag was set. NO FILES WERE DELETED.",
-			expectedOutputDebugAndDryRun: "Version: 2000-01-01 00:00:00.123456 +0000 UTC\n" +
-				"\tgs://bucket/prefix/foo/bar/v1/file1\n" +
-				"\tgs://bucket/prefix/foo/bar/v1/file2\n" +
-				"Version: 3000-01-01 00:00:00.123456 +0000 UTC\n" +
-				"\tgs://bucket/prefix/foo/bar/v2/file1\n" +
-				"\tgs://bucket/prefix/foo/bar/v2/file2\n\n" +
-				"number of deleted files: 4\n" +
-				"total size of deleted files: 15\n" +
-				"Dataset /foo/bar (2 versions) successfully deleted\n\r" +
-				"The dry-run flag was set. NO FILES WERE DELETED.",
+			expectedOutput: "Dataset /foo/bar (2 versions) successfully deleted\n" +
+				"  Version                      Files  Size\n" +
+				"  2000-01-01T00:00:00.123456Z  2      3B\n" +
+				"  3000-01-01T00:00:00.123456Z  2      12B\n" +
+				"  Total                        4      15B",
+			expectedOutputDebug: "Dataset /foo/bar (2 versions) successfully deleted\n" +
+				"  Version                                Files  Size\n" +
+				"  2000-01-01T00:00:00.123456Z            2      3B\n" +
+				"    gs://bucket/prefix/foo/bar/v1/file1         1B\n" +
+				"    gs://bucket/prefix/foo/bar/v1/file2         2B\n" +
+				"  3000-01-01T00:00:00.123456Z            2      12B\n" +
+				"    gs://bucket/prefix/foo/bar/v2/file1         4B\n" +
+				"    gs://bucket/prefix/foo/bar/v2/file2         8B\n" +
+				"  Total                                  4      15B",
+			expectedOutputDryRun: "The dry-run flag was set. NO FILES WERE DELETED.",
 		},
 	}
 
@@ -80,8 +73,7 @@ func TestExecuteRM(t *testing.T) {
 		output.Reset()
 
 		// Test rm with debug flag
-		viper.Set(CFGDebug, true)
-		printDeleteResponse(&values.response, &output, false)
+		printDeleteResponse(&values.response, &output, true)
 
 		if actual, expected := strings.TrimSpace(output.String()),
 			strings.TrimSpace(values.expectedOutputDebug); actual != expected {
@@ -91,8 +83,7 @@ func TestExecuteRM(t *testing.T) {
 		output.Reset()
 
 		// Test rm with dry-run flag
-		viper.Set(CFGDebug, false)
-		printDeleteResponse(&values.response, &output, true)
+		printDeleteSummary(&deleteSummary{DryRun: true, Datasets: []*maintenance.DeleteDatasetResponse{&values.response}}, &output)
 
 		if actual, expected := strings.TrimSpace(output.String()),
 			strings.TrimSpace(values.expectedOutputDryRun); actual != expected {
@@ -100,17 +91,53 @@ func TestExecuteRM(t *testing.T) {
 			t.Errorf("Result not as expected:\n%v", diff.LineDiff(expected, actual))
 		}
 		output.Reset()
+	}
+}
 
-		// Test rm with both debug and dry-run flags
-		viper.Set(CFGDebug, true)
-		printDeleteResponse(&values.response, &output, true)
+func TestPrintDeleteSummary(t *testing.T) {
+	summary := deleteSummary{
+		Datasets: []*maintenance.DeleteDatasetResponse{
+			{
+				DatasetPath: "/foo/bar",
+				TotalSize:   1024,
+				DatasetVersion: []maintenance.DatasetVersion{
+					{DeletedFiles: []maintenance.DatasetFile{{Size: 1024}}},
+				},
+			},
+			{
+				DatasetPath: "/foo/baz",
+				TotalSize:   2048,
+				DatasetVersion: []maintenance.DatasetVersion{
+					{DeletedFiles: []maintenance.DatasetFile{{Size: 1024}}},
+					{DeletedFiles: []maintenance.DatasetFile{{Size: 512}, {Size: 512}}},
+				},
+			},
+		},
+	}
 
-		if actual, expected := strings.TrimSpace(output.String()),
-			strings.TrimSpace(values.expectedOutputDebugAndDryRun); actual != expected {
-			fmt.Println("***** <rm> WITH DEBUG AND DRY-RUN FLAG *****")
-			t.Errorf("Result not as expected:\n%v", diff.LineDiff(expected, actual))
-		}
-		output.Reset()
+	var output bytes.Buffer
+	printDeleteSummary(&summary, &output)
+
+	expected := "Deleted 2 datasets (3 versions, 4 files, 3.0K)"
+	if actual := strings.TrimSpace(output.String()); actual != expected {
+		t.Errorf("Result not as expected:\n%v", diff.LineDiff(expected, actual))
+	}
+
+	totals := summary.withTotals()
+	if totals.TotalVersions != 3 || totals.TotalFiles != 4 || totals.TotalSize != 3072 {
+		t.Errorf("Unexpected totals %v", totals)
+	}
+}
 
+func TestRunRm_JSONErrors(t *testing.T) {
+	server := devserver.New(devserver.SeedCatalog())
+	for _, args := range [][]string{
+		{"rm", "--output", "json", "/skatt/nope"},
+		{"rm", "--output", "json", "--permanent", "/skatt/nope"},
+		{"rm", "--output", "json", "--permanent", "--recursive", "/nope"},
+	} {
+		code, stdout := runAgainstDevServer(t, server, args...)
+		assert.Equal(t, 1, code, args)
+		assert.Empty(t, stdout, args)
 	}
 }
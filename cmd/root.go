@@ -1,14 +1,14 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"runtime"
-	"time"
 
-	"github.com/briandowns/spinner"
-	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -19,20 +19,118 @@ const (
 	CFGJupyter   = "jupyter"
 	CFGAPIs      = "apis"
 	CFGAuthToken = "authtoken"
+	// CFGTrashExpiry is how long datasets are kept in the trash before they are permanently deleted
+	CFGTrashExpiry = "trash-expiry"
+	// CFGProtectedPaths is a list of path globs that destructive commands refuse to touch
+	CFGProtectedPaths = "protected-paths"
+	// CFGNoCache turns off the cache of listings
+	CFGNoCache = "no-cache"
+	// CFGCache makes all commands use the cache of listings, not just completion, browse and shell
+	CFGCache = "cache"
+	// CFGCacheTTL is how long cached listings are used before the folders are listed again
+	CFGCacheTTL = "cache-ttl"
+	// CFGHomeFolder is the folder that relative dataset paths are resolved against outside the shell, e.g. /user/ola
+	CFGHomeFolder = "home-folder"
 )
 
-var cfgFile string
-var rootCmd = &cobra.Command{
-	Use:     "dapla",
-	Version: versionInfo(),
-	Short:   "dapla command line utility",
-	Long:    `The dapla command is a collection of utilities you can use with the dapla platform.`,
+// NewRootCommand creates the dapla command, with all its sub commands, running in env
+func NewRootCommand(env *Env) *cobra.Command {
+	var cfgFile string
+	rootCmd := &cobra.Command{
+		Use:     "dapla",
+		Version: versionInfo(),
+		Short:   "dapla command line utility",
+		Long:    `The dapla command is a collection of utilities you can use with the dapla platform.`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// The arguments are valid at this point, so there is no need to show the usage on errors
+			cmd.SilenceUsage = true
+			return initConfig(env.Config, cfgFile, env.ConfigDir)
+		},
+		SilenceErrors: true,
+	}
+	rootCmd.SetIn(env.Stdin)
+	rootCmd.SetOut(env.Stdout)
+	rootCmd.SetErr(env.Stderr)
+
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "",
+		"config file (default is $HOME/.dapla-cli.yml)")
+	rootCmd.PersistentFlags().StringToString("apis", map[string]string{},
+		"override API URIs")
+	rootCmd.PersistentFlags().Bool("jupyter", false,
+		"set this flag to fetch user auth token from jupyter")
+	rootCmd.PersistentFlags().String("authtoken", "",
+		"explicit user auth token (if running outside of jupyter)")
+	rootCmd.PersistentFlags().BoolP("debug", "d", false,
+		"print debug information")
+	rootCmd.PersistentFlags().Bool("no-cache", false,
+		"list folders without using or updating the cache of listings")
+	rootCmd.PersistentFlags().Bool("cache", false,
+		"list folders from the cache of listings when they are fresh or the service can not be reached")
+
+	env.Config.BindPFlag("debug", rootCmd.PersistentFlags().Lookup("debug"))
+	env.Config.BindPFlag("jupyter", rootCmd.PersistentFlags().Lookup("jupyter"))
+	env.Config.BindPFlag("apis", rootCmd.PersistentFlags().Lookup("apis"))
+	env.Config.BindPFlag("authtoken", rootCmd.PersistentFlags().Lookup("authtoken"))
+	env.Config.BindPFlag("no-cache", rootCmd.PersistentFlags().Lookup("no-cache"))
+	env.Config.BindPFlag("cache", rootCmd.PersistentFlags().Lookup("cache"))
+
+	rootCmd.AddCommand(
+		newBrowseCommand(env),
+		newCacheCommand(env),
+		newCompletionCommand(),
+		newCpCommand(env),
+		newDevCommand(env),
+		newDoctorCommand(env),
+		newDuCommand(env),
+		newExportCommand(env),
+		newFindCommand(env),
+		newLsCommand(env),
+		newMvCommand(env),
+		newPluginCommand(env),
+		newPruneCommand(env),
+		newRestoreCommand(env),
+		newRetentionCommand(env),
+		newRmCommand(env),
+		newSchemaCommand(env),
+		newSearchCommand(env),
+		newShellCommand(env),
+		newStatCommand(env),
+		newTrashCommand(env),
+		newVersionsCommand(env),
+	)
+	addPluginCommands(rootCmd, env)
+	return rootCmd
+}
+
+// Run runs the dapla command with args in env, and returns the exit code. Errors are printed to the stderr of env.
+func Run(ctx context.Context, env *Env, args []string) int {
+	rootCmd := NewRootCommand(env)
+	if flags, name, pluginArgs, ok := splitPluginArgs(rootCmd, args); ok {
+		// Run plugins with the hidden plugin exec command, which passes all the arguments after the name of the
+		// plugin on as they are, while parsing the global flags before it
+		args = append(append(append([]string{}, flags...), "plugin", "exec", name, "--"), pluginArgs...)
+	}
+	rootCmd.SetArgs(args)
+	err := rootCmd.ExecuteContext(ctx)
+
+	var exit *exitError
+	if errors.As(err, &exit) {
+		return exit.code
+	}
+	if err != nil {
+		fmt.Fprintln(env.Stderr, "Error:", err)
+		return 1
+	}
+	return 0
 }
 
 // Execute uses the command line args  and run through the command tree finding appropriate matches
-// for commands and then corresponding flags.
-func Execute() error {
-	return rootCmd.Execute()
+// for commands and then corresponding flags. The context of the commands is cancelled on interrupt, which aborts
+// any ongoing requests. Returns the exit code.
+func Execute() int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return Run(ctx, DefaultEnv(), os.Args[1:])
 }
 
 func versionInfo() string {
@@ -48,64 +146,35 @@ func versionInfo() string {
 	return info
 }
 
-func init() {
-	cobra.OnInitialize(initConfig)
-
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "",
-		"config file (default is $HOME/.dapla-cli.yml)")
-	rootCmd.PersistentFlags().StringToString("apis", map[string]string{},
-		"override API URIs")
-	rootCmd.PersistentFlags().Bool("jupyter", false,
-		"set this flag to fetch user auth token from jupyter")
-	rootCmd.PersistentFlags().String("authtoken", "",
-		"explicit user auth token (if running outside of jupyter)")
-	rootCmd.PersistentFlags().BoolP("debug", "d", false,
-		"print debug information")
-
-	viper.BindPFlag("debug", rootCmd.PersistentFlags().Lookup("debug"))
-	viper.BindPFlag("jupyter", rootCmd.PersistentFlags().Lookup("jupyter"))
-	viper.BindPFlag("apis", rootCmd.PersistentFlags().Lookup("apis"))
-	viper.BindPFlag("authtoken", rootCmd.PersistentFlags().Lookup("authtoken"))
-}
+// initConfig func locates and assembles dapla-cli configuration from file. The config file is either cfgFile, or
+// .dapla-cli in configDir (with any extension). If both are empty, no config file is read.
+func initConfig(config *viper.Viper, cfgFile string, configDir string) error {
+	// Retrieve any overridden config params from the env
+	config.AutomaticEnv()
 
-// initConfig func locates and assembles dapla-cli configuration from file
-func initConfig() {
 	// Use config file from the flag
 	if cfgFile != "" {
-		viper.SetConfigFile(cfgFile)
+		config.SetConfigFile(cfgFile)
+	} else if configDir != "" {
+		// Search config in the config directory with name ".dapla-cli" (without extension).
+		config.AddConfigPath(configDir)
+		config.SetConfigName(".dapla-cli")
 	} else {
-		// Find home directory.
-		home, err := homedir.Dir()
-		cobra.CheckErr(err)
-
-		// Search config in home directory with name ".dapla-cli" (without extension).
-		viper.AddConfigPath(home)
-		viper.SetConfigName(".dapla-cli")
+		return nil
 	}
 
-	// Retrieve any overridden config params from the env
-	viper.AutomaticEnv()
-
 	// Read config from file
-	if err := viper.ReadInConfig(); err != nil {
+	if err := config.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
 			// Config file not found; which is okay
 		} else {
-			panic(fmt.Errorf("configuration error: %s", err))
+			return fmt.Errorf("configuration error: %s", err)
 		}
 	}
+	return nil
 }
 
-func effectiveConfig() string {
-	cfg, _ := json.MarshalIndent(viper.AllSettings(), "", "\t")
+func effectiveConfig(config *viper.Viper) string {
+	cfg, _ := json.MarshalIndent(config.AllSettings(), "", "\t")
 	return string(cfg)
 }
-
-// newSpinner func creates and starts a new CLI spinner
-func newSpinner(prefix string) *spinner.Spinner {
-	s := spinner.New(spinner.CharSets[9], 100*time.Millisecond, spinner.WithWriter(os.Stderr))
-	s.Color("reset")
-	s.Prefix = prefix + " "
-	s.Start()
-	return s
-}
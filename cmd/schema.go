@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+)
+
+func newSchemaCommand(env *Env) *cobra.Command {
+	return &cobra.Command{
+		Use:   "schema [PATH]",
+		Short: "Show the schema of a dataset",
+		Long:  `The schema command lists the fields of the dataset at a given PATH. Nested fields are shown with their full path, which is what column selectors and pseudo rule patterns are matched against.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := env.Maintenance()
+			if err != nil {
+				return err
+			}
+			schema, err := client.GetDatasetSchema(cmd.Context(), env.absPath(args[0]))
+			if err != nil {
+				return err
+			}
+
+			printSchema(schema, env.Stdout)
+			return nil
+		},
+		ValidArgsFunction: completePath(env),
+	}
+}
+
+// printSchema prints the fields of a dataset schema in tabular format
+func printSchema(schema *maintenance.DatasetSchema, output io.Writer) {
+	colorOutput := colorWriter{out: output}
+	writer := tabwriter.NewWriter(colorOutput, 15, 0, 2, ' ', tabwriter.FilterHTML)
+	defer writer.Flush()
+
+	fmt.Fprintln(writer, "<bold>Field</>\t<bold>Type</>\t")
+	for _, field := range schema.Fields {
+		fmt.Fprintln(writer, field.Path+"\t"+field.Type+"\t")
+	}
+}
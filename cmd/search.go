@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+)
+
+func newSearchCommand(env *Env) *cobra.Command {
+	var path string
+	var filters map[string]string
+	var limit int
+	var output outputFormat
+	searchCommand := &cobra.Command{
+		Use:   "search [QUERY]...",
+		Short: "Search for datasets and folders",
+		Long: `The search command searches the catalog for datasets and folders matching a free-text QUERY, and shows the
+results ordered by relevance. Use --filter to only show results with the given field values, e.g.
+--filter valuation=SENSITIVE,type=dataset.
+
+If the server does not support searching, the search falls back to walking all datasets and folders under
+--path, which is considerably slower.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := maintenance.SearchQuery{
+				Text:    strings.Join(args, " "),
+				Path:    env.absPath(path),
+				Filters: filters,
+			}
+
+			client, err := env.Maintenance()
+			if err != nil {
+				return err
+			}
+			spinner := env.spinner("Searching")
+			res, err := searchDatasets(cmd.Context(), client, query, limit, env.Stderr)
+			spinner.Stop()
+			if err != nil {
+				return err
+			}
+
+			if output == outputJSON {
+				return printJSON(res, env.Stdout)
+			}
+			printSearchResults(res, env.Stdout)
+			return nil
+		},
+	}
+	searchCommand.Flags().StringVar(&path, "path", "/", "only search under this path")
+	searchCommand.Flags().StringToStringVar(&filters, "filter", map[string]string{},
+		"only show results with these field values ("+strings.Join(maintenance.SearchFilters, ", ")+")")
+	searchCommand.Flags().IntVar(&limit, "limit", 20, "the maximum number of results to show")
+	addOutputFlag(searchCommand, &output)
+	searchCommand.RegisterFlagCompletionFunc("path", completePath(env))
+	searchCommand.RegisterFlagCompletionFunc("filter", completeSearchFilter)
+	return searchCommand
+}
+
+// completeSearchFilter completes the field of the last filter of --filter, or its value if the field has a fixed set
+// of values, e.g. valuation=SE<TAB>
+func completeSearchFilter(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	filter := toComplete[strings.LastIndex(toComplete, ",")+1:]
+	prefix := toComplete[:len(toComplete)-len(filter)]
+	var completions []string
+	if separator := strings.Index(filter, "="); separator >= 0 {
+		field, value := filter[:separator], filter[separator+1:]
+		for _, known := range maintenance.SearchFilterValues[field] {
+			// The values are compared without regard to case
+			if strings.HasPrefix(strings.ToLower(known), strings.ToLower(value)) {
+				completions = append(completions, prefix+field+"="+known)
+			}
+		}
+		return completions, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+	}
+	for _, field := range maintenance.SearchFilters {
+		if strings.HasPrefix(field, filter) {
+			completions = append(completions, prefix+field+"=")
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+}
+
+func searchDatasets(ctx context.Context, client maintenance.API, query maintenance.SearchQuery, limit int, notice io.Writer) (*maintenance.SearchResponse, error) {
+	res := &maintenance.SearchResponse{Results: []maintenance.SearchResult{}}
+	for len(res.Results) < limit {
+		query.Limit = limit - len(res.Results)
+		page, err := client.SearchDatasets(ctx, query)
+		if maintenance.IsSearchUnavailable(err) {
+			fmt.Fprintf(notice, "Search is not supported by the server, searching by walking %s instead\n", query.Path)
+			query.Limit = limit
+			query.PageToken = ""
+			return maintenance.WalkSearch(ctx, client, query)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		res.Results = append(res.Results, page.Results...)
+		res.Total = page.Total
+		res.NextPageToken = page.NextPageToken
+		if page.NextPageToken == "" {
+			break
+		}
+		query.PageToken = page.NextPageToken
+	}
+	return res, nil
+}
+
+// Output:
+// > dapla search inntekt
+//  Score  Name                       Type     Valuation  State
+//  1.00   /skatt/inntekt             BOUNDED  SENSITIVE  INPUT
+//  0.67   /skatt/person/inntekt2020  BOUNDED  SENSITIVE  INPUT
+//  Showing 2 of 14 results
+func printSearchResults(res *maintenance.SearchResponse, output io.Writer) {
+	colorOutput := colorWriter{out: output}
+	writer := tabwriter.NewWriter(colorOutput, 0, 0, 2, ' ', tabwriter.FilterHTML)
+
+	fmt.Fprintln(writer, "<bold>Score</>\t<bold>Name</>\t<bold>Type</>\t<bold>Valuation</>\t<bold>State</>\t")
+	for _, result := range res.Results {
+		name := result.Path
+		if result.IsFolder() {
+			name += "/"
+		}
+		fmt.Fprintf(writer, "%.2f\t%s\t%s\t%s\t%s\t\n", result.Score, name, result.Type, result.Valuation, result.State)
+	}
+	writer.Flush()
+
+	if len(res.Results) == 0 {
+		fmt.Fprintln(output, "No datasets or folders found")
+	} else if res.Total > len(res.Results) {
+		fmt.Fprintf(output, "Showing %d of %d results\n", len(res.Results), res.Total)
+	}
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/acarl005/stripansi"
+	"github.com/andreyvit/diff"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestSearchDatasets(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Get("/api/v1/search").
+		MatchParam("q", "^inntekt$").
+		MatchParam("limit", "^3$").
+		Reply(http.StatusOK).
+		BodyString(`{"results": [
+			{"path": "/skatt/inntekt", "depth": 0, "type": "BOUNDED", "valuation": "SENSITIVE", "state": "INPUT", "score": 0.95},
+			{"path": "/skatt/person", "depth": 1, "score": 0.5}
+		], "total": 4, "nextPageToken": "page2"}`)
+	gock.New("http://server.com").
+		Get("/api/v1/search").
+		MatchParam("limit", "^1$").
+		MatchParam("pageToken", "^page2$").
+		Reply(http.StatusOK).
+		BodyString(`{"results": [{"path": "/skatt/person/inntekt2020", "depth": 0, "score": 0.4}], "total": 4, "nextPageToken": "page3"}`)
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	client := maintenance.NewClient("http://server.com", "a secret secret")
+	var notice bytes.Buffer
+	res, err := searchDatasets(context.Background(), client, maintenance.SearchQuery{Text: "inntekt", Path: "/"}, 3, &notice)
+	assert.Nil(t, err)
+	assert.Empty(t, notice.String())
+
+	var output bytes.Buffer
+	printSearchResults(res, &output)
+
+	expected := `
+Score  Name                       Type     Valuation  State
+0.95   /skatt/inntekt             BOUNDED  SENSITIVE  INPUT
+0.50   /skatt/person/
+0.40   /skatt/person/inntekt2020
+Showing 3 of 4 results
+`
+	if actual, expected := diff.TrimLinesInString(stripansi.Strip(output.String())),
+		diff.TrimLinesInString(expected); actual != expected {
+		t.Errorf("Result not as expected:\n%v", diff.LineDiff(expected, actual))
+	}
+}
+
+func TestSearchDatasetsFallback(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Get("/api/v1/search").
+		Reply(http.StatusNotFound)
+	gock.New("http://server.com").
+		Get("/api/v1/list/skatt$").
+		Reply(http.StatusOK).
+		BodyString(`[{"path": "skatt/inntekt", "depth": 0},{"path": "skatt/naering", "depth": 0}]`)
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	client := maintenance.NewClient("http://server.com", "a secret secret")
+	var notice bytes.Buffer
+	res, err := searchDatasets(context.Background(), client, maintenance.SearchQuery{Text: "inntekt", Path: "skatt"}, 20, &notice)
+	assert.Nil(t, err)
+	assert.Equal(t, "Search is not supported by the server, searching by walking skatt instead\n", notice.String())
+	assert.Len(t, res.Results, 1)
+	assert.Equal(t, "skatt/inntekt", res.Results[0].Path)
+}
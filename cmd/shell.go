@@ -0,0 +1,390 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"github.com/statisticsnorway/dapla-cli/plugin"
+	"github.com/statisticsnorway/dapla-cli/shell"
+)
+
+// historyFile is the name of the file in the config directory holding the lines entered in the shell
+const historyFile = ".dapla-cli_history"
+
+// shellBuiltins are the commands handled by the shell itself, in addition to the dapla commands
+var shellBuiltins = []string{"cd", "exit", "history", "pwd"}
+
+func newShellCommand(env *Env) *cobra.Command {
+	return &cobra.Command{
+		Use:   "shell",
+		Short: "Run dapla commands interactively",
+		Long: `The shell command starts an interactive prompt where dapla commands can be run without the dapla prefix,
+e.g. "ls /skatt". The configuration is read, and the auth token retrieved, only once for the whole session. The
+global flags given to the shell command, e.g. "dapla --no-cache shell", apply to every command in the shell, unless
+the command gives them again, e.g. "--debug=false ls".
+
+The shell starts in the folder given by the home-folder config option, e.g. /user/ola, or the root folder if it is
+not set.
+
+The shell has a working folder, which relative dataset paths are resolved against. It is changed with "cd PATH"
+(or "cd" to go back to the home folder) and printed with "pwd". The arrow keys recall previous lines, which are
+saved in ~/.dapla-cli_history, and TAB completes commands, flags and paths. Ctrl+C aborts the running command, and
+"exit" or Ctrl+D ends the shell.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s := newShellSession(env)
+			s.flags = shellFlags(cmd.Root().PersistentFlags())
+			return s.run()
+		},
+	}
+}
+
+// shellSession holds the state of a running shell
+type shellSession struct {
+	// env is the Env of the commands run in the shell
+	env *Env
+	// cache holds the listings used by completion, and is cleared after every command, since commands may change
+	// the datasets
+	cache *listingCache
+	// historyFile is the file the lines are saved to, or empty if the history is not saved
+	historyFile string
+	// flags are the global flags given to the shell command, which are given to every command before its own args,
+	// so that they apply to all the commands in the shell, unless a command gives them again
+	flags  []string
+	reader *shell.LineReader
+}
+
+// newShellSession creates a shell running commands in env. The session reuses the API clients, and the config
+// already read into env.
+func newShellSession(env *Env) *shellSession {
+	s := &shellSession{}
+
+	var stdinFile *os.File
+	if file, ok := env.Stdin.(*os.File); ok {
+		stdinFile = file
+	}
+	s.reader = shell.NewLineReader(env.prompts(), env.Stderr, stdinFile)
+	s.reader.Complete = s.complete
+	if env.ConfigDir != "" {
+		s.historyFile = filepath.Join(env.ConfigDir, historyFile)
+	}
+
+	sessionEnv := *env
+	sessionEnv.ConfigDir = ""
+	sessionEnv.WorkingDir = shellHome(env)
+
+	var maintenanceOnce sync.Once
+	var maintenanceClient maintenance.API
+	var maintenanceErr error
+	sessionEnv.Maintenance = func() (maintenance.API, error) {
+		maintenanceOnce.Do(func() {
+			maintenanceClient, maintenanceErr = env.Maintenance()
+		})
+		return maintenanceClient, maintenanceErr
+	}
+	var exportOnce sync.Once
+	var exporter Exporter
+	var exportErr error
+	sessionEnv.Export = func() (Exporter, error) {
+		exportOnce.Do(func() {
+			exporter, exportErr = env.Export()
+		})
+		return exporter, exportErr
+	}
+
+	s.env = &sessionEnv
+	s.cache = &listingCache{}
+	return s
+}
+
+// run reads and executes lines until the input ends or the user exits
+func (s *shellSession) run() error {
+	if err := s.loadHistory(); err != nil {
+		fmt.Fprintln(s.env.Stderr, "Could not read the shell history:", err)
+	}
+
+	for {
+		prompt := ""
+		if s.reader.Interactive() {
+			prompt = "dapla:" + s.env.WorkingDir + "> "
+		}
+		line, err := s.reader.ReadLine(prompt)
+		if err == shell.ErrInterrupted {
+			continue
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if exit := s.execute(line); exit {
+			break
+		}
+		s.cache.clear()
+	}
+
+	if err := s.saveHistory(); err != nil {
+		fmt.Fprintln(s.env.Stderr, "Could not save the shell history:", err)
+	}
+	return nil
+}
+
+// execute runs a line of input, and returns true if the shell should exit
+func (s *shellSession) execute(line string) bool {
+	args, err := shell.Split(line)
+	if err != nil {
+		fmt.Fprintln(s.env.Stderr, "Error:", err)
+		return false
+	}
+	args = commandArgs(args)
+	if len(args) == 0 {
+		return false
+	}
+
+	switch args[0] {
+	case "exit", "quit":
+		return true
+	case "pwd":
+		fmt.Fprintln(s.env.Stdout, s.env.WorkingDir)
+	case "cd":
+		if err := s.cd(args[1:]); err != nil {
+			fmt.Fprintln(s.env.Stderr, "Error:", err)
+		}
+	case "history":
+		for i, historyLine := range s.reader.History.Lines {
+			fmt.Fprintf(s.env.Stdout, "%5d  %s\n", i+1, historyLine)
+		}
+	case "shell":
+		fmt.Fprintln(s.env.Stderr, "Error: already in the dapla shell")
+	default:
+		// Ctrl+C aborts the command, but not the shell
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		Run(ctx, s.env, append(append([]string{}, s.flags...), args...))
+	}
+	return false
+}
+
+// shellFlags returns the global flags that were given in flags as args, except --config, since the config file is
+// only read when the shell starts
+func shellFlags(flags *pflag.FlagSet) []string {
+	var args []string
+	flags.VisitAll(func(flag *pflag.Flag) {
+		switch {
+		case !flag.Changed || flag.Name == "config":
+		case flag.Name == CFGAPIs:
+			// The String method of map flags does not give a value that can be set again
+			apis, _ := flags.GetStringToString(flag.Name)
+			names := make([]string, 0, len(apis))
+			for name := range apis {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				args = append(args, "--"+flag.Name+"="+name+"="+apis[name])
+			}
+		default:
+			args = append(args, "--"+flag.Name+"="+flag.Value.String())
+		}
+	})
+	return args
+}
+
+// cd changes the working folder to the folder given by args, or the home folder if no folder is given
+func (s *shellSession) cd(args []string) error {
+	if len(args) > 1 {
+		return errors.New("cd takes at most one folder")
+	}
+	folder := shellHome(s.env)
+	if len(args) == 1 {
+		folder = s.env.absPath(args[0])
+	}
+	if folder == "/" {
+		s.env.WorkingDir = folder
+		return nil
+	}
+
+	client, err := s.cachedMaintenance()
+	if err != nil {
+		return err
+	}
+	res, err := client.ListDatasets(context.Background(), path.Dir(folder))
+	if err != nil {
+		return err
+	}
+	for _, element := range *res {
+		if strings.TrimSuffix(element.Path, "/") != folder {
+			continue
+		}
+		if !element.IsFolder() {
+			return fmt.Errorf("%s is a dataset, not a folder", folder)
+		}
+		s.env.WorkingDir = folder
+		return nil
+	}
+	return fmt.Errorf("folder %s not found", folder)
+}
+
+// shellHome returns the home folder of the shell: the home-folder config option, or the root folder if not set
+func shellHome(env *Env) string {
+	if home := env.Config.GetString(CFGHomeFolder); home != "" {
+		return path.Clean("/" + home)
+	}
+	return "/"
+}
+
+// complete completes the last word of head, the line up to the cursor, by running the hidden completion command of
+// cobra. Paths are completed against the cached listings.
+func (s *shellSession) complete(head string) ([]string, bool) {
+	args, err := shell.Split(head)
+	if err != nil {
+		return nil, false
+	}
+	toComplete := ""
+	if len(args) > 0 && !strings.HasSuffix(head, " ") && !strings.HasSuffix(head, "\t") {
+		toComplete = args[len(args)-1]
+		args = args[:len(args)-1]
+	}
+	args = commandArgs(args)
+
+	var builtins []string
+	onlyFolders := false
+	if len(args) == 0 {
+		for _, builtin := range shellBuiltins {
+			if strings.HasPrefix(builtin, toComplete) {
+				builtins = append(builtins, builtin)
+			}
+		}
+	} else if args[0] == "cd" {
+		// cd completes folders like ls
+		args = []string{"ls"}
+		onlyFolders = true
+	}
+
+	var output bytes.Buffer
+	completeEnv := *s.env
+	completeEnv.Maintenance = s.cachedMaintenance
+	completeEnv.Stdout = &output
+	completeEnv.Stderr = ioutil.Discard
+	completeArgs := append(append([]string{cobra.ShellCompRequestCmd}, s.flags...), args...)
+	Run(context.Background(), &completeEnv, append(completeArgs, toComplete))
+
+	candidates, directive := parseCompletions(output.String())
+	if onlyFolders {
+		var folders []string
+		for _, candidate := range candidates {
+			if strings.HasSuffix(candidate, "/") {
+				folders = append(folders, candidate)
+			}
+		}
+		candidates = folders
+	}
+	return append(builtins, candidates...), directive&cobra.ShellCompDirectiveNoSpace == 0
+}
+
+// cachedMaintenance returns the client of the session, with the listings cached
+func (s *shellSession) cachedMaintenance() (maintenance.API, error) {
+	client, err := s.env.cachedMaintenance()
+	if err != nil {
+		return nil, err
+	}
+	s.cache.API = client
+	return s.cache, nil
+}
+
+func (s *shellSession) loadHistory() error {
+	if s.historyFile == "" {
+		return nil
+	}
+	file, err := os.Open(s.historyFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return s.reader.History.Load(file)
+}
+
+func (s *shellSession) saveHistory() error {
+	if s.historyFile == "" {
+		return nil
+	}
+	file, err := os.OpenFile(s.historyFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if err := s.reader.History.Save(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// commandArgs returns the arguments of a dapla command line, without the dapla command itself if it is included
+func commandArgs(args []string) []string {
+	if len(args) > 0 && args[0] == "dapla" {
+		return args[1:]
+	}
+	return args
+}
+
+// parseCompletions parses the output of the hidden completion command of cobra (see plugin.ParseCompletions) into
+// the candidates, without their descriptions, and the directive
+func parseCompletions(output string) ([]string, cobra.ShellCompDirective) {
+	lines, directive, err := plugin.ParseCompletions(output)
+	if err != nil || cobra.ShellCompDirective(directive)&cobra.ShellCompDirectiveError != 0 {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	var candidates []string
+	for _, line := range lines {
+		if candidate := strings.SplitN(line, "\t", 2)[0]; candidate != "" {
+			candidates = append(candidates, candidate)
+		}
+	}
+	return candidates, cobra.ShellCompDirective(directive)
+}
+
+// listingCache is a data-maintenance API that caches the folder listings, so that completing a path does not list
+// the same folders again on every TAB
+type listingCache struct {
+	maintenance.API
+	listings map[string]*maintenance.ListDatasetResponse
+}
+
+func (c *listingCache) ListDatasets(ctx context.Context, path string) (*maintenance.ListDatasetResponse, error) {
+	if res, ok := c.listings[path]; ok {
+		return res, nil
+	}
+	res, err := c.API.ListDatasets(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if c.listings == nil {
+		c.listings = map[string]*maintenance.ListDatasetResponse{}
+	}
+	c.listings[path] = res
+	return res, nil
+}
+
+// clear empties the cache
+func (c *listingCache) clear() {
+	c.listings = nil
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunShell(t *testing.T) {
+	env, stdout, stderr := newTestEnv(newTestAPI(), "cd skatt\nls\ncd 2020\npwd\nls\ncd ..\ncd inntekt\ncd /nope\n"+
+		"dapla ls '2020'\ncd\npwd\nexit\nls\n")
+
+	assert.Equal(t, 0, Run(context.Background(), env, []string{"shell"}))
+	assert.Equal(t, "2020\nformue\ninntekt\n/skatt/2020\ninntekt\ninntekt\n/\n", stdout.String())
+	assert.Equal(t, "Error: /skatt/inntekt is a dataset, not a folder\nError: folder /nope not found\n", stderr.String())
+}
+
+func TestRunShell_GlobalFlags(t *testing.T) {
+	env, stdout, _ := newTestEnv(newTestAPI(), "doctor\n--debug=false --apis data-maintenance=http://other doctor\ndoctor\n")
+
+	// The global flags given to the shell apply to all commands, unless they are given again
+	assert.Equal(t, 0, Run(context.Background(), env, []string{
+		"--debug", "--no-cache", "--apis", "data-maintenance=http://maintenance", "shell",
+	}))
+	assert.Equal(t, 2, strings.Count(stdout.String(), `"debug": true`))
+	assert.Equal(t, 1, strings.Count(stdout.String(), `"debug": false`))
+	assert.Equal(t, 2, strings.Count(stdout.String(), `"data-maintenance": "http://maintenance"`))
+	assert.Equal(t, 1, strings.Count(stdout.String(), `"data-maintenance": "http://other"`))
+	assert.Equal(t, 3, strings.Count(stdout.String(), `"no-cache": true`))
+}
+
+func TestRunShell_HomeFolder(t *testing.T) {
+	env, stdout, stderr := newTestEnv(newTestAPI(), "pwd\nls\ncd /\ncd\npwd\n")
+	env.Config.Set(CFGHomeFolder, "/skatt/2020")
+
+	assert.Equal(t, 0, Run(context.Background(), env, []string{"shell"}))
+	assert.Equal(t, "/skatt/2020\ninntekt\n/skatt/2020\n", stdout.String())
+	assert.Empty(t, stderr.String())
+}
+
+func TestRunShell_Prompt(t *testing.T) {
+	api := newTestAPI()
+	env, stdout, stderr := newTestEnv(api, "cd /skatt\nrm --recursive .\ny\nno\nyes\npwd\n")
+
+	// The answers to the prompts of a command are read from the same input as the lines of the shell
+	assert.Equal(t, 0, Run(context.Background(), env, []string{"shell"}))
+	assert.Equal(t, []string{"trash /skatt/2020/inntekt", "trash /skatt/inntekt"}, api.calls)
+	assert.Equal(t, "Move dataset /skatt/2020/inntekt to trash? "+
+		"Move dataset /skatt/formue to trash? ... skipped\n"+
+		"Move dataset /skatt/inntekt to trash? ", stderr.String())
+	assert.True(t, strings.HasSuffix(stdout.String(), "/skatt\n"))
+}
+
+func TestRunShell_History(t *testing.T) {
+	env, stdout, _ := newTestEnv(newTestAPI(), "pwd\ncd /skatt\nhistory\n")
+	env.ConfigDir = t.TempDir()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(env.ConfigDir, historyFile), []byte("ls /skatt\n"), 0600))
+
+	assert.Equal(t, 0, Run(context.Background(), env, []string{"shell"}))
+	assert.Equal(t, "/\n    1  ls /skatt\n    2  pwd\n    3  cd /skatt\n    4  history\n", stdout.String())
+
+	saved, err := ioutil.ReadFile(filepath.Join(env.ConfigDir, historyFile))
+	assert.NoError(t, err)
+	assert.Equal(t, "ls /skatt\npwd\ncd /skatt\nhistory\n", string(saved))
+}
+
+func TestShellSession_Complete(t *testing.T) {
+	env, _, _ := newTestEnv(newTestAPI(), "")
+	session := newShellSession(env)
+
+	candidates, _ := session.complete("p")
+	assert.Equal(t, []string{"pwd", "plugin", "prune"}, candidates)
+
+	// The global flags of the shell are given when completing too
+	session.flags = []string{"--no-cache=true"}
+	candidates, _ = session.complete("ls /skatt/2")
+	assert.Equal(t, []string{"/skatt/2020/"}, candidates)
+	session.flags = nil
+
+	candidates, space := session.complete("ls /skatt/f")
+	assert.Equal(t, []string{"/skatt/formue"}, candidates)
+	assert.True(t, space)
+
+	session.env.WorkingDir = "/skatt"
+	candidates, space = session.complete("ls ")
+	assert.Equal(t, []string{"2020/", "formue", "inntekt"}, candidates)
+	assert.False(t, space)
+
+	candidates, _ = session.complete("dapla cd ")
+	assert.Equal(t, []string{"2020/"}, candidates)
+
+	candidates, _ = session.complete("ls 2020/")
+	assert.Equal(t, []string{"2020/inntekt"}, candidates)
+}
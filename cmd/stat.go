@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+)
+
+// statSchemaFields is the number of schema fields listed by stat, before the rest are summarized
+const statSchemaFields = 10
+
+func newStatCommand(env *Env) *cobra.Command {
+	var output outputFormat
+	statCommand := &cobra.Command{
+		Use:     "stat [PATH]",
+		Aliases: []string{"info"},
+		Short:   "Show detailed metadata of a dataset",
+		Long: `The stat command shows everything that is known about the dataset at a given PATH: who created it and when,
+its type, valuation and state, its versions and size, a summary of its schema, its pseudonymization rules and
+the datasets it was derived from (lineage), if available.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := env.Maintenance()
+			if err != nil {
+				return err
+			}
+			path := env.absPath(args[0])
+			spinner := env.spinner("Fetching metadata of " + path)
+			stat, err := statDataset(cmd.Context(), client, path)
+			spinner.Stop()
+			if err != nil {
+				return err
+			}
+
+			if output == outputJSON {
+				return printJSON(stat, env.Stdout)
+			}
+			printStat(stat, env.Stdout)
+			return nil
+		},
+		ValidArgsFunction: completePath(env),
+	}
+	addOutputFlag(statCommand, &output)
+	return statCommand
+}
+
+type datasetStat struct {
+	*maintenance.DatasetInfo
+	Versions      int                       `json:"versions"`
+	LatestVersion *time.Time                `json:"latestVersion,omitempty"`
+	Size          uint64                    `json:"size"`
+	Fields        []maintenance.SchemaField `json:"fields"`
+}
+
+// statDataset fetches the metadata, versions and schema of the dataset at path. Datasets without a schema are
+// reported without fields.
+func statDataset(ctx context.Context, client maintenance.API, path string) (*datasetStat, error) {
+	info, err := client.GetDatasetInfo(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := client.ListVersions(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	stat := &datasetStat{
+		DatasetInfo: info,
+		Versions:    len(versions.Versions),
+		Size:        versions.TotalSize(),
+		Fields:      []maintenance.SchemaField{},
+	}
+	if timestamps := versions.Timestamps(); len(timestamps) > 0 {
+		latest := timestamps[0]
+		for _, timestamp := range timestamps[1:] {
+			if timestamp.After(latest) {
+				latest = timestamp
+			}
+		}
+		stat.LatestVersion = &latest
+	}
+
+	schema, err := client.GetDatasetSchema(ctx, path)
+	if httpErr, ok := err.(*maintenance.HTTPError); ok && httpErr.StatusCode() == http.StatusNotFound {
+		return stat, nil
+	} else if err != nil {
+		return nil, err
+	}
+	stat.Fields = schema.Fields
+	return stat, nil
+}
+
+// Output:
+// > dapla stat /foo/bar
+//  Path:            /foo/bar
+//  Created by:      kari
+//  Created:         2021-03-01T12:00:00Z
+//  Type:            BOUNDED
+//  Valuation:       SENSITIVE
+//  State:           INPUT
+//  Versions:        2
+//  Latest version:  2021-04-02T08:32:21.234Z (1617352341234)
+//  Size:            3.1M (3250586 bytes)
+//  Schema:          2 fields
+//    person/fnr     string
+//    income         long
+//  Pseudo rules:    1 rule
+//    fnr            **/fnr  fpe-fnr(secret1)
+//  Lineage:         /kilde/bar@1612137600000
+func printStat(stat *datasetStat, output io.Writer) {
+	writer := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	defer writer.Flush()
+
+	fmt.Fprintf(writer, "Path:\t%s\n", stat.Path)
+	fmt.Fprintf(writer, "Created by:\t%s\n", stat.CreatedBy)
+	fmt.Fprintf(writer, "Created:\t%s\n", stat.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(writer, "Type:\t%s\n", stat.Type)
+	fmt.Fprintf(writer, "Valuation:\t%s\n", stat.Valuation)
+	fmt.Fprintf(writer, "State:\t%s\n", stat.State)
+	fmt.Fprintf(writer, "Versions:\t%d\n", stat.Versions)
+	if stat.LatestVersion != nil {
+		fmt.Fprintf(writer, "Latest version:\t%s (%d)\n",
+			stat.LatestVersion.Format(time.RFC3339Nano), maintenance.EpochMillis(*stat.LatestVersion))
+	}
+	fmt.Fprintf(writer, "Size:\t%s (%d bytes)\n", formatSize(stat.Size, true), stat.Size)
+
+	fmt.Fprintf(writer, "Schema:\t%d %s\n", len(stat.Fields), pluralize("field", len(stat.Fields)))
+	for i, field := range stat.Fields {
+		if i == statSchemaFields {
+			fmt.Fprintf(writer, "  ...and %d more\n", len(stat.Fields)-statSchemaFields)
+			break
+		}
+		fmt.Fprintf(writer, "  %s\t%s\n", field.Path, field.Type)
+	}
+
+	if len(stat.PseudoRules) == 0 {
+		fmt.Fprintln(writer, "Pseudo rules:\tnone")
+	} else {
+		fmt.Fprintf(writer, "Pseudo rules:\t%d %s\n", len(stat.PseudoRules), pluralize("rule", len(stat.PseudoRules)))
+		for _, rule := range stat.PseudoRules {
+			fmt.Fprintf(writer, "  %s\t%s  %s\n", rule.Name, rule.Pattern, rule.Func)
+		}
+	}
+
+	if len(stat.Lineage) == 0 {
+		fmt.Fprintln(writer, "Lineage:\tnone")
+	}
+	for i, source := range stat.Lineage {
+		label := ""
+		if i == 0 {
+			label = "Lineage:"
+		}
+		fmt.Fprintf(writer, "%s\t%s@%d\n", label, source.DatasetPath, maintenance.EpochMillis(source.Version))
+	}
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/andreyvit/diff"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestStatDataset(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Get("/api/v1/info/foo/bar").
+		Reply(http.StatusOK).
+		BodyString(`{"path": "/foo/bar", "createdBy": "kari", "createdDate": "2021-03-01T12:00:00Z",
+			"type": "BOUNDED", "valuation": "SENSITIVE", "state": "INPUT",
+			"pseudoRules": [{"name": "fnr", "pattern": "**/fnr", "func": "fpe-fnr(secret1)"}],
+			"lineage": [{"datasetPath": "/kilde/bar", "version": "2021-02-01T00:00:00Z"}]}`)
+	gock.New("http://server.com").
+		Get("/api/v1/versions/foo/bar").
+		Reply(http.StatusOK).
+		BodyString(`{"datasetPath": "/foo/bar", "versions": [
+			{"timestamp": "2021-04-02T08:32:21.234Z", "files": [{"uri": "gs://bucket/foo/bar/v2/file1", "size": 2048}]},
+			{"timestamp": "2021-03-01T12:00:00Z", "files": [{"uri": "gs://bucket/foo/bar/v1/file1", "size": 1024}]}
+		]}`)
+	gock.New("http://server.com").
+		Get("/api/v1/schema/foo/bar").
+		Reply(http.StatusOK).
+		BodyString(`{"datasetPath": "/foo/bar", "fields": [
+			{"path": "person/fnr", "type": "string"},
+			{"path": "income", "type": "long"}
+		]}`)
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	client := maintenance.NewClient("http://server.com", "a secret secret")
+	stat, err := statDataset(context.Background(), client, "foo/bar")
+	assert.Nil(t, err)
+
+	var output bytes.Buffer
+	printStat(stat, &output)
+
+	expected := `
+Path:            /foo/bar
+Created by:      kari
+Created:         2021-03-01T12:00:00Z
+Type:            BOUNDED
+Valuation:       SENSITIVE
+State:           INPUT
+Versions:        2
+Latest version:  2021-04-02T08:32:21.234Z (1617352341234)
+Size:            3.0K (3072 bytes)
+Schema:          2 fields
+  person/fnr     string
+  income         long
+Pseudo rules:    1 rule
+  fnr            **/fnr  fpe-fnr(secret1)
+Lineage:         /kilde/bar@1612137600000
+`
+	if actual, expected := diff.TrimLinesInString(output.String()), diff.TrimLinesInString(expected); actual != expected {
+		t.Errorf("Result not as expected:\n%v", diff.LineDiff(expected, actual))
+	}
+}
+
+func TestStatDatasetWithoutSchema(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Get("/api/v1/info/foo/bar").
+		Reply(http.StatusOK).
+		BodyString(`{"path": "/foo/bar", "createdBy": "kari", "createdDate": "2021-03-01T12:00:00Z"}`)
+	gock.New("http://server.com").
+		Get("/api/v1/versions/foo/bar").
+		Reply(http.StatusOK).
+		BodyString(`{"datasetPath": "/foo/bar", "versions": []}`)
+	gock.New("http://server.com").
+		Get("/api/v1/schema/foo/bar").
+		Reply(http.StatusNotFound)
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	client := maintenance.NewClient("http://server.com", "a secret secret")
+	stat, err := statDataset(context.Background(), client, "foo/bar")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, stat.Versions)
+	assert.Nil(t, stat.LatestVersion)
+	assert.Empty(t, stat.Fields)
+	assert.Equal(t, time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC), stat.CreatedAt)
+}
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+)
+
+func newTrashCommand(env *Env) *cobra.Command {
+	trashCommand := &cobra.Command{
+		Use:   "trash",
+		Short: "Manage deleted datasets",
+		Long: `The trash commands manage the datasets deleted by the current user. Deleted datasets are kept in the trash
+until they expire, after which they are permanently deleted. Use the restore command to restore a dataset from
+the trash.`,
+	}
+	trashCommand.AddCommand(newTrashLsCommand(env), newTrashEmptyCommand(env))
+	return trashCommand
+}
+
+func newTrashLsCommand(env *Env) *cobra.Command {
+	var output outputFormat
+	lsCommand := &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "List the datasets in the trash",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := env.Maintenance()
+			if err != nil {
+				return err
+			}
+			trashed, err := client.ListTrash(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			if output == outputJSON {
+				return printJSON(trashed, env.Stdout)
+			}
+			printTrash(trashed, env.Stdout)
+			return nil
+		},
+	}
+	addOutputFlag(lsCommand, &output)
+	return lsCommand
+}
+
+func newTrashEmptyCommand(env *Env) *cobra.Command {
+	var dryRun, yes, override bool
+	emptyCommand := &cobra.Command{
+		Use:   "empty [PATH]...",
+		Short: "Permanently delete the datasets in the trash",
+		Long: `The empty command permanently deletes all the datasets in the trash, or only the trashed datasets at or
+under the given PATHs. This can not be undone, so the datasets are listed and must be confirmed first, unless --yes
+is given. Protected datasets are refused, unless --override-protection is given and each path is confirmed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = env.absPaths(args)
+			if len(args) == 0 {
+				args = []string{""}
+			}
+
+			client, err := env.Maintenance()
+			if err != nil {
+				return err
+			}
+			trashed, err := client.ListTrash(cmd.Context())
+			if err != nil {
+				return err
+			}
+			var doomed []maintenance.TrashedDataset
+			for _, dataset := range trashed {
+				for _, path := range args {
+					if path == "" || maintenance.IsWithin(dataset.DatasetPath, path) {
+						doomed = append(doomed, dataset)
+						break
+					}
+				}
+			}
+			if len(doomed) == 0 {
+				fmt.Fprintln(env.Stdout, "The trash is empty")
+				return nil
+			}
+
+			protected, err := newProtection(cmd.Context(), env, client, override)
+			if err != nil {
+				return err
+			}
+			for _, dataset := range doomed {
+				if err := protected.check(dataset.DatasetPath); err != nil {
+					return err
+				}
+			}
+			if !yes && !dryRun {
+				printTrash(doomed, env.Stderr)
+				question := fmt.Sprintf("Permanently delete %d %s from the trash?", len(doomed), pluralize("dataset", len(doomed)))
+				if !confirm(env, question) {
+					fmt.Fprintln(env.Stderr, "... nothing was deleted")
+					return nil
+				}
+			}
+
+			summary := deleteSummary{DryRun: dryRun, Permanent: true}
+			for _, path := range args {
+				spinner := env.spinner("Emptying trash")
+				deleted, err := client.EmptyTrash(cmd.Context(), path, dryRun)
+				spinner.Stop()
+				if err != nil {
+					return err
+				}
+
+				for i := range deleted {
+					summary.Datasets = append(summary.Datasets, &deleted[i])
+					printDeleteResponse(&deleted[i], env.Stdout, env.Config.GetBool(CFGDebug))
+				}
+			}
+			printDeleteSummary(&summary, env.Stdout)
+			return nil
+		},
+	}
+	emptyCommand.Flags().BoolVarP(&dryRun, "dry-run", "", false, "dry run")
+	emptyCommand.Flags().BoolVarP(&yes, "yes", "y", false, "delete without asking for confirmation")
+	addOverrideProtectionFlag(emptyCommand, &override)
+	return emptyCommand
+}
+
+func newRestoreCommand(env *Env) *cobra.Command {
+	var to string
+	var override bool
+	conflict := conflictPolicy(maintenance.ConflictFail)
+	restoreCommand := &cobra.Command{
+		Use:   "restore [PATH]...",
+		Short: "Restore dataset(s) from the trash",
+		Long: `The restore command moves deleted datasets from the trash back to their original PATH, or to another path
+given by --to. The --conflict flag decides what happens if a dataset already exists at the destination.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if to != "" && len(args) > 1 {
+				return errors.New("--to can only be used when restoring a single dataset")
+			}
+			args = env.absPaths(args)
+			if to != "" {
+				to = env.absPath(to)
+			}
+
+			client, err := env.Maintenance()
+			if err != nil {
+				return err
+			}
+			protected, err := newProtection(cmd.Context(), env, client, override)
+			if err != nil {
+				return err
+			}
+			if conflict == conflictPolicy(maintenance.ConflictOverwrite) {
+				for _, path := range args {
+					if to != "" {
+						path = to
+					}
+					if err := protected.check(path); err != nil {
+						return err
+					}
+				}
+			}
+
+			var results []*maintenance.MoveDatasetResponse
+			for _, path := range args {
+				spinner := env.spinner("Restoring dataset " + path)
+				res, err := client.RestoreDataset(cmd.Context(), path, to, maintenance.ConflictPolicy(conflict))
+				spinner.Stop()
+				if err != nil {
+					return err
+				}
+				results = append(results, res)
+			}
+			printMoveResults(results, env.Stdout, false)
+			return nil
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			client, err := env.Maintenance()
+			if err != nil {
+				return handleCompleteError("could not create client:", err)
+			}
+			trashed, err := client.ListTrash(context.Background())
+			if err != nil {
+				return handleCompleteError("could not fetch trash:", err)
+			}
+			var paths []string
+			for _, dataset := range trashed {
+				paths = append(paths, dataset.DatasetPath)
+			}
+			return paths, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	restoreCommand.Flags().StringVar(&to, "to", "", "restore the dataset to this path instead of its original path")
+	restoreCommand.Flags().Var(&conflict, "conflict", "what to do if the destination already exists (fail, skip or overwrite)")
+	restoreCommand.RegisterFlagCompletionFunc("conflict", completeConflictPolicy)
+	restoreCommand.RegisterFlagCompletionFunc("to", completePath(env))
+	addOverrideProtectionFlag(restoreCommand, &override)
+	return restoreCommand
+}
+
+func printTrash(trashed []maintenance.TrashedDataset, output io.Writer) {
+	colorOutput := colorWriter{out: output}
+	writer := tabwriter.NewWriter(colorOutput, 15, 0, 2, ' ', tabwriter.FilterHTML)
+	defer writer.Flush()
+
+	fmt.Fprintln(writer,
+		"<bold>Name</>\t"+
+			"<bold>Deleted</>\t"+
+			"<bold>Expires</>\t"+
+			"<bold>Versions</>\t"+
+			"<bold>Size</>\t")
+	for _, dataset := range trashed {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%d\t%s\t\n",
+			dataset.DatasetPath,
+			dataset.TrashedAt.Format(time.RFC3339),
+			dataset.ExpiresAt.Format(time.RFC3339),
+			dataset.Versions,
+			formatSize(dataset.TotalSize, true))
+	}
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acarl005/stripansi"
+	"github.com/andreyvit/diff"
+	"github.com/statisticsnorway/dapla-cli/devserver"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"github.com/stretchr/testify/assert"
+)
+
+var testTrashed = []maintenance.TrashedDataset{
+	{
+		DatasetPath: "/foo/bar",
+		TrashedAt:   time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC),
+		ExpiresAt:   time.Date(2021, 5, 31, 0, 0, 0, 0, time.UTC),
+		Versions:    2,
+		TotalSize:   1536,
+	},
+	{
+		DatasetPath: "/foo/baz",
+		TrashedAt:   time.Date(2021, 5, 2, 0, 0, 0, 0, time.UTC),
+		ExpiresAt:   time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+		Versions:    1,
+		TotalSize:   512,
+	},
+}
+
+func TestPrintTrash(t *testing.T) {
+	var output bytes.Buffer
+	printTrash(testTrashed, &output)
+
+	expected := `
+Name           Deleted               Expires               Versions       Size
+/foo/bar       2021-05-01T00:00:00Z  2021-05-31T00:00:00Z  2              1.5K
+/foo/baz       2021-05-02T00:00:00Z  2021-06-01T00:00:00Z  1              512B
+`
+	if actual, expected := diff.TrimLinesInString(stripansi.Strip(output.String())),
+		diff.TrimLinesInString(expected); actual != expected {
+		t.Errorf("Result not as expected:\n%v", diff.LineDiff(expected, actual))
+	}
+}
+
+func TestPrintTrashResponse(t *testing.T) {
+	var output bytes.Buffer
+	printTrashResponse(&testTrashed[0], &output)
+	printDeleteSummary(&deleteSummary{Trashed: []*maintenance.TrashedDataset{&testTrashed[0], &testTrashed[1]}}, &output)
+
+	expected := "Dataset /foo/bar (2 versions, 1.5K) moved to trash, expires 2021-05-31T00:00:00Z\n" +
+		"Moved 2 datasets to trash (3 versions, 2.0K)"
+	if actual := strings.TrimSpace(output.String()); actual != expected {
+		t.Errorf("Result not as expected:\n%v", diff.LineDiff(expected, actual))
+	}
+}
+
+func TestRunTrashEmpty(t *testing.T) {
+	server := httptest.NewServer(devserver.New(devserver.SeedCatalog()).MaintenanceHandler())
+	defer server.Close()
+	client := maintenance.New(server.URL)
+	ctx := context.Background()
+	for _, path := range []string{"/skatt/person/formue", "/produkt/inntekt/statistikk"} {
+		_, err := client.TrashDataset(ctx, path, time.Hour, false)
+		assert.NoError(t, err)
+	}
+
+	// Protected datasets are refused
+	env, _, _ := newTestEnv(client, "")
+	assert.Equal(t, 1, Run(ctx, env, []string{"trash", "empty"}))
+
+	// Nothing is deleted without confirmation
+	env, stdout, stderr := newTestEnv(client, "n\n")
+	assert.Equal(t, 0, Run(ctx, env, []string{"trash", "empty", "/skatt"}))
+	assert.Contains(t, stderr.String(), "Permanently delete 1 dataset from the trash? [y/N] ")
+	assert.Empty(t, stdout.String())
+	trashed, _ := client.ListTrash(ctx)
+	assert.Len(t, trashed, 2)
+
+	env, stdout, _ = newTestEnv(client, "y\n")
+	assert.Equal(t, 0, Run(ctx, env, []string{"trash", "empty", "/skatt"}))
+	assert.Contains(t, stdout.String(), "Dataset /skatt/person/formue (1 version) successfully deleted")
+
+	// Protected datasets are deleted with --override-protection, after typing the path, and --yes skips the question
+	env, stdout, _ = newTestEnv(client, "/produkt/inntekt/statistikk\n")
+	assert.Equal(t, 0, Run(ctx, env, []string{"trash", "empty", "--yes", "--override-protection"}))
+	assert.Contains(t, stdout.String(), "Dataset /produkt/inntekt/statistikk (1 version) successfully deleted")
+	trashed, _ = client.ListTrash(ctx)
+	assert.Empty(t, trashed)
+}
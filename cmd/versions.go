@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+)
+
+func newVersionsCommand(env *Env) *cobra.Command {
+	var long bool
+	var diffSelectors []string
+	var output outputFormat
+	versionsCommand := &cobra.Command{
+		Use:   "versions [PATH]",
+		Short: "List the versions of a dataset",
+		Long: `The versions command lists all the versions of a dataset, with the number of files and total size of each version.
+
+Use --diff to compare the files of two versions. Versions are specified the same way as for the export
+command, e.g. --diff latest~1,latest. If only one version is given, it is compared with the latest version.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := env.Maintenance()
+			if err != nil {
+				return err
+			}
+			res, err := client.ListVersions(cmd.Context(), env.absPath(args[0]))
+			if err != nil {
+				return err
+			}
+
+			if len(diffSelectors) > 0 {
+				diff, err := diffVersions(res, diffSelectors)
+				if err != nil {
+					return err
+				}
+				if output == outputJSON {
+					return printJSON(diff, env.Stdout)
+				}
+				printVersionDiff(diff, env.Stdout)
+				return nil
+			}
+
+			if output == outputJSON {
+				return printJSON(res, env.Stdout)
+			}
+			printVersions(res, env.Stdout, long)
+			return nil
+		},
+		ValidArgsFunction: completePath(env),
+	}
+	versionsCommand.Flags().BoolVarP(&long, "long", "l", false, "list the files of each version")
+	versionsCommand.Flags().StringSliceVar(&diffSelectors, "diff", []string{}, "compare the files of two versions (OLD,NEW)")
+	addOutputFlag(versionsCommand, &output)
+	return versionsCommand
+}
+
+// resolveDatasetVersion resolves a version selector against the versions of the dataset at path
+func resolveDatasetVersion(ctx context.Context, client maintenance.API, path string, version string) (time.Time, error) {
+	selector, err := maintenance.ParseVersionSelector(version)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	res, err := client.ListVersions(ctx, path)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return selector.Resolve(res.Timestamps())
+}
+
+func diffVersions(versions *maintenance.ListVersionsResponse, selectors []string) (maintenance.FileDiff, error) {
+	if len(selectors) == 1 {
+		selectors = append(selectors, "latest")
+	}
+	if len(selectors) != 2 {
+		return maintenance.FileDiff{}, fmt.Errorf("expected two versions to compare, but got %d", len(selectors))
+	}
+
+	var resolved []maintenance.Version
+	for _, s := range selectors {
+		selector, err := maintenance.ParseVersionSelector(s)
+		if err != nil {
+			return maintenance.FileDiff{}, err
+		}
+		timestamp, err := selector.Resolve(versions.Timestamps())
+		if err != nil {
+			return maintenance.FileDiff{}, err
+		}
+		resolved = append(resolved, *versions.Version(timestamp))
+	}
+
+	return maintenance.DiffVersions(resolved[0], resolved[1]), nil
+}
+
+// printVersions prints the versions of a dataset in tabular format, latest version last
+func printVersions(versions *maintenance.ListVersionsResponse, output io.Writer, long bool) {
+	colorOutput := colorWriter{out: output}
+	writer := tabwriter.NewWriter(colorOutput, 15, 0, 2, ' ', tabwriter.FilterHTML)
+	defer writer.Flush()
+
+	fmt.Fprintln(writer,
+		"<bold>Version</>\t"+
+			"<bold>Timestamp</>\t"+
+			"<bold>Files</>\t"+
+			"<bold>Size</>\t")
+	sorted := append([]maintenance.Version{}, versions.Versions...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+	for _, version := range sorted {
+		fmt.Fprintf(writer, "%d\t%s\t%d\t%s\t\n",
+			maintenance.EpochMillis(version.Timestamp),
+			version.Timestamp.Format(time.RFC3339Nano),
+			len(version.Files),
+			formatSize(version.Size(), true))
+		if long {
+			for _, file := range version.Files {
+				fmt.Fprintf(writer, "  %s\t\t\t%s\t\n", file.URI, formatSize(file.Size, true))
+			}
+		}
+	}
+}
+
+// printVersionDiff prints the files that were added (+), removed (-) and changed (~) between two versions
+func printVersionDiff(diff maintenance.FileDiff, output io.Writer) {
+	colorOutput := colorWriter{out: output}
+	writer := tabwriter.NewWriter(colorOutput, 15, 0, 2, ' ', tabwriter.FilterHTML)
+	defer writer.Flush()
+
+	fmt.Fprintf(writer, "--- %s\n", diff.From.Format(time.RFC3339Nano))
+	fmt.Fprintf(writer, "+++ %s\n", diff.To.Format(time.RFC3339Nano))
+	for _, file := range diff.Added {
+		fmt.Fprintf(writer, "<fg=green>+ %s</>\t%d\t\n", file.Name(), file.Size)
+	}
+	for _, file := range diff.Removed {
+		fmt.Fprintf(writer, "<fg=red>- %s</>\t%d\t\n", file.Name(), file.Size)
+	}
+	for _, change := range diff.Changed {
+		fmt.Fprintf(writer, "<fg=yellow>~ %s</>\t%d -> %d\t\n", change.Name, change.FromSize, change.ToSize)
+	}
+	fmt.Fprintf(writer, "%d added, %d removed, %d changed, %d unchanged\n",
+		len(diff.Added), len(diff.Removed), len(diff.Changed), diff.Unchanged)
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/acarl005/stripansi"
+	"github.com/andreyvit/diff"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+)
+
+var testVersions = maintenance.ListVersionsResponse{
+	DatasetPath: "/foo/bar",
+	Versions: []maintenance.Version{
+		{
+			Timestamp: time.Date(2000, 1, 1, 0, 0, 0, 123000000, time.UTC),
+			Files: []maintenance.DatasetFile{
+				{URI: "gs://bucket/prefix/foo/bar/v1/file1", Size: 1},
+				{URI: "gs://bucket/prefix/foo/bar/v1/file2", Size: 2},
+			},
+		},
+		{
+			Timestamp: time.Date(3000, 1, 1, 0, 0, 0, 123000000, time.UTC),
+			Files: []maintenance.DatasetFile{
+				{URI: "gs://bucket/prefix/foo/bar/v2/file1", Size: 4},
+				{URI: "gs://bucket/prefix/foo/bar/v2/file3", Size: 8},
+			},
+		},
+	},
+}
+
+func TestPrintVersions(t *testing.T) {
+	// The server may return the versions in any order, e.g. latest first
+	unsorted := maintenance.ListVersionsResponse{
+		DatasetPath: testVersions.DatasetPath,
+		Versions:    []maintenance.Version{testVersions.Versions[1], testVersions.Versions[0]},
+	}
+	unsorted.Versions[1].Files = append(unsorted.Versions[1].Files, maintenance.DatasetFile{
+		URI: "gs://bucket/prefix/foo/bar/v1/file3", Size: 2048,
+	})
+
+	var output bytes.Buffer
+	printVersions(&unsorted, &output, false)
+
+	expected := `
+Version         Timestamp                 Files          Size
+946684800123    2000-01-01T00:00:00.123Z  3              2.0K
+32503680000123  3000-01-01T00:00:00.123Z  2              12B
+`
+	if actual, expected := diff.TrimLinesInString(stripansi.Strip(output.String())),
+		diff.TrimLinesInString(expected); actual != expected {
+		t.Errorf("Result not as expected:\n%v", diff.LineDiff(expected, actual))
+	}
+}
+
+func TestPrintVersionDiff(t *testing.T) {
+	versionDiff, err := diffVersions(&testVersions, []string{"latest~1"})
+	if err != nil {
+		t.Fatalf("Got error %v", err)
+	}
+
+	var output bytes.Buffer
+	printVersionDiff(versionDiff, &output)
+
+	expected := `
+--- 2000-01-01T00:00:00.123Z
++++ 3000-01-01T00:00:00.123Z
++ file3        8
+- file2        2
+~ file1        1 -> 4
+1 added, 1 removed, 1 changed, 0 unchanged
+`
+	if actual, expected := diff.TrimLinesInString(stripansi.Strip(output.String())),
+		diff.TrimLinesInString(expected); actual != expected {
+		t.Errorf("Result not as expected:\n%v", diff.LineDiff(expected, actual))
+	}
+}
@@ -0,0 +1,137 @@
+// Package devserver implements in-memory stand-ins for the data-maintenance service and the export endpoint of the
+// dapla-pseudo-service. The datasets are seeded from a YAML catalog, so that the CLI can be run and tested without
+// access to the real services. It is used by the dev serve command.
+package devserver
+
+import (
+	_ "embed" // for the seed catalog
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/statisticsnorway/dapla-cli/glob"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"gopkg.in/yaml.v2"
+)
+
+// BucketURI is the prefix of the URIs of the dataset files served by the fake data-maintenance service
+const BucketURI = "gs://dapla-dev-data"
+
+//go:embed seed.yml
+var seedCatalog []byte
+
+// Catalog holds the datasets and protected paths that the fake services start out with.
+//
+// Example:
+//
+//	protected:
+//	  - /produkt/**
+//	datasets:
+//	  - path: /skatt/person/inntekt
+//	    createdBy: ola.nordmann@ssb.no
+//	    createdDate: 2021-01-04T08:00:00Z
+//	    type: BOUNDED
+//	    valuation: SENSITIVE
+//	    state: INPUT
+//	    schema:
+//	      - path: person/fnr
+//	        type: string
+//	    versions:
+//	      - timestamp: 2021-01-04T08:00:00Z
+//	        files:
+//	          - name: part-00000.parquet
+//	            size: 1024
+type Catalog struct {
+	Protected []string  `yaml:"protected"`
+	Datasets  []Dataset `yaml:"datasets"`
+}
+
+// Dataset is a dataset in a Catalog
+type Dataset struct {
+	Path        string                   `yaml:"path"`
+	CreatedBy   string                   `yaml:"createdBy"`
+	CreatedAt   time.Time                `yaml:"createdDate"`
+	Type        string                   `yaml:"type"`
+	Valuation   string                   `yaml:"valuation"`
+	State       string                   `yaml:"state"`
+	Schema      []Field                  `yaml:"schema"`
+	PseudoRules []maintenance.PseudoRule `yaml:"pseudoRules"`
+	Versions    []Version                `yaml:"versions"`
+}
+
+// Field is a (possibly nested) field of a dataset, see maintenance.SchemaField
+type Field struct {
+	Path string `yaml:"path"`
+	Type string `yaml:"type"`
+}
+
+// Version is a version of a dataset in a Catalog
+type Version struct {
+	Timestamp time.Time `yaml:"timestamp"`
+	Files     []File    `yaml:"files"`
+}
+
+// File is a file of a dataset version. Its URI is made from the BucketURI, the dataset path, the version and the name.
+type File struct {
+	Name string `yaml:"name"`
+	Size uint64 `yaml:"size"`
+}
+
+// SeedCatalog returns the catalog that the dev serve command uses by default
+func SeedCatalog() *Catalog {
+	catalog, err := ParseCatalog(seedCatalog)
+	if err != nil {
+		panic(err)
+	}
+	return catalog
+}
+
+// LoadCatalog reads and validates a catalog from a YAML file
+func LoadCatalog(filename string) (*Catalog, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCatalog(data)
+}
+
+// ParseCatalog parses and validates a catalog
+func ParseCatalog(data []byte) (*Catalog, error) {
+	catalog := Catalog{}
+	if err := yaml.UnmarshalStrict(data, &catalog); err != nil {
+		return nil, fmt.Errorf("invalid catalog: %v", err)
+	}
+
+	for _, p := range catalog.Protected {
+		if err := glob.Validate(p); err != nil {
+			return nil, fmt.Errorf("invalid catalog: protected path %s: %v", p, err)
+		}
+	}
+
+	paths := map[string]bool{}
+	for _, d := range catalog.Datasets {
+		if !strings.HasPrefix(d.Path, "/") || strings.HasSuffix(d.Path, "/") || strings.Contains(d.Path, "//") {
+			return nil, fmt.Errorf("invalid catalog: dataset path %q must be absolute, without a trailing slash", d.Path)
+		}
+		if paths[d.Path] {
+			return nil, fmt.Errorf("invalid catalog: duplicate dataset %s", d.Path)
+		}
+		paths[d.Path] = true
+	}
+	for _, d := range catalog.Datasets {
+		for folder := path.Dir(d.Path); folder != "/"; folder = path.Dir(folder) {
+			if paths[folder] {
+				return nil, fmt.Errorf("invalid catalog: dataset %s is nested within dataset %s", d.Path, folder)
+			}
+		}
+	}
+
+	return &catalog, nil
+}
+
+// fileURI returns the URI of a file of a dataset version
+func fileURI(datasetPath string, timestamp time.Time, name string) string {
+	return fmt.Sprintf("%s%s/%d/%s", BucketURI, datasetPath, maintenance.EpochMillis(timestamp), name)
+}
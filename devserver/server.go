@@ -0,0 +1,580 @@
+package devserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/statisticsnorway/dapla-cli/export"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+)
+
+// ExportBucketURI is the prefix of the target URIs returned by the fake export endpoint
+const ExportBucketURI = "gs://dapla-dev-export"
+
+// Server holds the state of the fake services. The datasets of the catalog are only changed in memory, so every
+// server starts out with the datasets of its catalog. Auth tokens are accepted without being checked.
+type Server struct {
+	// Now returns the current time, which is used as the time datasets are moved to the trash
+	Now func() time.Time
+
+	mu        sync.Mutex
+	datasets  map[string]*dataset
+	trash     map[string]*trashedDataset
+	protected []string
+	exports   []export.Request
+}
+
+// dataset is a dataset served by the fake data-maintenance service, with its versions sorted by timestamp
+type dataset struct {
+	info     maintenance.DatasetInfo
+	schema   []maintenance.SchemaField
+	versions []maintenance.Version
+}
+
+type trashedDataset struct {
+	dataset   *dataset
+	trashedAt time.Time
+	expiresAt time.Time
+}
+
+// statusError is an error that is returned to the client with an HTTP status code
+type statusError struct {
+	code    int
+	message string
+}
+
+func (e *statusError) Error() string {
+	return e.message
+}
+
+func errorf(code int, format string, args ...interface{}) error {
+	return &statusError{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// New creates a server holding the datasets of catalog
+func New(catalog *Catalog) *Server {
+	s := &Server{
+		Now:       time.Now,
+		datasets:  map[string]*dataset{},
+		trash:     map[string]*trashedDataset{},
+		protected: append([]string{}, catalog.Protected...),
+	}
+	for _, d := range catalog.Datasets {
+		ds := &dataset{
+			info: maintenance.DatasetInfo{
+				Path:        d.Path,
+				CreatedBy:   d.CreatedBy,
+				CreatedAt:   d.CreatedAt,
+				Type:        d.Type,
+				Valuation:   d.Valuation,
+				State:       d.State,
+				PseudoRules: d.PseudoRules,
+			},
+			schema:   []maintenance.SchemaField{},
+			versions: []maintenance.Version{},
+		}
+		for _, f := range d.Schema {
+			ds.schema = append(ds.schema, maintenance.SchemaField{Path: f.Path, Type: f.Type})
+		}
+		for _, v := range d.Versions {
+			version := maintenance.Version{Timestamp: v.Timestamp, Files: []maintenance.DatasetFile{}}
+			for _, f := range v.Files {
+				version.Files = append(version.Files, maintenance.DatasetFile{URI: fileURI(d.Path, v.Timestamp, f.Name), Size: f.Size})
+			}
+			ds.versions = append(ds.versions, version)
+		}
+		sort.Slice(ds.versions, func(i, j int) bool {
+			return ds.versions[i].Timestamp.Before(ds.versions[j].Timestamp)
+		})
+		s.datasets[d.Path] = ds
+	}
+	return s
+}
+
+// Exports returns the requests received by the fake export endpoint, oldest first
+func (s *Server) Exports() []export.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]export.Request{}, s.exports...)
+}
+
+// MaintenanceHandler returns the handler of the fake data-maintenance service. It serves the endpoints used to list,
+// inspect, delete, move and trash datasets under maintenance.DefaultBasePath. The other endpoints, such as search
+// and copy, respond with 501 Not Implemented.
+func (s *Server) MaintenanceHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The dataset path is part of the URL path, so the URL path can not be routed by http.ServeMux, which
+		// redirects paths holding double slashes
+		rest := strings.TrimPrefix(r.URL.Path, maintenance.DefaultBasePath+"/")
+		if rest == r.URL.Path {
+			writeResponse(w, nil, errorf(http.StatusNotFound, "no such endpoint %s", r.URL.Path))
+			return
+		}
+		endpoint := strings.SplitN(rest, "/", 2)
+		datasetPath := "/"
+		if len(endpoint) > 1 {
+			datasetPath = path.Clean("/" + endpoint[1])
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		var res interface{}
+		var err error
+		switch r.Method + " " + endpoint[0] {
+		case "GET list":
+			res, err = s.list(r, datasetPath)
+		case "GET versions":
+			res, err = s.listVersions(datasetPath)
+		case "GET schema":
+			res, err = s.schema(datasetPath)
+		case "GET info":
+			res, err = s.info(datasetPath)
+		case "DELETE delete":
+			res, err = s.delete(r, datasetPath)
+		case "DELETE versions":
+			res, err = s.deleteVersions(r, datasetPath)
+		case "POST move":
+			res, err = s.move(r, datasetPath)
+		case "POST trash":
+			res, err = s.moveToTrash(r, datasetPath)
+		case "GET trash":
+			res, err = s.listTrash()
+		case "POST restore":
+			res, err = s.restore(r, datasetPath)
+		case "DELETE trash":
+			res, err = s.emptyTrash(r)
+		case "GET protected":
+			res = s.protected
+		default:
+			err = errorf(http.StatusNotImplemented, "%s %s is not implemented by the dev server", r.Method, r.URL.Path)
+		}
+		writeResponse(w, res, err)
+	})
+}
+
+// PseudoHandler returns the handler of the fake export endpoint of the dapla-pseudo-service. Export requests are
+// validated against the datasets of the fake data-maintenance service and recorded (see Exports), but nothing is
+// exported.
+func (s *Server) PseudoHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/export" || r.Method != http.MethodPost {
+			writeResponse(w, nil, errorf(http.StatusNotImplemented, "%s %s is not implemented by the dev server", r.Method, r.URL.Path))
+			return
+		}
+
+		req := export.Request{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeResponse(w, nil, errorf(http.StatusBadRequest, "invalid export request: %v", err))
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		res, err := s.export(req)
+		writeResponse(w, res, err)
+	})
+}
+
+func writeResponse(w http.ResponseWriter, res interface{}, err error) {
+	if err != nil {
+		code := http.StatusInternalServerError
+		if statusErr, ok := err.(*statusError); ok {
+			code = statusErr.code
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(code)
+		io.WriteString(w, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(res)
+}
+
+func (s *Server) dataset(datasetPath string) (*dataset, error) {
+	d, ok := s.datasets[datasetPath]
+	if !ok {
+		return nil, errorf(http.StatusNotFound, "dataset %s not found", datasetPath)
+	}
+	return d, nil
+}
+
+// isFolder returns true if there are datasets under folder
+func (s *Server) isFolder(folder string) bool {
+	for p := range s.datasets {
+		if maintenance.IsWithin(p, folder) && p != folder {
+			return true
+		}
+	}
+	return false
+}
+
+// list lists the datasets and folders directly under folder. The response is paginated if the pageSize query
+// parameter is given, and the page token is the offset of the page.
+func (s *Server) list(r *http.Request, folder string) (interface{}, error) {
+	folders := map[string]*maintenance.ListDatasetElement{}
+	elements := maintenance.ListDatasetResponse{}
+	for p, d := range s.datasets {
+		if !maintenance.IsWithin(p, folder) || p == folder {
+			continue
+		}
+		name := strings.SplitN(strings.TrimPrefix(p, strings.TrimSuffix(folder, "/")+"/"), "/", 2)
+		if len(name) == 1 {
+			elements = append(elements, maintenance.ListDatasetElement{
+				Path:      p,
+				CreatedBy: d.info.CreatedBy,
+				CreatedAt: d.info.CreatedAt,
+				Type:      d.info.Type,
+				Valuation: d.info.Valuation,
+				State:     d.info.State,
+			})
+			continue
+		}
+		// Folders are implied by the dataset paths, and are as old as their oldest dataset
+		element, ok := folders[name[0]]
+		if !ok {
+			element = &maintenance.ListDatasetElement{Path: path.Join(folder, name[0]), Depth: 1}
+			folders[name[0]] = element
+		}
+		if element.CreatedAt.IsZero() || d.info.CreatedAt.Before(element.CreatedAt) {
+			element.CreatedAt = d.info.CreatedAt
+			element.CreatedBy = d.info.CreatedBy
+		}
+	}
+	for _, element := range folders {
+		elements = append(elements, *element)
+	}
+	if len(elements) == 0 && folder != "/" {
+		return nil, errorf(http.StatusNotFound, "folder %s not found", folder)
+	}
+	sort.Slice(elements, func(i, j int) bool { return elements[i].Path < elements[j].Path })
+
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("pageSize"))
+	if pageSize <= 0 {
+		return elements, nil
+	}
+	offset := 0
+	if token := r.URL.Query().Get("pageToken"); token != "" {
+		var err error
+		if offset, err = strconv.Atoi(token); err != nil || offset < 0 || offset > len(elements) {
+			return nil, errorf(http.StatusBadRequest, "invalid page token %q", token)
+		}
+	}
+	page := struct {
+		Datasets      maintenance.ListDatasetResponse `json:"datasets"`
+		NextPageToken string                          `json:"nextPageToken,omitempty"`
+	}{Datasets: elements[offset:]}
+	if len(page.Datasets) > pageSize {
+		page.Datasets = page.Datasets[:pageSize]
+		page.NextPageToken = strconv.Itoa(offset + pageSize)
+	}
+	return page, nil
+}
+
+func (s *Server) listVersions(datasetPath string) (interface{}, error) {
+	d, err := s.dataset(datasetPath)
+	if err != nil {
+		return nil, err
+	}
+	return maintenance.ListVersionsResponse{DatasetPath: datasetPath, Versions: d.versions}, nil
+}
+
+func (s *Server) schema(datasetPath string) (interface{}, error) {
+	d, err := s.dataset(datasetPath)
+	if err != nil {
+		return nil, err
+	}
+	return maintenance.DatasetSchema{DatasetPath: datasetPath, Fields: d.schema}, nil
+}
+
+func (s *Server) info(datasetPath string) (interface{}, error) {
+	d, err := s.dataset(datasetPath)
+	if err != nil {
+		return nil, err
+	}
+	return d.info, nil
+}
+
+// delete deletes a dataset with all its versions. Unknown query parameters are ignored, like the real service does.
+func (s *Server) delete(r *http.Request, datasetPath string) (interface{}, error) {
+	d, err := s.dataset(datasetPath)
+	if err != nil {
+		return nil, err
+	}
+	if r.URL.Query().Get("dry-run") != "true" {
+		delete(s.datasets, datasetPath)
+	}
+	return deleteResponse(datasetPath, d.versions), nil
+}
+
+// deleteVersions deletes the versions of a dataset given by the versions query parameter (epoch millis)
+func (s *Server) deleteVersions(r *http.Request, datasetPath string) (interface{}, error) {
+	d, err := s.dataset(datasetPath)
+	if err != nil {
+		return nil, err
+	}
+	dryRun := r.URL.Query().Get("dry-run") == "true"
+
+	versions := r.URL.Query().Get("versions")
+	if versions == "" {
+		return nil, errorf(http.StatusBadRequest, "versions is required")
+	}
+
+	existing := map[int64]bool{}
+	for _, version := range d.versions {
+		existing[maintenance.EpochMillis(version.Timestamp)] = true
+	}
+	selected := map[int64]bool{}
+	for _, v := range strings.Split(versions, ",") {
+		millis, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, errorf(http.StatusBadRequest, "invalid version %q", v)
+		}
+		if !existing[millis] {
+			return nil, errorf(http.StatusNotFound, "dataset %s has no version %d", datasetPath, millis)
+		}
+		selected[millis] = true
+	}
+	var deleted, kept []maintenance.Version
+	for _, version := range d.versions {
+		if selected[maintenance.EpochMillis(version.Timestamp)] {
+			deleted = append(deleted, version)
+		} else {
+			kept = append(kept, version)
+		}
+	}
+	if !dryRun {
+		d.versions = append([]maintenance.Version{}, kept...)
+	}
+	return deleteResponse(datasetPath, deleted), nil
+}
+
+func deleteResponse(datasetPath string, versions []maintenance.Version) maintenance.DeleteDatasetResponse {
+	res := maintenance.DeleteDatasetResponse{DatasetPath: datasetPath, DatasetVersion: []maintenance.DatasetVersion{}}
+	for _, version := range versions {
+		res.DatasetVersion = append(res.DatasetVersion, maintenance.DatasetVersion{Timestamp: version.Timestamp, DeletedFiles: version.Files})
+		res.TotalSize += version.Size()
+	}
+	return res
+}
+
+func (s *Server) move(r *http.Request, src string) (interface{}, error) {
+	d, err := s.dataset(src)
+	if err != nil {
+		return nil, err
+	}
+	dst := r.URL.Query().Get("destination")
+	if dst == "" {
+		return nil, errorf(http.StatusBadRequest, "destination is required")
+	}
+	dst = path.Clean("/" + dst)
+	if dst == src {
+		return nil, errorf(http.StatusBadRequest, "cannot move %s to itself", src)
+	}
+
+	res, err := s.checkDestination(src, dst, maintenance.ConflictPolicy(r.URL.Query().Get("conflict")))
+	if err != nil || res.Status == maintenance.MoveStatusSkipped || r.URL.Query().Get("dry-run") == "true" {
+		return res, err
+	}
+	delete(s.datasets, src)
+	s.place(d, dst)
+	return res, nil
+}
+
+// checkDestination checks that a dataset can be moved from src to dst, given the conflict policy (fail by default)
+func (s *Server) checkDestination(src, dst string, conflict maintenance.ConflictPolicy) (*maintenance.MoveDatasetResponse, error) {
+	res := &maintenance.MoveDatasetResponse{Source: src, Destination: dst, Status: maintenance.MoveStatusMoved}
+	if s.isFolder(dst) {
+		return nil, errorf(http.StatusConflict, "destination %s is a folder", dst)
+	}
+	for folder := path.Dir(dst); folder != "/"; folder = path.Dir(folder) {
+		if _, ok := s.datasets[folder]; ok {
+			return nil, errorf(http.StatusBadRequest, "destination %s is within dataset %s", dst, folder)
+		}
+	}
+	if _, ok := s.datasets[dst]; ok {
+		switch conflict {
+		case maintenance.ConflictSkip:
+			res.Status = maintenance.MoveStatusSkipped
+		case maintenance.ConflictOverwrite:
+		case maintenance.ConflictFail, "":
+			return nil, errorf(http.StatusConflict, "destination %s already exists", dst)
+		default:
+			return nil, errorf(http.StatusBadRequest, "invalid conflict policy %q", conflict)
+		}
+	}
+	return res, nil
+}
+
+// place puts d at dst, replacing any dataset there, and moves its files along with it
+func (s *Server) place(d *dataset, dst string) {
+	moved := &dataset{info: d.info, schema: d.schema, versions: []maintenance.Version{}}
+	moved.info.Path = dst
+	for _, version := range d.versions {
+		files := []maintenance.DatasetFile{}
+		for _, file := range version.Files {
+			files = append(files, maintenance.DatasetFile{URI: fileURI(dst, version.Timestamp, file.Name()), Size: file.Size})
+		}
+		moved.versions = append(moved.versions, maintenance.Version{Timestamp: version.Timestamp, Files: files})
+	}
+	s.datasets[dst] = moved
+}
+
+// expireTrash deletes the trashed datasets that have expired
+func (s *Server) expireTrash() {
+	now := s.Now()
+	for p, t := range s.trash {
+		if !now.Before(t.expiresAt) {
+			delete(s.trash, p)
+		}
+	}
+}
+
+func trashedResponse(datasetPath string, t *trashedDataset) maintenance.TrashedDataset {
+	res := maintenance.TrashedDataset{
+		DatasetPath: datasetPath,
+		TrashedAt:   t.trashedAt,
+		ExpiresAt:   t.expiresAt,
+		Versions:    len(t.dataset.versions),
+	}
+	for _, version := range t.dataset.versions {
+		res.TotalSize += version.Size()
+	}
+	return res
+}
+
+// moveToTrash moves a dataset to the trash, where it is kept for the number of seconds given by the expiry query
+// parameter
+func (s *Server) moveToTrash(r *http.Request, datasetPath string) (interface{}, error) {
+	s.expireTrash()
+	d, err := s.dataset(datasetPath)
+	if err != nil {
+		return nil, err
+	}
+	expiry, err := strconv.ParseInt(r.URL.Query().Get("expiry"), 10, 64)
+	if err != nil || expiry <= 0 {
+		return nil, errorf(http.StatusBadRequest, "invalid expiry %q", r.URL.Query().Get("expiry"))
+	}
+
+	now := s.Now()
+	t := &trashedDataset{dataset: d, trashedAt: now, expiresAt: now.Add(time.Duration(expiry) * time.Second)}
+	if r.URL.Query().Get("dry-run") != "true" {
+		delete(s.datasets, datasetPath)
+		s.trash[datasetPath] = t
+	}
+	return trashedResponse(datasetPath, t), nil
+}
+
+// trashedPaths returns the paths of the trashed datasets at or under folder, sorted
+func (s *Server) trashedPaths(folder string) []string {
+	paths := []string{}
+	for p := range s.trash {
+		if maintenance.IsWithin(p, folder) {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (s *Server) listTrash() (interface{}, error) {
+	s.expireTrash()
+	res := []maintenance.TrashedDataset{}
+	for _, p := range s.trashedPaths("/") {
+		res = append(res, trashedResponse(p, s.trash[p]))
+	}
+	return res, nil
+}
+
+func (s *Server) restore(r *http.Request, datasetPath string) (interface{}, error) {
+	s.expireTrash()
+	t, ok := s.trash[datasetPath]
+	if !ok {
+		return nil, errorf(http.StatusNotFound, "dataset %s is not in the trash", datasetPath)
+	}
+	dst := datasetPath
+	if destination := r.URL.Query().Get("destination"); destination != "" {
+		dst = path.Clean("/" + destination)
+	}
+
+	res, err := s.checkDestination(datasetPath, dst, maintenance.ConflictPolicy(r.URL.Query().Get("conflict")))
+	if err != nil || res.Status == maintenance.MoveStatusSkipped {
+		return res, err
+	}
+	delete(s.trash, datasetPath)
+	s.place(t.dataset, dst)
+	return res, nil
+}
+
+// emptyTrash permanently deletes the trashed datasets at or under the folder given by the path query parameter, or
+// all trashed datasets if it is not given
+func (s *Server) emptyTrash(r *http.Request) (interface{}, error) {
+	s.expireTrash()
+	folder := "/"
+	if p := r.URL.Query().Get("path"); p != "" {
+		folder = path.Clean("/" + p)
+	}
+
+	res := []maintenance.DeleteDatasetResponse{}
+	for _, p := range s.trashedPaths(folder) {
+		res = append(res, deleteResponse(p, s.trash[p].dataset.versions))
+		if r.URL.Query().Get("dry-run") != "true" {
+			delete(s.trash, p)
+		}
+	}
+	return res, nil
+}
+
+// export validates an export request, records it, and returns the URI the export would have been written to
+func (s *Server) export(req export.Request) (interface{}, error) {
+	datasetPath := path.Clean("/" + req.DatasetPath)
+	d, err := s.dataset(datasetPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(d.versions) == 0 {
+		return nil, errorf(http.StatusBadRequest, "dataset %s has no versions", datasetPath)
+	}
+
+	version := d.versions[len(d.versions)-1].Timestamp
+	if req.DatasetTimestamp != nil {
+		found := false
+		for _, v := range d.versions {
+			found = found || v.Timestamp.Equal(*req.DatasetTimestamp)
+		}
+		if !found {
+			return nil, errorf(http.StatusNotFound, "dataset %s has no version %s", datasetPath,
+				req.DatasetTimestamp.Format(time.RFC3339Nano))
+		}
+		version = *req.DatasetTimestamp
+	}
+
+	if req.TargetPassword == "" {
+		return nil, errorf(http.StatusBadRequest, "targetPassword is required")
+	}
+	if len(req.PseudoRules) > 0 && req.PseudoRulesDatasetPath != "" {
+		return nil, errorf(http.StatusBadRequest, "cannot use both pseudoRules and pseudoRulesDatasetPath")
+	}
+	if req.PseudoRulesDatasetPath != "" {
+		if _, err := s.dataset(path.Clean("/" + req.PseudoRulesDatasetPath)); err != nil {
+			return nil, err
+		}
+	}
+
+	name := req.TargetContentName
+	if name == "" {
+		name = path.Base(datasetPath)
+	}
+	s.exports = append(s.exports, req)
+	return export.Response{
+		TargetURI: fmt.Sprintf("%s/export%s/%s-%s.zip", ExportBucketURI, datasetPath, version.UTC().Format("20060102"), name),
+	}, nil
+}
@@ -0,0 +1,240 @@
+package devserver
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/statisticsnorway/dapla-cli/export"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"github.com/stretchr/testify/assert"
+)
+
+var testCatalog = []byte(`
+protected:
+  - /produkt/**
+datasets:
+  - path: /skatt/inntekt
+    createdBy: ola
+    createdDate: 2021-01-01T00:00:00Z
+    schema:
+      - path: person/fnr
+        type: string
+    versions:
+      - timestamp: 2021-02-01T00:00:00Z
+        files:
+          - name: part-1.parquet
+            size: 2
+      - timestamp: 2021-01-01T00:00:00Z
+        files:
+          - name: part-0.parquet
+            size: 1
+  - path: /skatt/2020/inntekt
+    createdBy: kari
+    createdDate: 2020-01-01T00:00:00Z
+  - path: /skatt/formue
+    createdDate: 2021-03-01T00:00:00Z
+    versions:
+      - timestamp: 2021-03-01T00:00:00Z
+        files:
+          - name: part-0.parquet
+            size: 4
+`)
+
+var testNow = time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestServer(t *testing.T) (*Server, *maintenance.Client, func()) {
+	catalog, err := ParseCatalog(testCatalog)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	server := New(catalog)
+	server.Now = func() time.Time {
+		return testNow
+	}
+	httpServer := httptest.NewServer(server.MaintenanceHandler())
+	return server, maintenance.New(httpServer.URL, maintenance.WithPageSize(2)), httpServer.Close
+}
+
+func TestParseCatalog_Invalid(t *testing.T) {
+	for catalog, expected := range map[string]string{
+		"datasets: [{path: skatt}]":                          `invalid catalog: dataset path "skatt" must be absolute, without a trailing slash`,
+		"datasets: [{path: /skatt}, {path: /skatt}]":         "invalid catalog: duplicate dataset /skatt",
+		"datasets: [{path: /skatt}, {path: /skatt/inntekt}]": "invalid catalog: dataset /skatt/inntekt is nested within dataset /skatt",
+		"datasets: [{path: /skatt, owner: ola}]":             "invalid catalog: yaml: unmarshal errors:\n  line 1: field owner not found in type devserver.Dataset",
+		"protected: ['/skatt/[']":                            "invalid catalog: protected path /skatt/[: ",
+	} {
+		_, err := ParseCatalog([]byte(catalog))
+		if assert.Error(t, err, catalog) {
+			assert.Contains(t, err.Error(), expected, catalog)
+		}
+	}
+}
+
+func TestSeedCatalog(t *testing.T) {
+	assert.NotEmpty(t, SeedCatalog().Datasets)
+}
+
+func TestServer_List(t *testing.T) {
+	_, client, stop := newTestServer(t)
+	defer stop()
+
+	res, err := client.ListDatasets(context.Background(), "/skatt")
+	assert.NoError(t, err)
+	assert.Equal(t, maintenance.ListDatasetResponse{
+		{Path: "/skatt/2020", CreatedBy: "kari", CreatedAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Depth: 1},
+		{Path: "/skatt/formue", CreatedAt: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{Path: "/skatt/inntekt", CreatedBy: "ola", CreatedAt: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}, *res)
+
+	res, err = client.ListDatasets(context.Background(), "/")
+	assert.NoError(t, err)
+	assert.Equal(t, maintenance.ListDatasetResponse{
+		{Path: "/skatt", CreatedBy: "kari", CreatedAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Depth: 1},
+	}, *res)
+
+	_, err = client.ListDatasets(context.Background(), "/kilde")
+	assert.EqualError(t, err, "folder /kilde not found (404)")
+}
+
+func TestServer_Versions(t *testing.T) {
+	_, client, stop := newTestServer(t)
+	defer stop()
+
+	res, err := client.ListVersions(context.Background(), "/skatt/inntekt")
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{
+		time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC),
+	}, res.Timestamps())
+	assert.Equal(t, "gs://dapla-dev-data/skatt/inntekt/1609459200000/part-0.parquet", res.Versions[0].Files[0].URI)
+
+	schema, err := client.GetDatasetSchema(context.Background(), "/skatt/inntekt")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"person/fnr"}, schema.FieldPaths())
+
+	_, err = client.ListVersions(context.Background(), "/skatt/nope")
+	assert.EqualError(t, err, "dataset /skatt/nope not found (404)")
+}
+
+func TestServer_Delete(t *testing.T) {
+	_, client, stop := newTestServer(t)
+	defer stop()
+	ctx := context.Background()
+
+	res, err := client.DeleteDatasetVersions(ctx, "/skatt/inntekt", []time.Time{time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), res.TotalSize)
+	versions, _ := client.ListVersions(ctx, "/skatt/inntekt")
+	assert.Len(t, versions.Versions, 1)
+
+	_, err = client.DeleteDatasetVersions(ctx, "/skatt/inntekt", []time.Time{time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}, false)
+	assert.EqualError(t, err, "dataset /skatt/inntekt has no version 1609459200000 (404)")
+
+	res, err = client.DeleteDatasets(ctx, "/skatt/formue", true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res.GetNumberOfFiles())
+	_, err = client.ListVersions(ctx, "/skatt/formue")
+	assert.NoError(t, err, "dry-run should not delete the dataset")
+
+	_, err = client.DeleteDatasets(ctx, "/skatt/formue", false)
+	assert.NoError(t, err)
+	_, err = client.ListVersions(ctx, "/skatt/formue")
+	assert.EqualError(t, err, "dataset /skatt/formue not found (404)")
+}
+
+func TestServer_Move(t *testing.T) {
+	_, client, stop := newTestServer(t)
+	defer stop()
+	ctx := context.Background()
+
+	_, err := client.MoveDataset(ctx, "/skatt/formue", "/skatt/inntekt", maintenance.ConflictFail, false)
+	assert.EqualError(t, err, "destination /skatt/inntekt already exists (409)")
+
+	res, err := client.MoveDataset(ctx, "/skatt/formue", "/skatt/inntekt", maintenance.ConflictSkip, false)
+	assert.NoError(t, err)
+	assert.Equal(t, maintenance.MoveStatusSkipped, res.Status)
+
+	res, err = client.MoveDataset(ctx, "/skatt/formue", "/skatt/2021/formue", maintenance.ConflictFail, false)
+	assert.NoError(t, err)
+	assert.Equal(t, maintenance.MoveStatusMoved, res.Status)
+	versions, err := client.ListVersions(ctx, "/skatt/2021/formue")
+	assert.NoError(t, err)
+	assert.Equal(t, "gs://dapla-dev-data/skatt/2021/formue/1614556800000/part-0.parquet", versions.Versions[0].Files[0].URI)
+}
+
+func TestServer_Trash(t *testing.T) {
+	_, client, stop := newTestServer(t)
+	defer stop()
+	ctx := context.Background()
+
+	trashed, err := client.TrashDataset(ctx, "/skatt/inntekt", 24*time.Hour, false)
+	assert.NoError(t, err)
+	assert.Equal(t, maintenance.TrashedDataset{
+		DatasetPath: "/skatt/inntekt",
+		TrashedAt:   testNow,
+		ExpiresAt:   testNow.Add(24 * time.Hour),
+		Versions:    2,
+		TotalSize:   3,
+	}, *trashed)
+	_, err = client.TrashDataset(ctx, "/skatt/formue", 24*time.Hour, false)
+	assert.NoError(t, err)
+
+	trash, err := client.ListTrash(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, trash, 2)
+
+	_, err = client.RestoreDataset(ctx, "/skatt/inntekt", "", maintenance.ConflictFail)
+	assert.NoError(t, err)
+	_, err = client.ListVersions(ctx, "/skatt/inntekt")
+	assert.NoError(t, err)
+
+	deleted, err := client.EmptyTrash(ctx, "/skatt", false)
+	assert.NoError(t, err)
+	if assert.Len(t, deleted, 1) {
+		assert.Equal(t, "/skatt/formue", deleted[0].DatasetPath)
+	}
+	trash, err = client.ListTrash(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, trash)
+}
+
+func TestServer_ProtectedAndUnsupported(t *testing.T) {
+	_, client, stop := newTestServer(t)
+	defer stop()
+	ctx := context.Background()
+
+	protected, err := client.ListProtectedPaths(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/produkt/**"}, protected)
+
+	_, err = client.SearchDatasets(ctx, maintenance.SearchQuery{Text: "inntekt"})
+	assert.True(t, maintenance.IsSearchUnavailable(err))
+}
+
+func TestServer_Export(t *testing.T) {
+	server, _, stop := newTestServer(t)
+	defer stop()
+	httpServer := httptest.NewServer(server.PseudoHandler())
+	defer httpServer.Close()
+	client := export.NewClient(httpServer.URL, "token")
+	ctx := context.Background()
+
+	res, err := client.Export(ctx, export.Request{DatasetPath: "/skatt/inntekt", TargetContentName: "test", TargetPassword: "secret"})
+	assert.NoError(t, err)
+	assert.Equal(t, "gs://dapla-dev-export/export/skatt/inntekt/20210201-test.zip", res.TargetURI)
+
+	timestamp := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	res, err = client.Export(ctx, export.Request{DatasetPath: "/skatt/inntekt", DatasetTimestamp: &timestamp, TargetPassword: "secret"})
+	assert.NoError(t, err)
+	assert.Equal(t, "gs://dapla-dev-export/export/skatt/inntekt/20210101-inntekt.zip", res.TargetURI)
+
+	_, err = client.Export(ctx, export.Request{DatasetPath: "/skatt/nope", TargetPassword: "secret"})
+	assert.EqualError(t, err, "dataset /skatt/nope not found (404)")
+	_, err = client.Export(ctx, export.Request{DatasetPath: "/skatt/inntekt"})
+	assert.EqualError(t, err, "targetPassword is required (400)")
+
+	assert.Len(t, server.Exports(), 2)
+	assert.Equal(t, "test", server.Exports()[0].TargetContentName)
+}
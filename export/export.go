@@ -1,22 +1,33 @@
 package export
 
 import (
-	"errors"
-	"fmt"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
 
-	"github.com/go-resty/resty/v2"
+	"github.com/statisticsnorway/dapla-cli/api"
 )
 
-// PseudoRule represents a single pseudonymization rule
-type PseudoRule struct {
-	Name    string `json:"name"`
-	Pattern string `json:"pattern"`
-	Func    string `json:"func"`
+// PseudoRule represents a single pseudonymization rule. It is the same type as the pseudo rules of datasets, so that
+// the rules of a dataset can be used in export requests.
+type PseudoRule = api.PseudoRule
+
+// PseudoFuncs describes the pseudo functions supported by the dapla-pseudo-service, by name. The functions take the
+// ID of the key to use, e.g. fpe-fnr(secret1).
+var PseudoFuncs = map[string]string{
+	"fpe-anychar": "format-preserving encryption of any characters",
+	"fpe-digits":  "format-preserving encryption of the digits, keeping other characters",
+	"fpe-fnr":     "format-preserving encryption of national identity numbers",
+	"tink-daead":  "deterministic authenticated encryption",
 }
 
 // Request holds parameters used to invoke the dapla-pseudo-service export endpoint
 type Request struct {
 	DatasetPath            string       `json:"datasetPath"`
+	DatasetTimestamp       *time.Time   `json:"datasetTimestamp,omitempty"`
 	ColumnSelectors        []string     `json:"columnSelectors"`
 	TargetContentName      string       `json:"targetContentName"`
 	TargetContentType      string       `json:"targetContentType"`
@@ -31,37 +42,106 @@ type Response struct {
 	TargetURI string `json:"targetUri"`
 }
 
-// Client is a facade against the dapla-pseudo-service API
+// Client is a facade against the dapla-pseudo-service API. Create clients with New.
 type Client struct {
-	baseURL    string
-	RestClient resty.Client
-	authToken  string
+	BaseURL     string
+	Client      *http.Client
+	tokenSource api.TokenSource
+}
+
+// Option configures a Client created by New
+type Option func(c *Client)
+
+// New creates a new client that talks with the dapla-pseudo-service API at baseURL. Without options the client uses
+// http.DefaultTransport, no timeout and no auth token, like maintenance.New.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		BaseURL: baseURL,
+		Client:  &http.Client{},
+		tokenSource: func(ctx context.Context) (string, error) {
+			return "", nil
+		},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// NewClient creates a new client that talks with the dapla-pseudo-service API, using a fixed auth token
+func NewClient(baseURL string, token string) *Client {
+	return New(baseURL, WithToken(token))
 }
 
-// NewClient creates a new client that talks with the dapla-pseudo-service API
-func NewClient(baseURL string, token string, debug bool) *Client {
-	return &Client{
-		RestClient: *resty.New().SetDebug(debug),
-		baseURL:    baseURL,
-		authToken:  token,
+// WithHTTPClient sets the HTTP client used to send requests, e.g. to share its transport with other clients. The
+// client is copied, so that WithTransport and WithTimeout do not change it.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		copied := *client
+		c.Client = &copied
 	}
 }
 
-// Export client method triggers dataset export in the dapla-pseudo-service
-func (c *Client) Export(req Request) (*Response, error) {
-	resp, err := c.RestClient.R().
-		SetBody(req).
-		SetAuthToken(c.authToken).
-		SetResult(&Response{}). // or SetResult(AuthSuccess{}).
-		Post(fmt.Sprintf("%s/export", c.baseURL))
+// WithTransport sets the transport used to send requests, e.g. to add logging or retries
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		c.Client.Transport = transport
+	}
+}
+
+// WithTimeout sets the time limit for each request. Exports may take a long time, so it should be generous.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.Client.Timeout = timeout
+	}
+}
+
+// WithToken sets a fixed auth token
+func WithToken(token string) Option {
+	return WithTokenSource(func(ctx context.Context) (string, error) {
+		return token, nil
+	})
+}
 
+// WithTokenSource sets a callback that returns the auth token, e.g. to fetch or refresh the token when needed
+func WithTokenSource(tokenSource api.TokenSource) Option {
+	return func(c *Client) {
+		c.tokenSource = tokenSource
+	}
+}
+
+// Export triggers the export of a dataset in the dapla-pseudo-service. An api.HTTPError is returned if the
+// service responds with an error status code.
+func (c *Client) Export(ctx context.Context, req Request) (*Response, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/export", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	token, err := c.tokenSource(ctx)
 	if err != nil {
 		return nil, err
 	}
+	httpReq.Header.Set("Authorization", "Bearer "+token)
+	httpReq.Header.Set("Accept", "application/json; charset=utf-8")
+	httpReq.Header.Set("Content-Type", "application/json; charset=utf-8")
 
-	if resp.StatusCode() >= 400 {
-		return nil, errors.New(resp.Status())
+	res, err := c.Client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusBadRequest {
+		message, _ := ioutil.ReadAll(res.Body)
+		return nil, api.NewHTTPError(res.StatusCode, string(message))
 	}
 
-	return resp.Result().(*Response), nil
+	var response Response
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	return &response, nil
 }
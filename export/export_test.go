@@ -1,9 +1,12 @@
 package export
 
 import (
+	"context"
 	"net/http"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"gopkg.in/h2non/gock.v1"
 )
 
@@ -21,8 +24,7 @@ func TestClient_Export(t *testing.T) {
 	gock.New("http://server.com").
 		Reply(http.StatusForbidden)
 
-	client := NewClient("http://server.com", "a secret secret", true)
-	gock.InterceptClient(client.RestClient.GetClient())
+	client := NewClient("http://server.com", "a secret secret")
 
 	var req = Request{
 		DatasetPath:       "/path/to/dataset",
@@ -34,8 +36,53 @@ func TestClient_Export(t *testing.T) {
 		},
 	}
 
-	_, err := client.Export(req)
+	_, err := client.Export(context.Background(), req)
 	if err != nil {
 		t.Errorf("Got error %v", err)
 	}
 }
+
+func TestNew_HTTPClient(t *testing.T) {
+	httpClient := &http.Client{}
+	client := New("http://server.com", WithHTTPClient(httpClient), WithTimeout(time.Hour))
+
+	assert.Equal(t, time.Duration(0), httpClient.Timeout)
+	assert.Equal(t, time.Hour, client.Client.Timeout)
+}
+
+func TestNewPreview(t *testing.T) {
+	fields := []string{"person/fnr", "person/name", "person/address/street", "income"}
+
+	var req = Request{
+		DatasetPath:     "/path/to/dataset",
+		ColumnSelectors: []string{"person/**", "nosuchfield"},
+		PseudoRules: []PseudoRule{
+			{Pattern: "**/fnr", Func: "fpe-fnr(somesecret1)"},
+			{Pattern: "**/income", Func: "fpe-digits(somesecret1)"},
+		},
+	}
+
+	preview := NewPreview(req, fields)
+
+	assert.Equal(t, []string{"person/fnr", "person/name", "person/address/street"}, preview.Columns)
+	assert.Equal(t, []string{"person/fnr", "person/name", "person/address/street"}, preview.Selectors[0].Fields)
+	assert.True(t, preview.Selectors[1].IsEmpty())
+	assert.Equal(t, []string{"person/fnr"}, preview.PseudoRules[0].Fields)
+
+	// income is not a selected column, so the rule should not match anything
+	assert.True(t, preview.PseudoRules[1].IsEmpty())
+
+	unmatched := preview.Unmatched()
+	assert.Len(t, unmatched, 2)
+	assert.Equal(t, "nosuchfield", unmatched[0].Pattern)
+	assert.Equal(t, "**/income", unmatched[1].Pattern)
+}
+
+func TestNewPreviewWithoutSelectors(t *testing.T) {
+	fields := []string{"person/fnr", "income"}
+
+	preview := NewPreview(Request{DatasetPath: "/path/to/dataset"}, fields)
+
+	assert.Equal(t, fields, preview.Columns)
+	assert.Empty(t, preview.Unmatched())
+}
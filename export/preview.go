@@ -0,0 +1,78 @@
+package export
+
+import (
+	"github.com/statisticsnorway/dapla-cli/glob"
+)
+
+// PatternMatch holds the schema fields matched by a single column selector or pseudo rule pattern
+type PatternMatch struct {
+	Pattern string
+	Func    string
+	Fields  []string
+}
+
+// IsEmpty returns true iff the pattern did not match any fields
+func (m PatternMatch) IsEmpty() bool {
+	return len(m.Fields) == 0
+}
+
+// Preview describes which fields an export request would include and pseudonymize, given the fields of a dataset
+type Preview struct {
+	Fields      []string
+	Columns     []string
+	Selectors   []PatternMatch
+	PseudoRules []PatternMatch
+}
+
+// NewPreview matches the column selectors and pseudo rules of an export request against the field paths
+// of a dataset. If no column selectors are specified then all fields are selected. Pseudo rules are only
+// matched against the selected columns.
+func NewPreview(req Request, fields []string) Preview {
+	preview := Preview{Fields: fields}
+
+	selected := map[string]bool{}
+	for _, selector := range req.ColumnSelectors {
+		match := PatternMatch{Pattern: selector, Fields: matchingFields(selector, fields)}
+		for _, field := range match.Fields {
+			selected[field] = true
+		}
+		preview.Selectors = append(preview.Selectors, match)
+	}
+
+	for _, field := range fields {
+		if len(req.ColumnSelectors) == 0 || selected[field] {
+			preview.Columns = append(preview.Columns, field)
+		}
+	}
+
+	for _, rule := range req.PseudoRules {
+		preview.PseudoRules = append(preview.PseudoRules, PatternMatch{
+			Pattern: rule.Pattern,
+			Func:    rule.Func,
+			Fields:  matchingFields(rule.Pattern, preview.Columns),
+		})
+	}
+
+	return preview
+}
+
+// Unmatched returns all column selectors and pseudo rules that did not match any fields
+func (p Preview) Unmatched() []PatternMatch {
+	var unmatched []PatternMatch
+	for _, match := range append(append([]PatternMatch{}, p.Selectors...), p.PseudoRules...) {
+		if match.IsEmpty() {
+			unmatched = append(unmatched, match)
+		}
+	}
+	return unmatched
+}
+
+func matchingFields(pattern string, fields []string) []string {
+	var matches []string
+	for _, field := range fields {
+		if glob.Match(pattern, field) {
+			matches = append(matches, field)
+		}
+	}
+	return matches
+}
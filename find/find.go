@@ -0,0 +1,162 @@
+// Package find implements the expressions of the find command, which are modelled on the expressions of Unix find.
+// An expression is made up of predicates (-name, -type, -size, ...), operators (-and, -or, -not and parentheses)
+// and actions (-print, -print0, -json and -exec).
+package find
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/statisticsnorway/dapla-cli/glob"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+)
+
+// Entry is a dataset or folder that an expression is evaluated against. Size and Empty are only called by the
+// predicates that need them, since they may require additional requests.
+type Entry interface {
+	Element() maintenance.ListDatasetElement
+	// Size returns the total size of all the versions of a dataset. Folders have size 0.
+	Size() (uint64, error)
+	// Empty returns true for datasets without files and folders without datasets or folders
+	Empty() (bool, error)
+}
+
+// Env holds what the actions of an expression need to produce output
+type Env struct {
+	Out io.Writer
+	// Exec runs a command for the -exec action and returns whether it succeeded
+	Exec func(args []string) (bool, error)
+}
+
+// Expr is a parsed find expression
+type Expr interface {
+	Eval(env *Env, entry Entry) (bool, error)
+}
+
+type predicate func(env *Env, entry Entry) (bool, error)
+
+func (p predicate) Eval(env *Env, entry Entry) (bool, error) {
+	return p(env, entry)
+}
+
+type and struct {
+	left, right Expr
+}
+
+func (e and) Eval(env *Env, entry Entry) (bool, error) {
+	ok, err := e.left.Eval(env, entry)
+	if !ok || err != nil {
+		return false, err
+	}
+	return e.right.Eval(env, entry)
+}
+
+type or struct {
+	left, right Expr
+}
+
+func (e or) Eval(env *Env, entry Entry) (bool, error) {
+	ok, err := e.left.Eval(env, entry)
+	if ok || err != nil {
+		return ok, err
+	}
+	return e.right.Eval(env, entry)
+}
+
+type not struct {
+	expr Expr
+}
+
+func (e not) Eval(env *Env, entry Entry) (bool, error) {
+	ok, err := e.expr.Eval(env, entry)
+	return !ok, err
+}
+
+func namePredicate(pattern string) predicate {
+	return func(env *Env, entry Entry) (bool, error) {
+		return path.Match(pattern, path.Base(entry.Element().Path))
+	}
+}
+
+func pathPredicate(pattern string) predicate {
+	return func(env *Env, entry Entry) (bool, error) {
+		return glob.Match(pattern, entry.Element().Path), nil
+	}
+}
+
+func typePredicate(folder bool) predicate {
+	return func(env *Env, entry Entry) (bool, error) {
+		return entry.Element().IsFolder() == folder, nil
+	}
+}
+
+func fieldPredicate(field func(maintenance.ListDatasetElement) string, value string) predicate {
+	return func(env *Env, entry Entry) (bool, error) {
+		return strings.EqualFold(field(entry.Element()), value), nil
+	}
+}
+
+func createdPredicate(threshold time.Time, newer bool) predicate {
+	return func(env *Env, entry Entry) (bool, error) {
+		createdAt := entry.Element().CreatedAt
+		if newer {
+			return createdAt.After(threshold), nil
+		}
+		return createdAt.Before(threshold), nil
+	}
+}
+
+// sizePredicate compares the size of an entry, rounded up to whole units, with n. A positive cmp matches sizes
+// greater than n, a negative cmp sizes less than n, and 0 sizes equal to n.
+func sizePredicate(n uint64, unit uint64, cmp int) predicate {
+	return func(env *Env, entry Entry) (bool, error) {
+		size, err := entry.Size()
+		if err != nil {
+			return false, err
+		}
+		units := (size + unit - 1) / unit
+		switch {
+		case cmp > 0:
+			return units > n, nil
+		case cmp < 0:
+			return units < n, nil
+		default:
+			return units == n, nil
+		}
+	}
+}
+
+func emptyPredicate(env *Env, entry Entry) (bool, error) {
+	return entry.Empty()
+}
+
+func printAction(terminator string) predicate {
+	return func(env *Env, entry Entry) (bool, error) {
+		_, err := fmt.Fprint(env.Out, entry.Element().Path, terminator)
+		return err == nil, err
+	}
+}
+
+func jsonAction(env *Env, entry Entry) (bool, error) {
+	line, err := json.Marshal(entry.Element())
+	if err != nil {
+		return false, err
+	}
+	_, err = fmt.Fprintln(env.Out, string(line))
+	return err == nil, err
+}
+
+// execAction runs the command with every {} in its arguments replaced by the path of the entry
+func execAction(command []string) predicate {
+	return func(env *Env, entry Entry) (bool, error) {
+		args := make([]string, len(command))
+		for i, arg := range command {
+			args[i] = strings.ReplaceAll(arg, "{}", entry.Element().Path)
+		}
+		return env.Exec(args)
+	}
+}
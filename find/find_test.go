@@ -0,0 +1,172 @@
+package find
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"github.com/stretchr/testify/assert"
+)
+
+type testEntry struct {
+	element maintenance.ListDatasetElement
+	size    uint64
+	empty   bool
+}
+
+func (e testEntry) Element() maintenance.ListDatasetElement {
+	return e.element
+}
+
+func (e testEntry) Size() (uint64, error) {
+	return e.size, nil
+}
+
+func (e testEntry) Empty() (bool, error) {
+	return e.empty, nil
+}
+
+var now = time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+
+var testEntries = []testEntry{
+	{
+		element: maintenance.ListDatasetElement{Path: "/skatt/person", CreatedBy: "kari",
+			CreatedAt: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), Depth: 1},
+	},
+	{
+		element: maintenance.ListDatasetElement{Path: "/skatt/person/inntekt", CreatedBy: "kari",
+			CreatedAt: time.Date(2021, 4, 28, 0, 0, 0, 0, time.UTC), Valuation: "SENSITIVE", State: "INPUT"},
+		size: 3 << 20,
+	},
+	{
+		element: maintenance.ListDatasetElement{Path: "/skatt/person/adresse", CreatedBy: "ola",
+			CreatedAt: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC), Valuation: "INTERNAL", State: "RAW"},
+		empty: true,
+	},
+	{
+		element: maintenance.ListDatasetElement{Path: "/skatt/naering", CreatedBy: "ola",
+			CreatedAt: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC), Depth: 1},
+		empty: true,
+	},
+}
+
+// findPaths evaluates the expression against the test entries and returns the output
+func findPaths(t *testing.T, expression string) string {
+	expr, err := Parse(strings.Fields(expression), now)
+	if err != nil {
+		t.Fatalf("Could not parse %q: %v", expression, err)
+	}
+
+	var output bytes.Buffer
+	env := &Env{Out: &output}
+	for _, entry := range testEntries {
+		if _, err := expr.Eval(env, entry); err != nil {
+			t.Fatalf("Could not evaluate %q: %v", expression, err)
+		}
+	}
+	return output.String()
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   []string
+	}{
+		{"", []string{"/skatt/person", "/skatt/person/inntekt", "/skatt/person/adresse", "/skatt/naering"}},
+		{"-type dataset", []string{"/skatt/person/inntekt", "/skatt/person/adresse"}},
+		{"-type folder", []string{"/skatt/person", "/skatt/naering"}},
+		{"-name *e*", []string{"/skatt/person", "/skatt/person/inntekt", "/skatt/person/adresse", "/skatt/naering"}},
+		{"-name inn*", []string{"/skatt/person/inntekt"}},
+		{"-path /skatt/person/**", []string{"/skatt/person", "/skatt/person/inntekt", "/skatt/person/adresse"}},
+		{"-path /skatt/*", []string{"/skatt/person", "/skatt/naering"}},
+		{"-valuation sensitive", []string{"/skatt/person/inntekt"}},
+		{"-state RAW", []string{"/skatt/person/adresse"}},
+		{"-created-by ola", []string{"/skatt/person/adresse", "/skatt/naering"}},
+		{"-newer 7d", []string{"/skatt/person/inntekt"}},
+		{"-older 2021-01-01", []string{"/skatt/person/adresse"}},
+		{"-newer 2021-01-01 -older 2021-04-01", []string{"/skatt/naering"}},
+		{"-size +2M", []string{"/skatt/person/inntekt"}},
+		{"-size -1 -type dataset", []string{"/skatt/person/adresse"}},
+		{"-size 3M", []string{"/skatt/person/inntekt"}},
+		{"-size 3072K", []string{"/skatt/person/inntekt"}},
+		{"-empty", []string{"/skatt/person/adresse", "/skatt/naering"}},
+		{"-type dataset -and -created-by kari", []string{"/skatt/person/inntekt"}},
+		{"-type dataset -a -created-by kari", []string{"/skatt/person/inntekt"}},
+		{"-state RAW -or -state INPUT", []string{"/skatt/person/inntekt", "/skatt/person/adresse"}},
+		{"-not -type dataset", []string{"/skatt/person", "/skatt/naering"}},
+		{"! -empty -created-by ola", []string{}},
+		{"-created-by ola -o -created-by kari -type folder", []string{"/skatt/person", "/skatt/person/adresse", "/skatt/naering"}},
+		{"( -created-by ola -o -created-by kari ) -type folder", []string{"/skatt/person", "/skatt/naering"}},
+		{"-name inntekt -print -o -print", []string{"/skatt/person", "/skatt/person/inntekt", "/skatt/person/adresse", "/skatt/naering"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			expected := ""
+			for _, path := range test.expected {
+				expected += path + "\n"
+			}
+			assert.Equal(t, expected, findPaths(t, test.expression))
+		})
+	}
+}
+
+func TestParseActions(t *testing.T) {
+	assert.Equal(t, "/skatt/person/inntekt\x00", findPaths(t, "-name inntekt -print0"))
+	assert.Equal(t, "", findPaths(t, "-name nothing -json"))
+	assert.Contains(t, findPaths(t, "-name inntekt -json"), `"path":"/skatt/person/inntekt"`)
+
+	expr, err := Parse([]string{"-type", "dataset", "-exec", "dapla", "rm", "{}", ";", "-print"}, now)
+	assert.Nil(t, err)
+
+	var output bytes.Buffer
+	var commands [][]string
+	env := &Env{Out: &output, Exec: func(args []string) (bool, error) {
+		commands = append(commands, args)
+		return !strings.HasSuffix(args[2], "adresse"), nil
+	}}
+	for _, entry := range testEntries {
+		_, err := expr.Eval(env, entry)
+		assert.Nil(t, err)
+	}
+
+	assert.Equal(t, [][]string{
+		{"dapla", "rm", "/skatt/person/inntekt"},
+		{"dapla", "rm", "/skatt/person/adresse"},
+	}, commands)
+	// -print is only evaluated if the command succeeded
+	assert.Equal(t, "/skatt/person/inntekt\n", output.String())
+
+	env.Exec = func(args []string) (bool, error) {
+		return false, errors.New("command not found")
+	}
+	_, err = expr.Eval(env, testEntries[1])
+	assert.EqualError(t, err, "command not found")
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"-name":                 "missing argument to -name",
+		"-name [":               "invalid pattern [ for -name: syntax error in pattern",
+		"-type file":            "invalid type file for -type: expected dataset or folder",
+		"-newer yesterday":      "invalid time yesterday for -newer: expected a timestamp or a duration such as 7d",
+		"-size 10X":             "invalid size 10X for -size: expected [+|-]N[c|K|M|G|T]",
+		"-exec dapla rm {}":     "missing ; after -exec",
+		"-exec ;":               "missing command to -exec",
+		"( -empty":              "missing )",
+		"-empty )":              "unexpected )",
+		"-empty -or":            "expected an expression",
+		"-foo":                  "unknown predicate -foo",
+		"-type dataset -or -or": "unknown predicate -or",
+	}
+
+	for expression, expected := range tests {
+		t.Run(expression, func(t *testing.T) {
+			_, err := Parse(strings.Fields(expression), now)
+			assert.EqualError(t, err, expected)
+		})
+	}
+}
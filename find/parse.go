@@ -0,0 +1,295 @@
+package find
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/statisticsnorway/dapla-cli/glob"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"github.com/statisticsnorway/dapla-cli/retention"
+)
+
+// timeLayouts lists the supported formats of the -newer and -older arguments, in addition to durations
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"}
+
+// sizeUnits maps the suffixes of the -size argument to their size in bytes
+var sizeUnits = map[byte]uint64{
+	'c': 1,
+	'K': 1 << 10,
+	'M': 1 << 20,
+	'G': 1 << 30,
+	'T': 1 << 40,
+}
+
+// IsExpression returns true if arg starts an expression, i.e. it is not a path
+func IsExpression(arg string) bool {
+	return strings.HasPrefix(arg, "-") || arg == "(" || arg == "!"
+}
+
+// Parse parses an expression. Operators bind in the following order, tightest first: parentheses, -not (or !),
+// -and (or -a, or two expressions next to each other) and -or (or -o). If the expression contains no actions,
+// -print is applied to the entries that match it. Relative times of -newer and -older are relative to now.
+//
+// The following predicates and actions are supported:
+//
+//	-name PATTERN          the last element of the path matches a shell pattern
+//	-path PATTERN          the path matches a glob, where ** matches any number of folders
+//	-type dataset|folder   the entry is a dataset or a folder
+//	-valuation VALUATION   the dataset has the given valuation
+//	-state STATE           the dataset has the given state
+//	-created-by USER       the entry was created by the given user
+//	-newer TIME            the entry was created after TIME, a timestamp or a duration such as 7d
+//	-older TIME            the entry was created before TIME, a timestamp or a duration such as 7d
+//	-size [+|-]N[c|K|M|G|T] the size of all versions of the dataset, rounded up to the unit, is more than (+),
+//	                       less than (-) or exactly N
+//	-empty                 the dataset has no files, or the folder has no datasets or folders
+//	-print                 print the path followed by a newline
+//	-print0                print the path followed by a NUL character
+//	-json                  print the entry as a JSON line
+//	-exec COMMAND ;        run COMMAND with {} replaced by the path, true if the command succeeds
+func Parse(args []string, now time.Time) (Expr, error) {
+	p := &parser{args: args, now: now}
+	if len(args) == 0 {
+		return printAction("\n"), nil
+	}
+
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.args) {
+		return nil, fmt.Errorf("unexpected %s", p.args[p.pos])
+	}
+	if !p.hasAction {
+		expr = and{expr, printAction("\n")}
+	}
+	return expr, nil
+}
+
+type parser struct {
+	args      []string
+	pos       int
+	now       time.Time
+	hasAction bool
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.args) {
+		return p.args[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) next() string {
+	arg := p.peek()
+	p.pos++
+	return arg
+}
+
+func (p *parser) argument(name string) (string, error) {
+	if p.pos >= len(p.args) {
+		return "", fmt.Errorf("missing argument to %s", name)
+	}
+	return p.next(), nil
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "-o" || p.peek() == "-or" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = or{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.pos < len(p.args) {
+		switch p.peek() {
+		case "-o", "-or", ")":
+			return left, nil
+		case "-a", "-and":
+			p.next()
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = and{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (Expr, error) {
+	if p.peek() == "!" || p.peek() == "-not" {
+		p.next()
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return not{expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	if p.pos >= len(p.args) {
+		return nil, fmt.Errorf("expected an expression")
+	}
+
+	name := p.next()
+	switch name {
+	case "(":
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		return expr, nil
+
+	case "-name":
+		pattern, err := p.argument(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %s for -name: %v", pattern, err)
+		}
+		return namePredicate(pattern), nil
+
+	case "-path":
+		pattern, err := p.argument(name)
+		if err != nil {
+			return nil, err
+		}
+		if err := glob.Validate(pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern %s for -path: %v", pattern, err)
+		}
+		return pathPredicate(pattern), nil
+
+	case "-type":
+		entryType, err := p.argument(name)
+		if err != nil {
+			return nil, err
+		}
+		switch entryType {
+		case "dataset":
+			return typePredicate(false), nil
+		case "folder":
+			return typePredicate(true), nil
+		}
+		return nil, fmt.Errorf("invalid type %s for -type: expected dataset or folder", entryType)
+
+	case "-valuation", "-state", "-created-by":
+		value, err := p.argument(name)
+		if err != nil {
+			return nil, err
+		}
+		fields := map[string]func(maintenance.ListDatasetElement) string{
+			"-valuation":  func(e maintenance.ListDatasetElement) string { return e.Valuation },
+			"-state":      func(e maintenance.ListDatasetElement) string { return e.State },
+			"-created-by": func(e maintenance.ListDatasetElement) string { return e.CreatedBy },
+		}
+		return fieldPredicate(fields[name], value), nil
+
+	case "-newer", "-older":
+		value, err := p.argument(name)
+		if err != nil {
+			return nil, err
+		}
+		threshold, err := p.parseTime(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time %s for %s: expected a timestamp or a duration such as 7d", value, name)
+		}
+		return createdPredicate(threshold, name == "-newer"), nil
+
+	case "-size":
+		value, err := p.argument(name)
+		if err != nil {
+			return nil, err
+		}
+		return parseSize(value)
+
+	case "-empty":
+		return predicate(emptyPredicate), nil
+
+	case "-print":
+		p.hasAction = true
+		return printAction("\n"), nil
+
+	case "-print0":
+		p.hasAction = true
+		return printAction("\x00"), nil
+
+	case "-json":
+		p.hasAction = true
+		return predicate(jsonAction), nil
+
+	case "-exec":
+		var command []string
+		for p.pos < len(p.args) && p.peek() != ";" {
+			command = append(command, p.next())
+		}
+		if p.next() != ";" {
+			return nil, fmt.Errorf("missing ; after -exec")
+		}
+		if len(command) == 0 {
+			return nil, fmt.Errorf("missing command to -exec")
+		}
+		p.hasAction = true
+		return execAction(command), nil
+	}
+
+	return nil, fmt.Errorf("unknown predicate %s", name)
+}
+
+// parseTime parses a timestamp, or a duration that is subtracted from now
+func (p *parser) parseTime(value string) (time.Time, error) {
+	if duration, err := retention.ParseDuration(value); err == nil {
+		return p.now.Add(-duration), nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %s", value)
+}
+
+func parseSize(value string) (Expr, error) {
+	cmp := 0
+	number := value
+	if strings.HasPrefix(number, "+") {
+		cmp, number = 1, number[1:]
+	} else if strings.HasPrefix(number, "-") {
+		cmp, number = -1, number[1:]
+	}
+
+	unit := uint64(1)
+	if len(number) > 0 {
+		if u, ok := sizeUnits[number[len(number)-1]]; ok {
+			unit, number = u, number[:len(number)-1]
+		}
+	}
+
+	n, err := strconv.ParseUint(number, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid size %s for -size: expected [+|-]N[c|K|M|G|T]", value)
+	}
+	return sizePredicate(n, unit, cmp), nil
+}
@@ -0,0 +1,62 @@
+// Package glob implements glob matching of slash separated paths, such as dataset paths and schema field paths.
+package glob
+
+import (
+	"path"
+	"strings"
+)
+
+// Match reports whether name matches the glob pattern. Patterns are matched segment by segment, where each
+// segment supports the syntax of path.Match. In addition the special segment '**' matches zero or more segments.
+// Leading and trailing slashes are ignored. A malformed pattern never matches.
+func Match(pattern, name string) bool {
+	return matchSegments(split(pattern), split(name))
+}
+
+// Validate returns an error if the pattern is malformed
+func Validate(pattern string) error {
+	for _, segment := range split(pattern) {
+		if segment == "**" {
+			continue
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func split(s string) []string {
+	s = strings.Trim(s, "/")
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, "/")
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Collapse consecutive '**' and try every possible number of consumed segments
+			rest := pattern[1:]
+			for len(rest) > 0 && rest[0] == "**" {
+				rest = rest[1:]
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(rest, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,47 @@
+package glob
+
+import (
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{"foo", "foo", true},
+		{"foo", "bar", false},
+		{"*", "foo", true},
+		{"*", "foo/bar", false},
+		{"foo/*", "foo/bar", true},
+		{"**", "foo/bar/baz", true},
+		{"**/fnr", "fnr", true},
+		{"**/fnr", "person/fnr", true},
+		{"**/fnr", "person/address/fnr", true},
+		{"**/fnr", "person/fnr2", false},
+		{"person/**", "person/address/street", true},
+		{"person/**/street", "person/street", true},
+		{"/tmp/**", "/tmp/foo/bar", true},
+		{"/tmp/**", "/raw/foo", false},
+		{"/tmp/**", "/tmp", true},
+		{"pers?n/*name", "person/firstname", true},
+		{"[a-c]*", "bar", true},
+		{"[", "[", false},
+	}
+
+	for _, test := range tests {
+		if actual := Match(test.pattern, test.name); actual != test.expected {
+			t.Errorf("Match(%q, %q): expected %v, but got %v", test.pattern, test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate("**/foo/*bar"); err != nil {
+		t.Errorf("Got error %v", err)
+	}
+	if err := Validate("foo/[bar"); err == nil {
+		t.Errorf("Expected error for malformed pattern")
+	}
+}
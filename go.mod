@@ -6,15 +6,17 @@ require (
 	github.com/acarl005/stripansi v0.0.0-20180116102854-5a71ef0e047d
 	github.com/andreyvit/diff v0.0.0-20170406064948-c7f18ee00883
 	github.com/briandowns/spinner v1.12.0
-	github.com/go-resty/resty/v2 v2.6.0
 	github.com/google/go-cmp v0.5.5
 	github.com/gookit/color v1.3.8
 	github.com/mitchellh/go-homedir v1.1.0
 	github.com/pkg/errors v0.9.1
 	github.com/sergi/go-diff v1.2.0 // indirect
 	github.com/spf13/cobra v1.1.3
+	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.7.1
 	github.com/stretchr/testify v1.7.0
+	golang.org/x/sys v0.0.0-20210330210617-4fbd30eecc44
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
 	gopkg.in/h2non/gock.v1 v1.0.16
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -1,14 +1,11 @@
 package main
 
 import (
-	"fmt"
-	"github.com/statisticsnorway/dapla-cli/cmd"
 	"os"
+
+	"github.com/statisticsnorway/dapla-cli/cmd"
 )
 
 func main() {
-	if err := cmd.Execute(); err != nil {
-		_, _ = fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
-	}
+	os.Exit(cmd.Execute())
 }
@@ -0,0 +1,108 @@
+package maintenance
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/statisticsnorway/dapla-cli/api"
+)
+
+// API is the data-maintenance API. It is implemented by Client, and can be implemented by fakes in tests.
+type API interface {
+	ListDatasets(ctx context.Context, path string) (*ListDatasetResponse, error)
+	IterateDatasets(ctx context.Context, path string, fn IterateFunc) error
+	SearchDatasets(ctx context.Context, query SearchQuery) (*SearchResponse, error)
+	ListVersions(ctx context.Context, path string) (*ListVersionsResponse, error)
+	GetDatasetSchema(ctx context.Context, path string) (*DatasetSchema, error)
+	GetDatasetInfo(ctx context.Context, path string) (*DatasetInfo, error)
+	DeleteDatasets(ctx context.Context, path string, dryRun bool) (*DeleteDatasetResponse, error)
+	DeleteDatasetVersions(ctx context.Context, path string, timestamps []time.Time, dryRun bool) (*DeleteDatasetResponse, error)
+	MoveDataset(ctx context.Context, src string, dst string, conflict ConflictPolicy, dryRun bool) (*MoveDatasetResponse, error)
+	CopyDataset(ctx context.Context, src string, dst string, versions []time.Time, progress func(CopyProgress)) (*CopyDatasetResponse, error)
+	TrashDataset(ctx context.Context, path string, expiry time.Duration, dryRun bool) (*TrashedDataset, error)
+	ListTrash(ctx context.Context) ([]TrashedDataset, error)
+	RestoreDataset(ctx context.Context, path string, dst string, conflict ConflictPolicy) (*MoveDatasetResponse, error)
+	EmptyTrash(ctx context.Context, path string, dryRun bool) ([]DeleteDatasetResponse, error)
+	ListProtectedPaths(ctx context.Context) ([]string, error)
+}
+
+var _ API = (*Client)(nil)
+
+// DefaultBasePath is the path of the API endpoints relative to the base URL
+const DefaultBasePath = "/api/v1"
+
+// TokenSource returns the auth token to send with a request. It is called for every request.
+type TokenSource = api.TokenSource
+
+// Option configures a Client created by New
+type Option func(c *Client)
+
+// New creates a new client that talks with the data-maintenance API at baseURL. Without options the client uses
+// http.DefaultTransport, no timeout, the DefaultBasePath and no auth token.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		BaseURL:  baseURL,
+		Client:   &http.Client{},
+		PageSize: DefaultPageSize,
+		basePath: DefaultBasePath,
+		tokenSource: func(ctx context.Context) (string, error) {
+			return "", nil
+		},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithHTTPClient sets the HTTP client used to send requests, e.g. to share its transport with other clients. The
+// client is copied, so that WithTransport and WithTimeout do not change it.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		copied := *client
+		c.Client = &copied
+	}
+}
+
+// WithTransport sets the transport used to send requests, e.g. to add logging or retries
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		c.Client.Transport = transport
+	}
+}
+
+// WithTimeout sets the time limit for each request, including reading the response body
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.Client.Timeout = timeout
+	}
+}
+
+// WithBasePath sets the path of the API endpoints relative to the base URL, e.g. /api/v2
+func WithBasePath(basePath string) Option {
+	return func(c *Client) {
+		c.basePath = basePath
+	}
+}
+
+// WithPageSize sets the number of datasets and folders to request per page when listing, see Client.PageSize
+func WithPageSize(pageSize int) Option {
+	return func(c *Client) {
+		c.PageSize = pageSize
+	}
+}
+
+// WithToken sets a fixed auth token
+func WithToken(token string) Option {
+	return WithTokenSource(func(ctx context.Context) (string, error) {
+		return token, nil
+	})
+}
+
+// WithTokenSource sets a callback that returns the auth token, e.g. to fetch or refresh the token when needed
+func WithTokenSource(tokenSource TokenSource) Option {
+	return func(c *Client) {
+		c.tokenSource = tokenSource
+	}
+}
@@ -0,0 +1,98 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gopkg.in/h2non/gock.v1"
+)
+
+type recordingTransport struct {
+	requests []*http.Request
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests = append(t.requests, req)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestNew(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Get("/api/v2/versions/foo/bar").
+		MatchHeader("Authorization", "^Bearer token 1$").
+		Reply(http.StatusOK).
+		BodyString(`{"datasetPath": "/foo/bar", "versions": []}`)
+
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	transport := &recordingTransport{}
+	tokens := 0
+	client := New("http://server.com",
+		WithBasePath("/api/v2"),
+		WithTransport(transport),
+		WithTimeout(time.Minute),
+		WithPageSize(10),
+		WithTokenSource(func(ctx context.Context) (string, error) {
+			tokens++
+			return "token " + string(rune('0'+tokens)), nil
+		}))
+
+	if _, err := client.ListVersions(context.Background(), "foo/bar"); err != nil {
+		t.Fatalf("Got error %v", err)
+	}
+	if len(transport.requests) != 1 {
+		t.Errorf("Expected the request to be sent with the transport, got %d requests", len(transport.requests))
+	}
+	if client.Client.Timeout != time.Minute || client.PageSize != 10 {
+		t.Errorf("Expected timeout and page size to be set, got %v and %d", client.Client.Timeout, client.PageSize)
+	}
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	httpClient := &http.Client{}
+	client := New("http://server.com", WithHTTPClient(httpClient), WithTransport(&recordingTransport{}), WithTimeout(time.Minute))
+
+	if httpClient.Transport != nil || httpClient.Timeout != 0 {
+		t.Errorf("Expected the given HTTP client to be unchanged, got %v and %v", httpClient.Transport, httpClient.Timeout)
+	}
+	if client.Client.Timeout != time.Minute {
+		t.Errorf("Expected the timeout to be set on the copy, got %v", client.Client.Timeout)
+	}
+}
+
+func TestNewTokenSourceError(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	client := New("http://server.com", WithTokenSource(func(ctx context.Context) (string, error) {
+		return "", errors.New("no token")
+	}))
+
+	if _, err := client.ListTrash(context.Background()); err == nil || err.Error() != "no token" {
+		t.Errorf("Expected the token source error, but got %v", err)
+	}
+}
+
+func TestClientCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	client := NewClient(server.URL, "a secret secret")
+	if _, err := client.ListTrash(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the request to be cancelled, but got %v", err)
+	}
+}
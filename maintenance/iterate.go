@@ -0,0 +1,121 @@
+package maintenance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// DefaultPageSize is the number of datasets and folders requested per page by NewClient clients
+const DefaultPageSize = 1000
+
+// IterateFunc is called by IterateDatasets for each dataset and folder
+type IterateFunc func(element ListDatasetElement) error
+
+// IterateDatasets calls fn for each dataset and folder directly under path, in the order they are returned by the
+// server. The elements are decoded as they arrive, and paginated responses are followed page by page, so fn is
+// called as soon as the first element is received. If fn returns an error, the iteration stops and the error is
+// returned.
+//
+// Both unpaginated responses (a JSON array of elements) and paginated responses are supported. A paginated response
+// is an object holding the elements of the page in "datasets", and the token of the next page in "nextPageToken".
+// The token is passed back in the pageToken query parameter, until the last page, which has no token. An error is
+// returned if the server repeats a token, rather than requesting the same pages forever.
+func (c *Client) IterateDatasets(ctx context.Context, path string, fn IterateFunc) error {
+	pageToken := ""
+	seen := map[string]bool{}
+	for {
+		queryParams := map[string]string{}
+		if c.PageSize > 0 {
+			queryParams["pageSize"] = strconv.Itoa(c.PageSize)
+		}
+		if pageToken != "" {
+			queryParams["pageToken"] = pageToken
+		}
+
+		req, err := c.createRequest(ctx, "GET", c.endpoint("list/%s", path), queryParams)
+		if err != nil {
+			return err
+		}
+
+		res, err := c.send(req)
+		if err != nil {
+			return err
+		}
+		pageToken, err = decodeListPage(res.Body, fn)
+		res.Body.Close()
+		if err != nil || pageToken == "" {
+			return err
+		}
+		if seen[pageToken] {
+			return fmt.Errorf("the server returned the page token %q of %s more than once", pageToken, path)
+		}
+		seen[pageToken] = true
+	}
+}
+
+// decodeListPage decodes a (possibly paginated) list response, calling fn for each element. Returns the token of the
+// next page, or an empty string if this is the last page.
+func decodeListPage(body io.Reader, fn IterateFunc) (string, error) {
+	decoder := json.NewDecoder(body)
+	token, err := decoder.Token()
+	if err == io.EOF {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	switch token {
+	case nil:
+		return "", nil
+
+	case json.Delim('['):
+		return "", decodeListElements(decoder, fn)
+
+	case json.Delim('{'):
+		nextPageToken := ""
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return "", err
+			}
+			switch key {
+			case "datasets":
+				if token, err := decoder.Token(); err != nil {
+					return "", err
+				} else if token != json.Delim('[') {
+					return "", fmt.Errorf("unexpected %v in datasets of list response", token)
+				}
+				err = decodeListElements(decoder, fn)
+			case "nextPageToken":
+				err = decoder.Decode(&nextPageToken)
+			default:
+				var ignored json.RawMessage
+				err = decoder.Decode(&ignored)
+			}
+			if err != nil {
+				return "", err
+			}
+		}
+		return nextPageToken, nil
+	}
+
+	return "", fmt.Errorf("unexpected %v in list response", token)
+}
+
+// decodeListElements decodes the elements of a JSON array, calling fn for each, and consumes the end of the array
+func decodeListElements(decoder *json.Decoder, fn IterateFunc) error {
+	for decoder.More() {
+		var element ListDatasetElement
+		if err := decoder.Decode(&element); err != nil {
+			return err
+		}
+		if err := fn(element); err != nil {
+			return err
+		}
+	}
+	_, err := decoder.Token()
+	return err
+}
@@ -0,0 +1,159 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestClient_IterateDatasetsPaginated(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Get("/api/v1/list/foo").
+		MatchParam("pageSize", "^2$").
+		MatchParam("pageToken", "^page2$").
+		Reply(http.StatusOK).
+		BodyString(`{"datasets": [{"path": "/foo/c", "depth": 0}]}`)
+	gock.New("http://server.com").
+		Get("/api/v1/list/foo").
+		MatchParam("pageSize", "^2$").
+		Reply(http.StatusOK).
+		BodyString(`{"datasets": [{"path": "/foo/a", "depth": 0},{"path": "/foo/b", "depth": 1}], "total": 3, "nextPageToken": "page2"}`)
+
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	var client = NewClient("http://server.com", "a secret secret")
+	client.PageSize = 2
+
+	var visited []string
+	err := client.IterateDatasets(context.Background(), "foo", func(element ListDatasetElement) error {
+		visited = append(visited, element.Path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Got error %v", err)
+	}
+
+	if expected := []string{"/foo/a", "/foo/b", "/foo/c"}; !cmp.Equal(expected, visited) {
+		t.Errorf("Expected %v, but got %v", expected, visited)
+	}
+}
+
+func TestClient_IterateDatasetsRepeatedPageToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"datasets": [{"path": "/foo/a", "depth": 0}], "nextPageToken": "again"}`)
+	}))
+	defer server.Close()
+
+	var client = NewClient(server.URL, "a secret secret")
+
+	pages := 0
+	err := client.IterateDatasets(context.Background(), "foo", func(element ListDatasetElement) error {
+		pages++
+		return nil
+	})
+	if err == nil || err.Error() != `the server returned the page token "again" of foo more than once` {
+		t.Errorf("Expected error about the repeated token, but got %v", err)
+	}
+	if pages != 2 {
+		t.Errorf("Expected 2 pages, but got %d", pages)
+	}
+}
+
+func TestClient_IterateDatasetsStopsOnError(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Get("/api/v1/list/foo").
+		Reply(http.StatusOK).
+		BodyString(`[{"path": "/foo/a", "depth": 0},{"path": "/foo/b", "depth": 0}]`)
+
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	var client = NewClient("http://server.com", "a secret secret")
+
+	stop := errors.New("stop")
+	calls := 0
+	err := client.IterateDatasets(context.Background(), "foo", func(element ListDatasetElement) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("Expected the iteration to stop after the first element, got %d calls and error %v", calls, err)
+	}
+}
+
+func TestClient_IterateDatasetsStreams(t *testing.T) {
+	received := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"path": "/foo/a", "depth": 0}`)
+		w.(http.Flusher).Flush()
+		// Only send the rest of the response when the first element has been received
+		<-received
+		fmt.Fprint(w, `,{"path": "/foo/b", "depth": 0}]`)
+	}))
+	defer server.Close()
+
+	var client = NewClient(server.URL, "a secret secret")
+
+	var visited []string
+	err := client.IterateDatasets(context.Background(), "foo", func(element ListDatasetElement) error {
+		if len(visited) == 0 {
+			close(received)
+		}
+		visited = append(visited, element.Path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Got error %v", err)
+	}
+
+	if expected := []string{"/foo/a", "/foo/b"}; !cmp.Equal(expected, visited) {
+		t.Errorf("Expected %v, but got %v", expected, visited)
+	}
+}
+
+func TestDecodeListPage(t *testing.T) {
+	tests := map[string]struct {
+		body          string
+		nextPageToken string
+		count         int
+		err           bool
+	}{
+		"empty":     {body: ``},
+		"null":      {body: `null`},
+		"array":     {body: `[{"path": "/a"}, {"path": "/b"}]`, count: 2},
+		"page":      {body: `{"nextPageToken": "t", "datasets": [{"path": "/a"}]}`, nextPageToken: "t", count: 1},
+		"last page": {body: `{"datasets": [], "nextPageToken": ""}`},
+		"string":    {body: `"foo"`, err: true},
+		"bad page":  {body: `{"datasets": {}}`, err: true},
+		"truncated": {body: `[{"path": "/a"}, {"pa`, count: 1, err: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			count := 0
+			nextPageToken, err := decodeListPage(strings.NewReader(test.body), func(element ListDatasetElement) error {
+				count++
+				return nil
+			})
+			if (err != nil) != test.err {
+				t.Errorf("Expected error %v, but got %v", test.err, err)
+			}
+			if nextPageToken != test.nextPageToken || count != test.count {
+				t.Errorf("Expected %d elements and token %q, but got %d and %q",
+					test.count, test.nextPageToken, count, nextPageToken)
+			}
+		})
+	}
+}
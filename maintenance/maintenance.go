@@ -1,30 +1,32 @@
 package maintenance
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"path"
 	"strconv"
 	"time"
+
+	"github.com/statisticsnorway/dapla-cli/api"
 )
 
-// Client struct is a facade against the data-maintenance API
+// Client struct is a facade against the data-maintenance API. Create clients with New or NewClient.
 type Client struct {
-	BaseURL    string
-	Client     *http.Client
-	authBearer string
-}
-
-// HTTPError holds information returned from an erroneous HTTP request, such as status code and error message
-type HTTPError struct {
-	statusCode int
-	message    string
+	BaseURL string
+	Client  *http.Client
+	// PageSize is the number of datasets and folders to request per page when listing, or 0 to let the server decide
+	PageSize    int
+	basePath    string
+	tokenSource TokenSource
 }
 
-func (httpError *HTTPError) Error() string {
-	return httpError.message + " (" + strconv.Itoa(httpError.statusCode) + ")"
-}
+// HTTPError holds information returned from an erroneous HTTP request, such as status code and error message. It is
+// shared with the clients of the other Dapla APIs.
+type HTTPError = api.HTTPError
 
 // ListDatasetElement struct holds one result item from the ListDatasets method
 type ListDatasetElement struct {
@@ -37,6 +39,12 @@ type ListDatasetElement struct {
 	Depth     int       `json:"depth"`
 }
 
+// Valuations lists the valuations of datasets, from the most to the least sensitive
+var Valuations = []string{"SENSITIVE", "SHIELDED", "INTERNAL", "OPEN"}
+
+// States lists the states of datasets, in the order the data is processed
+var States = []string{"RAW", "INPUT", "PROCESSED", "OUTPUT", "PRODUCT", "OTHER"}
+
 // ListDatasetResponse holds an array of result item from the ListDatasets method
 type ListDatasetResponse []ListDatasetElement
 
@@ -53,6 +61,15 @@ type DatasetVersion struct {
 	DeletedFiles []DatasetFile `json:"deletedFiles"`
 }
 
+// Size returns the total size of the deleted files of a version
+func (v DatasetVersion) Size() uint64 {
+	var size uint64
+	for _, file := range v.DeletedFiles {
+		size += file.Size
+	}
+	return size
+}
+
 // GetNumberOfFiles returns the number of deleted files from a DeleteDatasetResponse
 func (r DeleteDatasetResponse) GetNumberOfFiles() int {
 	noOfFiles := 0
@@ -62,12 +79,167 @@ func (r DeleteDatasetResponse) GetNumberOfFiles() int {
 	return noOfFiles
 }
 
+// Timestamps returns the timestamps of all versions in a DeleteDatasetResponse
+func (r DeleteDatasetResponse) Timestamps() []time.Time {
+	timestamps := make([]time.Time, 0, len(r.DatasetVersion))
+	for _, datasetVersion := range r.DatasetVersion {
+		timestamps = append(timestamps, datasetVersion.Timestamp)
+	}
+	return timestamps
+}
+
 // DatasetFile holds information about a dataset file
 type DatasetFile struct {
 	URI  string `json:"uri"`
 	Size uint64 `json:"size"`
 }
 
+// ConflictPolicy decides what happens when the destination of a move already exists
+type ConflictPolicy string
+
+// Supported conflict policies
+const (
+	ConflictFail      ConflictPolicy = "fail"
+	ConflictSkip      ConflictPolicy = "skip"
+	ConflictOverwrite ConflictPolicy = "overwrite"
+)
+
+// MoveDatasetResponse holds results from invoking the MoveDataset method
+type MoveDatasetResponse struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	// Status is either moved or skipped (if the destination exists and the conflict policy is skip)
+	Status string `json:"status"`
+}
+
+// Move statuses
+const (
+	MoveStatusMoved   = "moved"
+	MoveStatusSkipped = "skipped"
+)
+
+// CopyProgress describes a single file copied by the CopyDataset method
+type CopyProgress struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Size        uint64 `json:"size"`
+}
+
+// CopyDatasetResponse summarizes the results from invoking the CopyDataset method
+type CopyDatasetResponse struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Files       int    `json:"files"`
+	Size        uint64 `json:"size"`
+}
+
+// TrashedDataset holds information about a dataset in the trash of the current user
+type TrashedDataset struct {
+	DatasetPath string    `json:"datasetPath"`
+	TrashedAt   time.Time `json:"trashedAt"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	Versions    int       `json:"versions"`
+	TotalSize   uint64    `json:"totalSize"`
+}
+
+// ListVersionsResponse holds all versions of a dataset, as returned by the ListVersions method
+type ListVersionsResponse struct {
+	DatasetPath string    `json:"datasetPath"`
+	Versions    []Version `json:"versions"`
+}
+
+// Version holds the files of a specific version/timestamp of a dataset
+type Version struct {
+	Timestamp time.Time     `json:"timestamp"`
+	Files     []DatasetFile `json:"files"`
+}
+
+// Size returns the total size of all files in a version
+func (v Version) Size() uint64 {
+	var size uint64
+	for _, file := range v.Files {
+		size += file.Size
+	}
+	return size
+}
+
+// Timestamps returns the timestamps of all versions in a ListVersionsResponse
+func (r ListVersionsResponse) Timestamps() []time.Time {
+	timestamps := make([]time.Time, 0, len(r.Versions))
+	for _, version := range r.Versions {
+		timestamps = append(timestamps, version.Timestamp)
+	}
+	return timestamps
+}
+
+// TotalSize returns the total size of all versions in a ListVersionsResponse
+func (r ListVersionsResponse) TotalSize() uint64 {
+	var size uint64
+	for _, version := range r.Versions {
+		size += version.Size()
+	}
+	return size
+}
+
+// Version returns the version with the given timestamp, or nil if there is no such version
+func (r ListVersionsResponse) Version(timestamp time.Time) *Version {
+	for i := range r.Versions {
+		if r.Versions[i].Timestamp.Equal(timestamp) {
+			return &r.Versions[i]
+		}
+	}
+	return nil
+}
+
+// DatasetSchema holds the fields of a dataset, as returned by the GetDatasetSchema method
+type DatasetSchema struct {
+	DatasetPath string        `json:"datasetPath"`
+	Fields      []SchemaField `json:"fields"`
+}
+
+// DatasetInfo holds the metadata the backend keeps about a dataset, as returned by the GetDatasetInfo method
+type DatasetInfo struct {
+	Path        string          `json:"path"`
+	CreatedBy   string          `json:"createdBy"`
+	CreatedAt   time.Time       `json:"createdDate"`
+	Type        string          `json:"type"`
+	Valuation   string          `json:"valuation"`
+	State       string          `json:"state"`
+	PseudoRules []PseudoRule    `json:"pseudoRules"`
+	Lineage     []LineageSource `json:"lineage"`
+}
+
+// PseudoRule describes how a field of a dataset is pseudonymized. It is shared with the export client, so that the
+// rules of a dataset can be used in export requests.
+type PseudoRule = api.PseudoRule
+
+// LineageSource points to a version of a dataset that another dataset was derived from
+type LineageSource struct {
+	DatasetPath string    `json:"datasetPath"`
+	Version     time.Time `json:"version"`
+}
+
+// SchemaField describes a single (possibly nested) field of a dataset. The path of nested fields is slash separated,
+// e.g. person/address/street
+type SchemaField struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+}
+
+// FieldPaths returns the paths of all fields in the schema
+func (s DatasetSchema) FieldPaths() []string {
+	paths := make([]string, 0, len(s.Fields))
+	for _, field := range s.Fields {
+		paths = append(paths, field.Path)
+	}
+	return paths
+}
+
+// Name returns the name of a dataset file, i.e. the last element of its URI
+func (f DatasetFile) Name() string {
+	return path.Base(f.URI)
+}
+
 // IsFolder returns true iff a ListDatasetElement represents a folder
 func (e ListDatasetElement) IsFolder() bool {
 	return e.Depth > 0
@@ -78,22 +250,27 @@ func (e ListDatasetElement) IsDataset() bool {
 	return !e.IsFolder()
 }
 
-// NewClient func creates a new client that talks with the data-maintenance API
+// NewClient func creates a new client that talks with the data-maintenance API, using a fixed auth token
 func NewClient(baseURL string, authBearer string) *Client {
-	return &Client{
-		BaseURL:    baseURL,
-		Client:     http.DefaultClient,
-		authBearer: authBearer,
-	}
+	return New(baseURL, WithToken(authBearer))
+}
+
+// endpoint returns the URL of an API endpoint, given by a format relative to the base URL and base path
+func (c *Client) endpoint(format string, args ...interface{}) string {
+	return c.BaseURL + c.basePath + "/" + fmt.Sprintf(format, args...)
 }
 
-func (c *Client) createRequest(method, url string, queryParams map[string]string) (*http.Request, error) {
-	req, err := http.NewRequest(method, url, nil)
+func (c *Client) createRequest(ctx context.Context, method, url string, queryParams map[string]string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.authBearer))
+	token, err := c.tokenSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 	req.Header.Set("Accept", "application/json; charset=utf-8")
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
 
@@ -108,68 +285,290 @@ func (c *Client) createRequest(method, url string, queryParams map[string]string
 	return req, nil
 }
 
-// DeleteDatasets client method implements rm command for a specific path
-func (c *Client) DeleteDatasets(path string, dryRun bool) (*DeleteDatasetResponse, error) {
+// send sends the request and returns the response. An HTTPError is returned if the server responds with an
+// error status code. The caller must close the response body.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	res, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
 
-	var req *http.Request
-	var err error
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusBadRequest {
+		defer res.Body.Close()
+		bytes, _ := ioutil.ReadAll(res.Body)
+		return nil, api.NewHTTPError(res.StatusCode, string(bytes))
+	}
 
-	req, err = c.createRequest("DELETE", fmt.Sprintf("%s/api/v1/delete/%s", c.BaseURL, path),
-		map[string]string{"dry-run": strconv.FormatBool(dryRun)})
+	return res, nil
+}
 
+// do sends the request and decodes the JSON response body into result
+func (c *Client) do(req *http.Request, result interface{}) error {
+	res, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return json.NewDecoder(res.Body).Decode(result)
+}
+
+// DeleteDatasets client method implements rm command for a specific path
+func (c *Client) DeleteDatasets(ctx context.Context, path string, dryRun bool) (*DeleteDatasetResponse, error) {
+	req, err := c.createRequest(ctx, "DELETE", c.endpoint("delete/%s", path),
+		map[string]string{"dry-run": strconv.FormatBool(dryRun)})
 	if err != nil {
 		return nil, err
 	}
 
-	res, err := c.Client.Do(req)
+	resp := DeleteDatasetResponse{}
+	if err := c.do(req, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// DeleteDatasetVersions client method deletes specific versions of the dataset at a path. The versions are
+// identified by their timestamps. It uses the versions endpoint, never the endpoint deleting whole datasets, and
+// returns an error if the server reports deleted versions that were not requested.
+func (c *Client) DeleteDatasetVersions(ctx context.Context, path string, timestamps []time.Time, dryRun bool) (*DeleteDatasetResponse, error) {
+	req, err := c.createRequest(ctx, "DELETE", c.endpoint("versions/%s", path),
+		map[string]string{
+			"dry-run":  strconv.FormatBool(dryRun),
+			"versions": joinEpochMillis(timestamps),
+		})
 	if err != nil {
 		return nil, err
 	}
-	defer res.Body.Close()
 
-	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusBadRequest {
-		bytes, _ := ioutil.ReadAll(res.Body)
-		return nil, &HTTPError{
-			statusCode: res.StatusCode,
-			message:    string(bytes),
+	resp := DeleteDatasetResponse{}
+	if err := c.do(req, &resp); err != nil {
+		return nil, err
+	}
+
+	requested := map[int64]bool{}
+	for _, timestamp := range timestamps {
+		requested[EpochMillis(timestamp)] = true
+	}
+	for _, version := range resp.DatasetVersion {
+		if !requested[EpochMillis(version.Timestamp)] {
+			return &resp, fmt.Errorf("the server reported version %d of %s as deleted, which was not requested (%d %s in the response)",
+				EpochMillis(version.Timestamp), path, len(resp.DatasetVersion), pluralize("version", len(resp.DatasetVersion)))
 		}
 	}
 
-	resp := DeleteDatasetResponse{}
-	err = json.NewDecoder(res.Body).Decode(&resp)
+	return &resp, nil
+}
+
+// MoveDataset client method moves (renames) the dataset at path src to path dst, including all its versions
+func (c *Client) MoveDataset(ctx context.Context, src string, dst string, conflict ConflictPolicy, dryRun bool) (*MoveDatasetResponse, error) {
+	req, err := c.createRequest(ctx, "POST", c.endpoint("move/%s", src),
+		map[string]string{
+			"destination": dst,
+			"conflict":    string(conflict),
+			"dry-run":     strconv.FormatBool(dryRun),
+		})
 	if err != nil {
 		return nil, err
 	}
 
+	resp := MoveDatasetResponse{}
+	if err := c.do(req, &resp); err != nil {
+		return nil, err
+	}
+
 	return &resp, nil
 }
 
-// ListDatasets client method implements ls command for a specific path
-func (c *Client) ListDatasets(path string) (*ListDatasetResponse, error) {
-	req, err2 := c.createRequest("GET", fmt.Sprintf("%s/api/v1/list/%s", c.BaseURL, path), nil)
-	if err2 != nil {
-		return nil, err2
+// CopyDataset client method copies the dataset at path src to path dst. If no versions are given all versions are
+// copied, otherwise only the versions with the given timestamps. The server reports each copied file as it
+// goes, and progress (if not nil) is called for each of them.
+func (c *Client) CopyDataset(ctx context.Context, src string, dst string, versions []time.Time, progress func(CopyProgress)) (*CopyDatasetResponse, error) {
+	queryParams := map[string]string{"destination": dst}
+	if len(versions) > 0 {
+		queryParams["versions"] = joinEpochMillis(versions)
 	}
 
-	res, err := c.Client.Do(req)
+	req, err := c.createRequest(ctx, "POST", c.endpoint("copy/%s", src), queryParams)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/x-ndjson")
+
+	res, err := c.send(req)
 	if err != nil {
 		return nil, err
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusBadRequest {
-		bytes, _ := ioutil.ReadAll(res.Body)
-		return nil, &HTTPError{
-			statusCode: res.StatusCode,
-			message:    string(bytes),
+	resp := CopyDatasetResponse{Source: src, Destination: dst}
+	decoder := json.NewDecoder(res.Body)
+	for {
+		var file CopyProgress
+		if err := decoder.Decode(&file); err == io.EOF {
+			break
+		} else if err != nil {
+			return &resp, err
 		}
+		resp.Files++
+		resp.Size += file.Size
+		if progress != nil {
+			progress(file)
+		}
+	}
+
+	return &resp, nil
+}
+
+// TrashDataset client method moves the dataset at a path to the trash of the current user. The dataset is deleted
+// permanently when the expiry duration has passed, unless it is restored before then.
+func (c *Client) TrashDataset(ctx context.Context, path string, expiry time.Duration, dryRun bool) (*TrashedDataset, error) {
+	req, err := c.createRequest(ctx, "POST", c.endpoint("trash/%s", path),
+		map[string]string{
+			"expiry":  strconv.FormatInt(int64(expiry/time.Second), 10),
+			"dry-run": strconv.FormatBool(dryRun),
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := TrashedDataset{}
+	if err := c.do(req, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// ListTrash client method lists the datasets in the trash of the current user
+func (c *Client) ListTrash(ctx context.Context) ([]TrashedDataset, error) {
+	req, err := c.createRequest(ctx, "GET", c.endpoint("trash"), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := []TrashedDataset{}
+	if err := c.do(req, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// RestoreDataset client method moves a dataset from the trash of the current user back to its original path, or to
+// dst if it is not empty
+func (c *Client) RestoreDataset(ctx context.Context, path string, dst string, conflict ConflictPolicy) (*MoveDatasetResponse, error) {
+	queryParams := map[string]string{"conflict": string(conflict)}
+	if dst != "" {
+		queryParams["destination"] = dst
+	}
+
+	req, err := c.createRequest(ctx, "POST", c.endpoint("restore/%s", path), queryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := MoveDatasetResponse{}
+	if err := c.do(req, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// EmptyTrash client method permanently deletes the datasets in the trash of the current user. If path is not
+// empty, only the trashed datasets at or under path are deleted.
+func (c *Client) EmptyTrash(ctx context.Context, path string, dryRun bool) ([]DeleteDatasetResponse, error) {
+	queryParams := map[string]string{"dry-run": strconv.FormatBool(dryRun)}
+	if path != "" {
+		queryParams["path"] = path
 	}
 
+	req, err := c.createRequest(ctx, "DELETE", c.endpoint("trash"), queryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := []DeleteDatasetResponse{}
+	if err := c.do(req, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ListProtectedPaths client method returns the globs of the dataset paths that destructive commands should not touch
+func (c *Client) ListProtectedPaths(ctx context.Context) ([]string, error) {
+	req, err := c.createRequest(ctx, "GET", c.endpoint("protected"), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := []string{}
+	if err := c.do(req, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ListDatasets client method implements ls command for a specific path. All pages of the listing are read into
+// memory; use IterateDatasets to process large folders as they are received.
+func (c *Client) ListDatasets(ctx context.Context, path string) (*ListDatasetResponse, error) {
 	resp := ListDatasetResponse{}
-	err = json.NewDecoder(res.Body).Decode(&resp)
+	err := c.IterateDatasets(ctx, path, func(element ListDatasetElement) error {
+		resp = append(resp, element)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	return &resp, nil
 }
+
+// ListVersions client method lists all versions of the dataset at a specific path
+func (c *Client) ListVersions(ctx context.Context, path string) (*ListVersionsResponse, error) {
+	req, err := c.createRequest(ctx, "GET", c.endpoint("versions/%s", path), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := ListVersionsResponse{}
+	if err := c.do(req, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// GetDatasetSchema client method retrieves the schema fields of the dataset at a specific path
+func (c *Client) GetDatasetSchema(ctx context.Context, path string) (*DatasetSchema, error) {
+	req, err := c.createRequest(ctx, "GET", c.endpoint("schema/%s", path), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := DatasetSchema{}
+	if err := c.do(req, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// GetDatasetInfo client method retrieves the metadata of the dataset at a specific path
+func (c *Client) GetDatasetInfo(ctx context.Context, path string) (*DatasetInfo, error) {
+	req, err := c.createRequest(ctx, "GET", c.endpoint("info/%s", path), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := DatasetInfo{}
+	if err := c.do(req, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
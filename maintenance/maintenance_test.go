@@ -1,7 +1,9 @@
 package maintenance
 
 import (
+	"context"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -68,7 +70,7 @@ func TestClient_ListDatasets(t *testing.T) {
 		Depth:     2,
 	}
 
-	datasets, err := client.ListDatasets("foo")
+	datasets, err := client.ListDatasets(context.Background(), "foo")
 	if err != nil {
 		t.Errorf("Got error %v", err)
 	}
@@ -143,7 +145,7 @@ func TestClient_DeleteDatasets(t *testing.T) {
 
 	var client = NewClient("http://server.com", "a secret secret")
 
-	response, err := client.DeleteDatasets("foo/bar", false)
+	response, err := client.DeleteDatasets(context.Background(), "foo/bar", false)
 	if err != nil {
 		t.Errorf("Got error %v", err)
 	}
@@ -154,6 +156,8 @@ func TestClient_DeleteDatasets(t *testing.T) {
 }
 
 func TestClient_DeleteDatasetsDryRun(t *testing.T) {
+	defer gock.Off()
+
 	gock.New("http://server.com").
 		Delete("/api/v1/delete/foo/bar").
 		MatchParam("dry-run", "true").
@@ -208,7 +212,7 @@ func TestClient_DeleteDatasetsDryRun(t *testing.T) {
 
 	var client = NewClient("http://server.com", "a secret secret")
 
-	response, err := client.DeleteDatasets("foo/bar", true)
+	response, err := client.DeleteDatasets(context.Background(), "foo/bar", true)
 	if err != nil {
 		t.Errorf("Got error %v", err)
 	}
@@ -248,3 +252,487 @@ func TestClient_DeleteDatasetResponseMethods(t *testing.T) {
 	}
 
 }
+
+func TestClient_GetDatasetSchema(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Get("/api/v1/schema/foo/bar").
+		MatchHeader("Authorization", "^Bearer a secret secret$").
+		Reply(http.StatusOK).BodyString(`{
+	"datasetPath": "/foo/bar",
+	"fields": [{
+		"path": "person/fnr",
+		"type": "string"
+	},{
+		"path": "person/address/street",
+		"type": "string"
+	},{
+		"path": "income",
+		"type": "long"
+	}]
+}`)
+
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	expectedSchema := DatasetSchema{
+		DatasetPath: "/foo/bar",
+		Fields: []SchemaField{
+			{Path: "person/fnr", Type: "string"},
+			{Path: "person/address/street", Type: "string"},
+			{Path: "income", Type: "long"},
+		},
+	}
+
+	var client = NewClient("http://server.com", "a secret secret")
+
+	schema, err := client.GetDatasetSchema(context.Background(), "foo/bar")
+	if err != nil {
+		t.Errorf("Got error %v", err)
+	}
+
+	if !cmp.Equal(expectedSchema, *schema) {
+		t.Errorf("Expected %v, but got %v", expectedSchema, schema)
+	}
+
+	expectedPaths := []string{"person/fnr", "person/address/street", "income"}
+	if paths := schema.FieldPaths(); !cmp.Equal(expectedPaths, paths) {
+		t.Errorf("Expected %v, but got %v", expectedPaths, paths)
+	}
+}
+
+func TestClient_ListVersions(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Get("/api/v1/versions/foo/bar").
+		MatchHeader("Authorization", "^Bearer a secret secret$").
+		Reply(http.StatusOK).BodyString(`{
+	"datasetPath": "/foo/bar",
+	"versions":[{
+		"timestamp": "2000-01-01T00:00:00.123456Z",
+		"files":[{
+			"uri": "gs://bucket/prefix/foo/bar/v1/file1",
+			"size": 1
+		},{
+			"uri": "gs://bucket/prefix/foo/bar/v1/file2",
+			"size": 2
+		}]
+	},{
+		"timestamp": "3000-01-01T00:00:00.123456Z",
+		"files":[{
+			"uri": "gs://bucket/prefix/foo/bar/v2/file1",
+			"size": 4
+		}]
+	}]
+}`)
+
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	expectedResponse := ListVersionsResponse{
+		DatasetPath: "/foo/bar",
+		Versions: []Version{
+			{
+				Timestamp: time.Date(2000, 1, 1, 0, 0, 0, 123456000, time.UTC),
+				Files: []DatasetFile{
+					{URI: "gs://bucket/prefix/foo/bar/v1/file1", Size: 1},
+					{URI: "gs://bucket/prefix/foo/bar/v1/file2", Size: 2},
+				},
+			},
+			{
+				Timestamp: time.Date(3000, 1, 1, 0, 0, 0, 123456000, time.UTC),
+				Files: []DatasetFile{
+					{URI: "gs://bucket/prefix/foo/bar/v2/file1", Size: 4},
+				},
+			},
+		},
+	}
+
+	var client = NewClient("http://server.com", "a secret secret")
+
+	response, err := client.ListVersions(context.Background(), "foo/bar")
+	if err != nil {
+		t.Errorf("Got error %v", err)
+	}
+
+	if !cmp.Equal(expectedResponse, *response) {
+		t.Errorf("Expected %v, but got %v", expectedResponse, response)
+	}
+
+	if response.TotalSize() != 7 {
+		t.Errorf("Expected total size to be 7, but got %v", response.TotalSize())
+	}
+
+	if version := response.Version(time.Date(3000, 1, 1, 0, 0, 0, 123456000, time.UTC)); version == nil || version.Size() != 4 {
+		t.Errorf("Expected to find version of size 4, but got %v", version)
+	}
+}
+
+func TestClient_ListVersionsNotFound(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Get("/api/v1/versions/foo/bar").
+		Reply(http.StatusNotFound).
+		BodyString("dataset not found")
+
+	var client = NewClient("http://server.com", "a secret secret")
+
+	_, err := client.ListVersions(context.Background(), "foo/bar")
+	if _, ok := err.(*HTTPError); !ok {
+		t.Errorf("Expected HTTPError, but got %v", err)
+	}
+}
+
+func TestClient_DeleteDatasetVersions(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Delete("/api/v1/versions/foo/bar").
+		MatchParam("dry-run", "false").
+		MatchParam("versions", "^946684800123,946771200000$").
+		MatchHeader("Authorization", "^Bearer a secret secret$").
+		Reply(http.StatusOK).BodyString(`{
+	"datasetPath": "/foo/bar",
+	"totalSize": 3,
+	"deletedVersions":[{
+		"timestamp": "2000-01-01T00:00:00.123Z",
+		"deletedFiles":[{
+			"uri": "gs://bucket/prefix/foo/bar/v1/file1",
+			"size": 1
+		}]
+	},{
+		"timestamp": "2000-01-02T00:00:00Z",
+		"deletedFiles":[{
+			"uri": "gs://bucket/prefix/foo/bar/v2/file1",
+			"size": 2
+		}]
+	}]
+}`)
+
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	var client = NewClient("http://server.com", "a secret secret")
+
+	response, err := client.DeleteDatasetVersions(context.Background(), "foo/bar", []time.Time{
+		time.Date(2000, 1, 1, 0, 0, 0, 123000000, time.UTC),
+		time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
+	}, false)
+	if err != nil {
+		t.Fatalf("Got error %v", err)
+	}
+
+	if response.TotalSize != 3 || len(response.DatasetVersion) != 2 {
+		t.Errorf("Unexpected response %v", response)
+	}
+}
+
+func TestClient_DeleteDatasetVersions_UnrequestedVersions(t *testing.T) {
+	defer gock.Off()
+
+	// A server that deletes the whole dataset instead of the requested version
+	gock.New("http://server.com").
+		Delete("/api/v1/versions/foo/bar").
+		Reply(http.StatusOK).BodyString(`{
+	"datasetPath": "/foo/bar",
+	"totalSize": 3,
+	"deletedVersions":[
+		{"timestamp": "2000-01-01T00:00:00.123Z", "deletedFiles":[]},
+		{"timestamp": "2000-01-02T00:00:00Z", "deletedFiles":[]}
+	]
+}`)
+
+	var client = NewClient("http://server.com", "a secret secret")
+
+	_, err := client.DeleteDatasetVersions(context.Background(), "foo/bar", []time.Time{
+		time.Date(2000, 1, 1, 0, 0, 0, 123000000, time.UTC),
+	}, false)
+	if err == nil || !strings.Contains(err.Error(), "version 946771200000 of foo/bar as deleted, which was not requested") {
+		t.Errorf("Expected error about the unrequested version, but got %v", err)
+	}
+}
+
+func TestClient_MoveDataset(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Post("/api/v1/move/foo/bar").
+		MatchParam("destination", "^/foo/baz$").
+		MatchParam("conflict", "^skip$").
+		MatchParam("dry-run", "^true$").
+		MatchHeader("Authorization", "^Bearer a secret secret$").
+		Reply(http.StatusOK).BodyString(`{
+	"source": "/foo/bar",
+	"destination": "/foo/baz",
+	"status": "moved"
+}`)
+
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	var client = NewClient("http://server.com", "a secret secret")
+
+	response, err := client.MoveDataset(context.Background(), "foo/bar", "/foo/baz", ConflictSkip, true)
+	if err != nil {
+		t.Fatalf("Got error %v", err)
+	}
+
+	expectedResponse := MoveDatasetResponse{Source: "/foo/bar", Destination: "/foo/baz", Status: MoveStatusMoved}
+	if !cmp.Equal(expectedResponse, *response) {
+		t.Errorf("Expected %v, but got %v", expectedResponse, response)
+	}
+}
+
+func TestClient_MoveDatasetConflict(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Post("/api/v1/move/foo/bar").
+		Reply(http.StatusConflict).
+		BodyString("destination /foo/baz already exists")
+
+	var client = NewClient("http://server.com", "a secret secret")
+
+	_, err := client.MoveDataset(context.Background(), "foo/bar", "/foo/baz", ConflictFail, false)
+	if httpError, ok := err.(*HTTPError); !ok || httpError.StatusCode() != http.StatusConflict {
+		t.Errorf("Expected conflict error, but got %v", err)
+	}
+}
+
+func TestClient_CopyDataset(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Post("/api/v1/copy/foo/bar").
+		MatchParam("destination", "^/tmp/bar$").
+		MatchParam("versions", "^946684800123$").
+		MatchHeader("Authorization", "^Bearer a secret secret$").
+		MatchHeader("Accept", "^application/x-ndjson$").
+		Reply(http.StatusOK).BodyString(`{"source": "gs://bucket/prefix/foo/bar/v1/file1", "destination": "gs://bucket/prefix/tmp/bar/v1/file1", "size": 1}
+{"source": "gs://bucket/prefix/foo/bar/v1/file2", "destination": "gs://bucket/prefix/tmp/bar/v1/file2", "size": 2}
+`)
+
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	var client = NewClient("http://server.com", "a secret secret")
+
+	var copied []CopyProgress
+	response, err := client.CopyDataset(context.Background(), "foo/bar", "/tmp/bar",
+		[]time.Time{time.Date(2000, 1, 1, 0, 0, 0, 123000000, time.UTC)},
+		func(progress CopyProgress) {
+			copied = append(copied, progress)
+		})
+	if err != nil {
+		t.Fatalf("Got error %v", err)
+	}
+
+	expectedResponse := CopyDatasetResponse{Source: "foo/bar", Destination: "/tmp/bar", Files: 2, Size: 3}
+	if !cmp.Equal(expectedResponse, *response) {
+		t.Errorf("Expected %v, but got %v", expectedResponse, response)
+	}
+
+	if len(copied) != 2 || copied[1].Destination != "gs://bucket/prefix/tmp/bar/v1/file2" {
+		t.Errorf("Unexpected progress %v", copied)
+	}
+}
+
+func TestClient_TrashDataset(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Post("/api/v1/trash/foo/bar").
+		MatchParam("expiry", "^2592000$").
+		MatchParam("dry-run", "^false$").
+		MatchHeader("Authorization", "^Bearer a secret secret$").
+		Reply(http.StatusOK).BodyString(`{
+	"datasetPath": "/foo/bar",
+	"trashedAt": "2021-05-01T00:00:00Z",
+	"expiresAt": "2021-05-31T00:00:00Z",
+	"versions": 2,
+	"totalSize": 15
+}`)
+
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	var client = NewClient("http://server.com", "a secret secret")
+
+	response, err := client.TrashDataset(context.Background(), "foo/bar", 30*24*time.Hour, false)
+	if err != nil {
+		t.Fatalf("Got error %v", err)
+	}
+
+	expectedResponse := TrashedDataset{
+		DatasetPath: "/foo/bar",
+		TrashedAt:   time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC),
+		ExpiresAt:   time.Date(2021, 5, 31, 0, 0, 0, 0, time.UTC),
+		Versions:    2,
+		TotalSize:   15,
+	}
+	if !cmp.Equal(expectedResponse, *response) {
+		t.Errorf("Expected %v, but got %v", expectedResponse, response)
+	}
+}
+
+func TestClient_ListTrash(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Get("/api/v1/trash").
+		MatchHeader("Authorization", "^Bearer a secret secret$").
+		Reply(http.StatusOK).BodyString(`[{
+	"datasetPath": "/foo/bar",
+	"trashedAt": "2021-05-01T00:00:00Z",
+	"expiresAt": "2021-05-31T00:00:00Z",
+	"versions": 2,
+	"totalSize": 15
+}]`)
+
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	var client = NewClient("http://server.com", "a secret secret")
+
+	response, err := client.ListTrash(context.Background())
+	if err != nil {
+		t.Fatalf("Got error %v", err)
+	}
+
+	if len(response) != 1 || response[0].DatasetPath != "/foo/bar" || response[0].Versions != 2 {
+		t.Errorf("Unexpected response %v", response)
+	}
+}
+
+func TestClient_RestoreDataset(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Post("/api/v1/restore/foo/bar").
+		MatchParam("conflict", "^fail$").
+		MatchHeader("Authorization", "^Bearer a secret secret$").
+		Reply(http.StatusOK).BodyString(`{
+	"source": "/foo/bar",
+	"destination": "/foo/bar",
+	"status": "moved"
+}`)
+
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	var client = NewClient("http://server.com", "a secret secret")
+
+	response, err := client.RestoreDataset(context.Background(), "foo/bar", "", ConflictFail)
+	if err != nil {
+		t.Fatalf("Got error %v", err)
+	}
+
+	if response.Status != MoveStatusMoved || response.Destination != "/foo/bar" {
+		t.Errorf("Unexpected response %v", response)
+	}
+}
+
+func TestClient_EmptyTrash(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Delete("/api/v1/trash").
+		MatchParam("dry-run", "^true$").
+		MatchParam("path", "^/foo$").
+		MatchHeader("Authorization", "^Bearer a secret secret$").
+		Reply(http.StatusOK).BodyString(`[{
+	"datasetPath": "/foo/bar",
+	"totalSize": 3,
+	"deletedVersions":[{
+		"timestamp": "2000-01-01T00:00:00Z",
+		"deletedFiles":[{
+			"uri": "gs://bucket/prefix/foo/bar/v1/file1",
+			"size": 3
+		}]
+	}]
+}]`)
+
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	var client = NewClient("http://server.com", "a secret secret")
+
+	response, err := client.EmptyTrash(context.Background(), "/foo", true)
+	if err != nil {
+		t.Fatalf("Got error %v", err)
+	}
+
+	if len(response) != 1 || response[0].TotalSize != 3 {
+		t.Errorf("Unexpected response %v", response)
+	}
+}
+
+func TestClient_ListProtectedPaths(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Get("/api/v1/protected").
+		MatchHeader("Authorization", "^Bearer a secret secret$").
+		Reply(http.StatusOK).
+		BodyString(`["/produkt/**", "/kilde/**"]`)
+
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	var client = NewClient("http://server.com", "a secret secret")
+
+	paths, err := client.ListProtectedPaths(context.Background())
+	if err != nil {
+		t.Fatalf("Got error %v", err)
+	}
+
+	if expected := []string{"/produkt/**", "/kilde/**"}; !cmp.Equal(expected, paths) {
+		t.Errorf("Expected %v, but got %v", expected, paths)
+	}
+}
+
+func TestClient_GetDatasetInfo(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Get("/api/v1/info/foo/bar").
+		MatchHeader("Authorization", "^Bearer a secret secret$").
+		Reply(http.StatusOK).BodyString(`{
+	"path": "/foo/bar",
+	"createdBy": "kari",
+	"createdDate": "2021-03-01T12:00:00Z",
+	"type": "BOUNDED",
+	"valuation": "SENSITIVE",
+	"state": "INPUT",
+	"pseudoRules": [{"name": "fnr", "pattern": "**/fnr", "func": "fpe-fnr(secret1)"}],
+	"lineage": [{"datasetPath": "/kilde/bar", "version": "2021-02-01T00:00:00Z"}]
+}`)
+
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	expectedInfo := DatasetInfo{
+		Path:        "/foo/bar",
+		CreatedBy:   "kari",
+		CreatedAt:   time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC),
+		Type:        "BOUNDED",
+		Valuation:   "SENSITIVE",
+		State:       "INPUT",
+		PseudoRules: []PseudoRule{{Name: "fnr", Pattern: "**/fnr", Func: "fpe-fnr(secret1)"}},
+		Lineage:     []LineageSource{{DatasetPath: "/kilde/bar", Version: time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)}},
+	}
+
+	var client = NewClient("http://server.com", "a secret secret")
+
+	info, err := client.GetDatasetInfo(context.Background(), "foo/bar")
+	if err != nil {
+		t.Fatalf("Got error %v", err)
+	}
+
+	if !cmp.Equal(expectedInfo, *info) {
+		t.Errorf("Expected %v, but got %v", expectedInfo, info)
+	}
+}
@@ -0,0 +1,192 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SearchFilters lists the fields that search results can be filtered on
+var SearchFilters = []string{"type", "valuation", "state", "createdBy"}
+
+// SearchFilterValues lists the values of the search filters that have a fixed set of values. Any value can be given
+// for the other filters, e.g. createdBy.
+var SearchFilterValues = map[string][]string{
+	"type":      {"dataset", "folder"},
+	"valuation": Valuations,
+	"state":     States,
+}
+
+// SearchQuery holds the parameters of a dataset search
+type SearchQuery struct {
+	// Text is a free-text query matched against the dataset paths (and whatever else the server indexes)
+	Text string
+	// Path restricts the search to datasets and folders under this path
+	Path string
+	// Filters restricts the search to datasets and folders with the given field values, see SearchFilters
+	Filters map[string]string
+	// Limit is the maximum number of results to return in one page, or 0 to let the server decide
+	Limit int
+	// PageToken is the NextPageToken of the previous page, or empty for the first page
+	PageToken string
+}
+
+// SearchResult is a single dataset or folder matching a search, along with its relevance score
+type SearchResult struct {
+	ListDatasetElement
+	Score float64 `json:"score"`
+}
+
+// SearchResponse holds a page of search results, ordered by descending score
+type SearchResponse struct {
+	Results       []SearchResult `json:"results"`
+	Total         int            `json:"total"`
+	NextPageToken string         `json:"nextPageToken"`
+}
+
+// SearchDatasets client method searches the catalog for datasets and folders. Servers without a search endpoint
+// respond with an HTTPError with status code 404 or 501, see IsSearchUnavailable. The filters are sent as query
+// parameters, so anything but the SearchFilters is refused, rather than overriding the other parameters.
+func (c *Client) SearchDatasets(ctx context.Context, query SearchQuery) (*SearchResponse, error) {
+	if err := ValidateSearchFilters(query.Filters); err != nil {
+		return nil, err
+	}
+	queryParams := map[string]string{"q": query.Text}
+	if query.Path != "" {
+		queryParams["path"] = query.Path
+	}
+	if query.Limit > 0 {
+		queryParams["limit"] = strconv.Itoa(query.Limit)
+	}
+	if query.PageToken != "" {
+		queryParams["pageToken"] = query.PageToken
+	}
+	for field, value := range query.Filters {
+		queryParams[field] = value
+	}
+
+	req, err := c.createRequest(ctx, "GET", c.endpoint("search"), queryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := SearchResponse{}
+	if err := c.do(req, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// IsSearchUnavailable returns true if err means that the server does not support searching
+func IsSearchUnavailable(err error) bool {
+	httpErr, ok := err.(*HTTPError)
+	return ok && (httpErr.StatusCode() == http.StatusNotFound || httpErr.StatusCode() == http.StatusNotImplemented)
+}
+
+// WalkSearch searches for datasets and folders by walking the tree under the query path with api, and is used when
+// the server does not support searching. Every term of the query text must be part of the path of a result. Terms
+// matching the whole name of a dataset or folder score highest, then terms that are part of the name, then the rest
+// of the path. All results are returned in a single page.
+func WalkSearch(ctx context.Context, api API, query SearchQuery) (*SearchResponse, error) {
+	if err := ValidateSearchFilters(query.Filters); err != nil {
+		return nil, err
+	}
+
+	terms := strings.Fields(strings.ToLower(query.Text))
+	resp := SearchResponse{Results: []SearchResult{}}
+	err := WalkDatasets(ctx, api, query.Path, func(element ListDatasetElement) error {
+		if !matchesFilters(element, query.Filters) {
+			return nil
+		}
+		if score, ok := scoreElement(element, terms); ok {
+			resp.Results = append(resp.Results, SearchResult{element, score})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(resp.Results, func(i, j int) bool {
+		if resp.Results[i].Score != resp.Results[j].Score {
+			return resp.Results[i].Score > resp.Results[j].Score
+		}
+		return resp.Results[i].Path < resp.Results[j].Path
+	})
+	resp.Total = len(resp.Results)
+	if query.Limit > 0 && len(resp.Results) > query.Limit {
+		resp.Results = resp.Results[:query.Limit]
+	}
+	return &resp, nil
+}
+
+// ValidateSearchFilters returns an error if filters holds a field that is not one of the SearchFilters
+func ValidateSearchFilters(filters map[string]string) error {
+	for field := range filters {
+		if !isSearchFilter(field) {
+			return fmt.Errorf("unsupported search filter %s, expected one of %s", field, strings.Join(SearchFilters, ", "))
+		}
+	}
+	return nil
+}
+
+func isSearchFilter(field string) bool {
+	for _, filter := range SearchFilters {
+		if filter == field {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesFilters(element ListDatasetElement, filters map[string]string) bool {
+	for field, value := range filters {
+		var actual string
+		switch field {
+		case "type":
+			actual = "dataset"
+			if element.IsFolder() {
+				actual = "folder"
+			}
+		case "valuation":
+			actual = element.Valuation
+		case "state":
+			actual = element.State
+		case "createdBy":
+			actual = element.CreatedBy
+		}
+		if !strings.EqualFold(actual, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// scoreElement scores the element between 0 and 1, and returns false if any of the terms is not part of its path
+func scoreElement(element ListDatasetElement, terms []string) (float64, bool) {
+	if len(terms) == 0 {
+		return 1, true
+	}
+
+	fullPath := strings.ToLower(element.Path)
+	name := path.Base(fullPath)
+	score := 0
+	for _, term := range terms {
+		switch {
+		case name == term:
+			score += 3
+		case strings.Contains(name, term):
+			score += 2
+		case strings.Contains(fullPath, term):
+			score++
+		default:
+			return 0, false
+		}
+	}
+	return float64(score) / float64(3*len(terms)), true
+}
@@ -0,0 +1,131 @@
+package maintenance
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/statisticsnorway/dapla-cli/api"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestClient_SearchDatasets(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Get("/api/v1/search").
+		MatchHeader("Authorization", "^Bearer a secret secret$").
+		MatchParam("q", "^inntekt$").
+		MatchParam("path", "^/skatt$").
+		MatchParam("valuation", "^SENSITIVE$").
+		MatchParam("limit", "^10$").
+		MatchParam("pageToken", "^abc$").
+		Reply(http.StatusOK).
+		BodyString(`{"results": [{"path": "/skatt/inntekt", "depth": 0, "score": 0.9}], "total": 11, "nextPageToken": "def"}`)
+
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	var client = NewClient("http://server.com", "a secret secret")
+
+	res, err := client.SearchDatasets(context.Background(), SearchQuery{
+		Text:      "inntekt",
+		Path:      "/skatt",
+		Filters:   map[string]string{"valuation": "SENSITIVE"},
+		Limit:     10,
+		PageToken: "abc",
+	})
+	if err != nil {
+		t.Fatalf("Got error %v", err)
+	}
+
+	expected := SearchResponse{
+		Results:       []SearchResult{{ListDatasetElement{Path: "/skatt/inntekt"}, 0.9}},
+		Total:         11,
+		NextPageToken: "def",
+	}
+	if !cmp.Equal(expected, *res) {
+		t.Errorf("Expected %v, but got %v", expected, res)
+	}
+}
+
+func TestClient_SearchDatasets_InvalidFilter(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Reply(http.StatusOK).
+		BodyString(`{"results": []}`)
+
+	var client = NewClient("http://server.com", "a secret secret")
+
+	for _, field := range []string{"q", "limit", "pageToken"} {
+		_, err := client.SearchDatasets(context.Background(), SearchQuery{
+			Text:    "inntekt",
+			Filters: map[string]string{field: "x"},
+		})
+		if err == nil {
+			t.Errorf("Expected error for filter %s", field)
+		}
+	}
+	if !gock.IsPending() {
+		t.Error("Expected no requests to be sent")
+	}
+}
+
+func TestIsSearchUnavailable(t *testing.T) {
+	if !IsSearchUnavailable(api.NewHTTPError(http.StatusNotFound, "")) {
+		t.Error("Expected 404 to mean that search is unavailable")
+	}
+	if !IsSearchUnavailable(api.NewHTTPError(http.StatusNotImplemented, "")) {
+		t.Error("Expected 501 to mean that search is unavailable")
+	}
+	if IsSearchUnavailable(api.NewHTTPError(http.StatusForbidden, "")) {
+		t.Error("Expected 403 to not mean that search is unavailable")
+	}
+}
+
+func TestClient_WalkSearch(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Get("/api/v1/list/skatt$").
+		Reply(http.StatusOK).
+		BodyString(`[{"path": "skatt/inntekt", "depth": 0, "valuation": "SENSITIVE"},
+			{"path": "skatt/person", "depth": 1},
+			{"path": "skatt/naering", "depth": 0, "valuation": "INTERNAL"}]`)
+	gock.New("http://server.com").
+		Get("/api/v1/list/skatt/person$").
+		Reply(http.StatusOK).
+		BodyString(`[{"path": "skatt/person/inntekt2020", "depth": 0, "valuation": "SENSITIVE"},
+			{"path": "skatt/person/adresse", "depth": 0, "valuation": "SENSITIVE"}]`)
+
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	var client = NewClient("http://server.com", "a secret secret")
+
+	res, err := WalkSearch(context.Background(), client, SearchQuery{
+		Text:    "Inntekt",
+		Path:    "skatt",
+		Filters: map[string]string{"valuation": "sensitive", "type": "dataset"},
+	})
+	if err != nil {
+		t.Fatalf("Got error %v", err)
+	}
+
+	expected := SearchResponse{
+		Results: []SearchResult{
+			{ListDatasetElement{Path: "skatt/inntekt", Valuation: "SENSITIVE"}, 1},
+			{ListDatasetElement{Path: "skatt/person/inntekt2020", Valuation: "SENSITIVE"}, 2.0 / 3},
+		},
+		Total: 2,
+	}
+	if !cmp.Equal(expected, *res) {
+		t.Errorf("Expected %v, but got %v", expected, res)
+	}
+
+	if _, err := WalkSearch(context.Background(), client, SearchQuery{Filters: map[string]string{"size": "0"}}); err == nil {
+		t.Error("Expected an error for an unsupported filter")
+	}
+}
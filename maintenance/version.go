@@ -0,0 +1,196 @@
+package maintenance
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VersionSelector identifies a single version of a dataset. A selector is either relative to the latest
+// version (latest, latest~N) or a (possibly abbreviated) timestamp, in which case the latest version within
+// the precision of the timestamp is selected.
+type VersionSelector struct {
+	raw    string
+	offset int
+	from   time.Time
+	to     time.Time
+}
+
+// versionTimestampLayouts lists the supported timestamp formats, along with the precision they represent
+var versionTimestampLayouts = []struct {
+	layout    string
+	precision time.Duration
+}{
+	{time.RFC3339Nano, time.Nanosecond},
+	{"2006-01-02T15:04:05", time.Second},
+	{"2006-01-02T15:04", time.Minute},
+	{"2006-01-02", 24 * time.Hour},
+}
+
+// ParseVersionSelector parses a version selector. The following formats are supported:
+//
+//	latest                      the latest version
+//	latest~N                    the Nth version before the latest version
+//	1617352341234               epoch milliseconds
+//	2021-04-02T08:32:21.234Z    RFC3339 timestamp
+//	2021-04-02T08:32:21         timestamp (UTC)
+//	2021-04-02T08:32            timestamp with minute precision (UTC)
+//	2021-04-02                  date (UTC)
+func ParseVersionSelector(s string) (VersionSelector, error) {
+	selector := VersionSelector{raw: s}
+
+	switch {
+	case s == "latest":
+		return selector, nil
+
+	case strings.HasPrefix(s, "latest~"):
+		offset, err := strconv.Atoi(strings.TrimPrefix(s, "latest~"))
+		if err != nil || offset < 0 {
+			return selector, fmt.Errorf("invalid version %s: expected latest~N where N is a non-negative number", s)
+		}
+		selector.offset = offset
+		return selector, nil
+
+	case isDigits(s):
+		millis, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return selector, fmt.Errorf("invalid version %s: %v", s, err)
+		}
+		selector.from = time.Unix(0, millis*int64(time.Millisecond)).UTC()
+		selector.to = selector.from.Add(time.Millisecond)
+		return selector, nil
+	}
+
+	for _, l := range versionTimestampLayouts {
+		if t, err := time.Parse(l.layout, s); err == nil {
+			selector.from = t
+			selector.to = t.Add(l.precision)
+			return selector, nil
+		}
+	}
+
+	return selector, fmt.Errorf("invalid version %s: expected latest, latest~N, epoch milliseconds or a timestamp", s)
+}
+
+// String returns the selector as it was specified
+func (s VersionSelector) String() string {
+	return s.raw
+}
+
+// Resolve returns the timestamp of the version selected among the given version timestamps
+func (s VersionSelector) Resolve(timestamps []time.Time) (time.Time, error) {
+	sorted := append([]time.Time{}, timestamps...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].After(sorted[j]) })
+
+	if s.from.IsZero() {
+		if s.offset >= len(sorted) {
+			return time.Time{}, fmt.Errorf("version %s not found: the dataset has %d %s", s.raw, len(sorted), pluralize("version", len(sorted)))
+		}
+		return sorted[s.offset], nil
+	}
+
+	for _, t := range sorted {
+		if !t.Before(s.from) && t.Before(s.to) {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("version %s not found", s.raw)
+}
+
+// SplitVersionPath splits a dataset path with an optional version suffix (path@version) into the path and the
+// version. Returns an error if the path ends with an @ and no version, rather than treating it as the latest version.
+func SplitVersionPath(path string) (string, string, error) {
+	if i := strings.LastIndex(path, "@"); i >= 0 {
+		if i == len(path)-1 {
+			return "", "", fmt.Errorf("missing version after @ in %s", path)
+		}
+		return path[:i], path[i+1:], nil
+	}
+	return path, "", nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func pluralize(text string, n int) string {
+	if n != 1 {
+		return text + "s"
+	}
+	return text
+}
+
+// FileDiff holds the differences between the files of two versions of a dataset. Files are compared by name,
+// i.e. the last element of the file URI, since each version is stored under its own prefix.
+type FileDiff struct {
+	From      time.Time     `json:"from"`
+	To        time.Time     `json:"to"`
+	Added     []DatasetFile `json:"added"`
+	Removed   []DatasetFile `json:"removed"`
+	Changed   []FileChange  `json:"changed"`
+	Unchanged int           `json:"unchanged"`
+}
+
+// FileChange holds a file that is present in both versions, but with a different size
+type FileChange struct {
+	Name     string `json:"name"`
+	FromSize uint64 `json:"fromSize"`
+	ToSize   uint64 `json:"toSize"`
+}
+
+// DiffVersions compares the files of two versions of a dataset
+func DiffVersions(from, to Version) FileDiff {
+	diff := FileDiff{From: from.Timestamp, To: to.Timestamp}
+
+	fromFiles := map[string]DatasetFile{}
+	for _, file := range from.Files {
+		fromFiles[file.Name()] = file
+	}
+
+	toFiles := map[string]bool{}
+	for _, file := range to.Files {
+		toFiles[file.Name()] = true
+		fromFile, ok := fromFiles[file.Name()]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, file)
+		case fromFile.Size != file.Size:
+			diff.Changed = append(diff.Changed, FileChange{Name: file.Name(), FromSize: fromFile.Size, ToSize: file.Size})
+		default:
+			diff.Unchanged++
+		}
+	}
+
+	for _, file := range from.Files {
+		if !toFiles[file.Name()] {
+			diff.Removed = append(diff.Removed, file)
+		}
+	}
+
+	return diff
+}
+
+// EpochMillis returns the timestamp of a version as epoch milliseconds, which is how versions are addressed in
+// the short format
+func EpochMillis(timestamp time.Time) int64 {
+	return timestamp.Unix()*1000 + int64(timestamp.Nanosecond())/int64(time.Millisecond)
+}
+
+// joinEpochMillis formats the timestamps as a comma separated list of epoch milliseconds
+func joinEpochMillis(timestamps []time.Time) string {
+	millis := make([]string, 0, len(timestamps))
+	for _, timestamp := range timestamps {
+		millis = append(millis, strconv.FormatInt(EpochMillis(timestamp), 10))
+	}
+	return strings.Join(millis, ",")
+}
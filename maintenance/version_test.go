@@ -0,0 +1,122 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVersionSelector_Resolve(t *testing.T) {
+	timestamps := []time.Time{
+		time.Date(2021, 4, 1, 8, 0, 0, 0, time.UTC),
+		time.Date(2021, 4, 2, 8, 32, 21, 234000000, time.UTC),
+		time.Date(2021, 4, 2, 9, 15, 0, 0, time.UTC),
+		time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		selector string
+		expected time.Time
+	}{
+		{"latest", timestamps[2]},
+		{"latest~0", timestamps[2]},
+		{"latest~1", timestamps[1]},
+		{"latest~3", timestamps[3]},
+		{"1617352341234", timestamps[1]},
+		{"2021-04-02T08:32:21.234Z", timestamps[1]},
+		{"2021-04-02T08:32:21", timestamps[1]},
+		{"2021-04-02T08:32", timestamps[1]},
+		{"2021-04-02", timestamps[2]},
+		{"2021-03-01", timestamps[3]},
+	}
+
+	for _, test := range tests {
+		selector, err := ParseVersionSelector(test.selector)
+		if err != nil {
+			t.Errorf("Got error %v", err)
+			continue
+		}
+		actual, err := selector.Resolve(timestamps)
+		if err != nil {
+			t.Errorf("Got error %v for selector %s", err, test.selector)
+		} else if !actual.Equal(test.expected) {
+			t.Errorf("Expected %s to resolve to %v, but got %v", test.selector, test.expected, actual)
+		}
+	}
+
+	for _, missing := range []string{"latest~4", "2021-04-03", "1617352341235"} {
+		selector, _ := ParseVersionSelector(missing)
+		if _, err := selector.Resolve(timestamps); err == nil {
+			t.Errorf("Expected error for selector %s", missing)
+		}
+	}
+}
+
+func TestParseVersionSelector_Invalid(t *testing.T) {
+	for _, invalid := range []string{"", "newest", "latest~", "latest~x", "latest~-1", "2021-13-01"} {
+		if _, err := ParseVersionSelector(invalid); err == nil {
+			t.Errorf("Expected error for selector %q", invalid)
+		}
+	}
+
+	expected := "invalid version latest~-1: expected latest~N where N is a non-negative number"
+	if _, err := ParseVersionSelector("latest~-1"); err == nil || err.Error() != expected {
+		t.Errorf("Expected error %q, but got %v", expected, err)
+	}
+}
+
+func TestSplitVersionPath(t *testing.T) {
+	tests := []struct {
+		input   string
+		path    string
+		version string
+	}{
+		{"/foo/bar", "/foo/bar", ""},
+		{"/foo/bar@latest~2", "/foo/bar", "latest~2"},
+		{"/foo/bar@2021-04-02", "/foo/bar", "2021-04-02"},
+	}
+
+	for _, test := range tests {
+		path, version, err := SplitVersionPath(test.input)
+		if err != nil || path != test.path || version != test.version {
+			t.Errorf("Expected (%s, %s), but got (%s, %s, %v)", test.path, test.version, path, version, err)
+		}
+	}
+
+	if _, _, err := SplitVersionPath("/foo/bar@"); err == nil {
+		t.Error("Expected error for an empty version")
+	}
+}
+
+func TestDiffVersions(t *testing.T) {
+	from := Version{
+		Timestamp: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+		Files: []DatasetFile{
+			{URI: "gs://bucket/prefix/foo/bar/v1/file1", Size: 1},
+			{URI: "gs://bucket/prefix/foo/bar/v1/file2", Size: 2},
+			{URI: "gs://bucket/prefix/foo/bar/v1/file3", Size: 3},
+		},
+	}
+	to := Version{
+		Timestamp: time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC),
+		Files: []DatasetFile{
+			{URI: "gs://bucket/prefix/foo/bar/v2/file1", Size: 1},
+			{URI: "gs://bucket/prefix/foo/bar/v2/file2", Size: 5},
+			{URI: "gs://bucket/prefix/foo/bar/v2/file4", Size: 4},
+		},
+	}
+
+	diff := DiffVersions(from, to)
+
+	if len(diff.Added) != 1 || diff.Added[0].Name() != "file4" {
+		t.Errorf("Expected file4 to be added, but got %v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Name() != "file3" {
+		t.Errorf("Expected file3 to be removed, but got %v", diff.Removed)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0] != (FileChange{Name: "file2", FromSize: 2, ToSize: 5}) {
+		t.Errorf("Expected file2 to be changed, but got %v", diff.Changed)
+	}
+	if diff.Unchanged != 1 {
+		t.Errorf("Expected 1 unchanged file, but got %v", diff.Unchanged)
+	}
+}
@@ -0,0 +1,44 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// SkipFolder can be returned by a WalkFunc to skip the contents of the folder it was called with
+var SkipFolder = errors.New("skip this folder")
+
+// WalkFunc is called by WalkDatasets for each dataset and folder that is visited
+type WalkFunc func(element ListDatasetElement) error
+
+// WalkDatasets walks the tree of datasets and folders rooted at path using api, calling fn for each dataset and folder in
+// the tree (but not path itself). Folders are visited before their contents. If fn returns SkipFolder when
+// invoked on a folder, the contents of the folder are skipped. Any other error stops the walk and is returned.
+func WalkDatasets(ctx context.Context, api API, path string, fn WalkFunc) error {
+	res, err := api.ListDatasets(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	for _, element := range *res {
+		err := fn(element)
+		if element.IsFolder() {
+			if err == SkipFolder {
+				continue
+			}
+			if err == nil {
+				err = WalkDatasets(ctx, api, element.Path, fn)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsWithin returns true if path is equal to or nested within folder. A trailing slash on folder is ignored.
+func IsWithin(path, folder string) bool {
+	return folder == "/" || path == folder || strings.HasPrefix(path, strings.TrimSuffix(folder, "/")+"/")
+}
@@ -0,0 +1,65 @@
+package maintenance
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestClient_WalkDatasets(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://server.com").
+		Get("/api/v1/list/foo").
+		Reply(http.StatusOK).
+		BodyString(`[{"path": "foo/a", "depth": 0},{"path": "foo/bar", "depth": 1},{"path": "foo/skip", "depth": 1}]`)
+	gock.New("http://server.com").
+		Get("/api/v1/list/foo/bar").
+		Reply(http.StatusOK).
+		BodyString(`[{"path": "foo/bar/b", "depth": 0}]`)
+
+	gock.New("http://server.com").
+		Reply(http.StatusForbidden)
+
+	var client = NewClient("http://server.com", "a secret secret")
+
+	var visited []string
+	err := WalkDatasets(context.Background(), client, "foo", func(element ListDatasetElement) error {
+		visited = append(visited, element.Path)
+		if element.Path == "foo/skip" {
+			return SkipFolder
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Got error %v", err)
+	}
+
+	expected := []string{"foo/a", "foo/bar", "foo/bar/b", "foo/skip"}
+	if !cmp.Equal(expected, visited) {
+		t.Errorf("Expected %v, but got %v", expected, visited)
+	}
+}
+
+func TestIsWithin(t *testing.T) {
+	tests := []struct {
+		path     string
+		folder   string
+		expected bool
+	}{
+		{"/skatt/inntekt", "/", true},
+		{"/skatt/inntekt", "/skatt", true},
+		{"/skatt/inntekt", "/skatt/", true},
+		{"/skatt/inntekt", "/skatt/inntekt", true},
+		{"/skatt/inntektsdata", "/skatt/inntekt", false},
+		{"/skatt", "/skatt/inntekt", false},
+	}
+	for _, test := range tests {
+		if actual := IsWithin(test.path, test.folder); actual != test.expected {
+			t.Errorf("IsWithin(%q, %q) = %v, expected %v", test.path, test.folder, actual, test.expected)
+		}
+	}
+}
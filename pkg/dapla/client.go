@@ -0,0 +1,200 @@
+package dapla
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/statisticsnorway/dapla-cli/api"
+	"github.com/statisticsnorway/dapla-cli/export"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+)
+
+// Client is a client of the Dapla APIs. Create clients with New. The services of a client are safe for concurrent use.
+type Client struct {
+	// Datasets manages the datasets in the data-maintenance service
+	Datasets *DatasetsService
+	// Export exports datasets with the dapla-pseudo-service
+	Export *ExportService
+	// Pseudo describes and previews the pseudonymization of datasets
+	Pseudo *PseudoService
+}
+
+// Error is returned when an API responds with an error status code. StatusCode returns the status code, and Message
+// the error message in the body of the response.
+type Error struct {
+	statusCode int
+	message    string
+}
+
+func (e *Error) Error() string {
+	return e.message + " (" + strconv.Itoa(e.statusCode) + ")"
+}
+
+// StatusCode returns the HTTP status code of the response
+func (e *Error) StatusCode() int {
+	return e.statusCode
+}
+
+// Message returns the error message in the body of the response
+func (e *Error) Message() string {
+	return e.message
+}
+
+// convertError converts the errors reported by the APIs to an *Error, and returns other errors as they are
+func convertError(err error) error {
+	var httpError *api.HTTPError
+	if errors.As(err, &httpError) {
+		return &Error{statusCode: httpError.StatusCode(), message: httpError.Message()}
+	}
+	return err
+}
+
+// TokenSource returns the auth token to send with a request. It is called for every request.
+type TokenSource func(ctx context.Context) (string, error)
+
+// ErrNotConfigured is returned by the services whose API has no URL, see WithDataMaintenanceURL and
+// WithPseudoServiceURL
+var ErrNotConfigured = errors.New("the URL of the API is not configured")
+
+// Environment variables read by FromEnvironment. The dapla command sets them when it runs plugins.
+const (
+	EnvDataMaintenanceURL = "DAPLA_API_DATA_MAINTENANCE"
+	EnvPseudoServiceURL   = "DAPLA_API_DAPLA_PSEUDO_SERVICE"
+	EnvAuthToken          = "DAPLA_AUTHTOKEN"
+)
+
+// options holds the configuration of a Client given by Options
+type options struct {
+	dataMaintenanceURL string
+	pseudoServiceURL   string
+	tokenSource        TokenSource
+	httpClient         *http.Client
+	transport          http.RoundTripper
+	timeout            time.Duration
+	pageSize           int
+}
+
+// Option configures a Client created by New
+type Option func(o *options)
+
+// New creates a client of the Dapla APIs. The URL of each API is given with an option; the services of APIs without
+// a URL return ErrNotConfigured. Without options the client uses http.DefaultTransport, no timeout and no auth token.
+func New(opts ...Option) *Client {
+	o := options{
+		httpClient: &http.Client{},
+		pageSize:   maintenance.DefaultPageSize,
+		tokenSource: func(ctx context.Context) (string, error) {
+			return "", nil
+		},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	// Copy the HTTP client, so that the client given by WithHTTPClient is not changed
+	httpClient := *o.httpClient
+	if o.transport != nil {
+		httpClient.Transport = o.transport
+	}
+	if o.timeout > 0 {
+		httpClient.Timeout = o.timeout
+	}
+
+	client := &Client{
+		Datasets: &DatasetsService{},
+		Export:   &ExportService{},
+	}
+	if o.dataMaintenanceURL != "" {
+		client.Datasets.api = maintenance.New(o.dataMaintenanceURL,
+			maintenance.WithHTTPClient(&httpClient),
+			maintenance.WithTokenSource(api.TokenSource(o.tokenSource)),
+			maintenance.WithPageSize(o.pageSize))
+	}
+	if o.pseudoServiceURL != "" {
+		client.Export.client = export.New(o.pseudoServiceURL,
+			export.WithHTTPClient(&httpClient),
+			export.WithTokenSource(api.TokenSource(o.tokenSource)))
+	}
+	client.Pseudo = &PseudoService{datasets: client.Datasets}
+	return client
+}
+
+// WithDataMaintenanceURL sets the base URL of the data-maintenance service, which is used by the Datasets and Pseudo
+// services
+func WithDataMaintenanceURL(baseURL string) Option {
+	return func(o *options) {
+		o.dataMaintenanceURL = baseURL
+	}
+}
+
+// WithPseudoServiceURL sets the base URL of the dapla-pseudo-service, which is used by the Export service
+func WithPseudoServiceURL(baseURL string) Option {
+	return func(o *options) {
+		o.pseudoServiceURL = baseURL
+	}
+}
+
+// WithToken sets a fixed auth token
+func WithToken(token string) Option {
+	return WithTokenSource(func(ctx context.Context) (string, error) {
+		return token, nil
+	})
+}
+
+// WithTokenSource sets a callback that returns the auth token, e.g. to fetch or refresh the token when needed
+func WithTokenSource(tokenSource TokenSource) Option {
+	return func(o *options) {
+		o.tokenSource = tokenSource
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send requests to all APIs, e.g. to share its transport with other
+// clients. The client is copied, so that WithTransport and WithTimeout do not change it.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
+
+// WithTransport sets the transport used to send requests, e.g. to add logging or retries
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.transport = transport
+	}
+}
+
+// WithTimeout sets the time limit for each request, including reading the response body. Exports may take a long
+// time, so it should be generous.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithPageSize sets the number of datasets and folders to request per page when listing, or 0 to let the server
+// decide
+func WithPageSize(pageSize int) Option {
+	return func(o *options) {
+		o.pageSize = pageSize
+	}
+}
+
+// FromEnvironment sets the URLs of the APIs and the auth token from the environment variables EnvDataMaintenanceURL,
+// EnvPseudoServiceURL and EnvAuthToken, if they are set. Plugins of the dapla command are given these variables, so
+// that they call the APIs like the command does.
+func FromEnvironment() Option {
+	return func(o *options) {
+		if url := os.Getenv(EnvDataMaintenanceURL); url != "" {
+			o.dataMaintenanceURL = url
+		}
+		if url := os.Getenv(EnvPseudoServiceURL); url != "" {
+			o.pseudoServiceURL = url
+		}
+		if token := os.Getenv(EnvAuthToken); token != "" {
+			WithToken(token)(o)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package dapla
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/statisticsnorway/dapla-cli/devserver"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingTransport records the auth header of each request before sending it
+type recordingTransport struct {
+	auth []string
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.auth = append(t.auth, req.Header.Get("Authorization"))
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestNew_SharedOptions(t *testing.T) {
+	server := devserver.New(devserver.SeedCatalog())
+	maintenanceServer := httptest.NewServer(server.MaintenanceHandler())
+	defer maintenanceServer.Close()
+	pseudoServer := httptest.NewServer(server.PseudoHandler())
+	defer pseudoServer.Close()
+
+	transport := &recordingTransport{}
+	httpClient := &http.Client{}
+	client := New(
+		WithDataMaintenanceURL(maintenanceServer.URL),
+		WithPseudoServiceURL(pseudoServer.URL),
+		WithHTTPClient(httpClient),
+		WithTransport(transport),
+		WithToken("secret"),
+	)
+	ctx := context.Background()
+
+	_, err := client.Datasets.List(ctx, "/skatt")
+	assert.NoError(t, err)
+	_, err = client.Export.Dataset(ctx, ExportRequest{DatasetPath: "/skatt/person/inntekt", TargetPassword: "secret"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Bearer secret", "Bearer secret"}, transport.auth)
+	// The given HTTP client is not changed
+	assert.Nil(t, httpClient.Transport)
+}
+
+func TestNew_NotConfigured(t *testing.T) {
+	client := New()
+	ctx := context.Background()
+
+	_, err := client.Datasets.List(ctx, "/skatt")
+	assert.Equal(t, ErrNotConfigured, err)
+	_, err = client.Export.Dataset(ctx, ExportRequest{DatasetPath: "/skatt/person/inntekt"})
+	assert.Equal(t, ErrNotConfigured, err)
+	_, err = client.Pseudo.Preview(ctx, ExportRequest{DatasetPath: "/skatt/person/inntekt"})
+	assert.Equal(t, ErrNotConfigured, err)
+}
+
+func TestFromEnvironment(t *testing.T) {
+	for name, value := range map[string]string{
+		EnvDataMaintenanceURL: "http://maintenance",
+		EnvPseudoServiceURL:   "http://pseudo",
+		EnvAuthToken:          "secret",
+	} {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+
+	o := options{}
+	FromEnvironment()(&o)
+	assert.Equal(t, "http://maintenance", o.dataMaintenanceURL)
+	assert.Equal(t, "http://pseudo", o.pseudoServiceURL)
+	token, err := o.tokenSource(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", token)
+}
+
+func TestDatasetsService_Changes(t *testing.T) {
+	server := httptest.NewServer(devserver.New(devserver.SeedCatalog()).MaintenanceHandler())
+	defer server.Close()
+	datasets := New(WithDataMaintenanceURL(server.URL)).Datasets
+	ctx := context.Background()
+
+	versions, err := datasets.Versions(ctx, "/raw/skatt/hendelser")
+	assert.NoError(t, err)
+	deleted, err := datasets.DeleteVersions(ctx, "/raw/skatt/hendelser", versions.Timestamps()[:1], false)
+	assert.NoError(t, err)
+	assert.Equal(t, versions.Timestamps()[:1], deleted.Timestamps())
+	assert.Equal(t, len(versions.Versions[0].Files), deleted.Files())
+
+	trashed, err := datasets.Trash(ctx, "/skatt/person/formue", time.Hour, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "/skatt/person/formue", trashed.DatasetPath)
+	inTrash, err := datasets.ListTrash(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []Trashed{*trashed}, inTrash)
+
+	moved, err := datasets.Restore(ctx, "/skatt/person/formue", "", ConflictFail)
+	assert.NoError(t, err)
+	assert.Equal(t, "/skatt/person/formue", moved.Destination)
+
+	_, err = datasets.Move(ctx, "/skatt/person/formue", "/skatt/person/inntekt", ConflictFail, false)
+	var apiErr *Error
+	assert.True(t, errors.As(err, &apiErr), "expected an *Error, got %v", err)
+}
+
+func TestDatasetsService_WalkSkipFolder(t *testing.T) {
+	server := httptest.NewServer(devserver.New(devserver.SeedCatalog()).MaintenanceHandler())
+	defer server.Close()
+	datasets := New(WithDataMaintenanceURL(server.URL)).Datasets
+
+	// SkipFolder skips the rest of the folder holding a dataset, and the walk goes on after it
+	var visited []string
+	err := datasets.Walk(context.Background(), "/skatt", func(dataset Dataset) error {
+		visited = append(visited, dataset.Path)
+		if dataset.Path == "/skatt/2020/inntekt" || dataset.Path == "/skatt/person/formue" {
+			return SkipFolder
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/skatt/2020", "/skatt/2020/inntekt", "/skatt/person", "/skatt/person/formue"}, visited)
+
+	// Skipping the rest of the root ends the walk
+	visited = nil
+	err = datasets.Walk(context.Background(), "/skatt/person", func(dataset Dataset) error {
+		visited = append(visited, dataset.Path)
+		return SkipFolder
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/skatt/person/formue"}, visited)
+}
+
+func TestPseudoService_Funcs(t *testing.T) {
+	funcs := New().Pseudo.Funcs()
+	assert.Contains(t, funcs, "fpe-fnr")
+
+	delete(funcs, "fpe-fnr")
+	assert.Contains(t, New().Pseudo.Funcs(), "fpe-fnr")
+}
@@ -0,0 +1,544 @@
+package dapla
+
+import (
+	"context"
+	"errors"
+	"path"
+	"time"
+
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+)
+
+// Dataset is a dataset or folder in a listing. Folders have a Depth greater than 0, see IsFolder.
+type Dataset struct {
+	Path      string    `json:"path"`
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdDate"`
+	Type      string    `json:"type"`
+	Valuation string    `json:"valuation"`
+	State     string    `json:"state"`
+	Depth     int       `json:"depth"`
+}
+
+// IsFolder returns true iff the dataset is a folder
+func (d Dataset) IsFolder() bool {
+	return d.Depth > 0
+}
+
+// IsDataset returns true iff the dataset is a dataset, not a folder
+func (d Dataset) IsDataset() bool {
+	return !d.IsFolder()
+}
+
+// DatasetInfo holds the metadata the data-maintenance service keeps about a dataset
+type DatasetInfo struct {
+	Path        string          `json:"path"`
+	CreatedBy   string          `json:"createdBy"`
+	CreatedAt   time.Time       `json:"createdDate"`
+	Type        string          `json:"type"`
+	Valuation   string          `json:"valuation"`
+	State       string          `json:"state"`
+	PseudoRules []PseudoRule    `json:"pseudoRules"`
+	Lineage     []LineageSource `json:"lineage"`
+}
+
+// LineageSource points to a version of a dataset that another dataset was derived from
+type LineageSource struct {
+	DatasetPath string    `json:"datasetPath"`
+	Version     time.Time `json:"version"`
+}
+
+// Schema holds the fields of a dataset
+type Schema struct {
+	DatasetPath string        `json:"datasetPath"`
+	Fields      []SchemaField `json:"fields"`
+}
+
+// FieldPaths returns the paths of all fields in the schema
+func (s Schema) FieldPaths() []string {
+	paths := make([]string, 0, len(s.Fields))
+	for _, field := range s.Fields {
+		paths = append(paths, field.Path)
+	}
+	return paths
+}
+
+// SchemaField is a single (possibly nested) field of a dataset, with a slash separated path
+type SchemaField struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+}
+
+// Versions holds all versions of a dataset
+type Versions struct {
+	DatasetPath string    `json:"datasetPath"`
+	Versions    []Version `json:"versions"`
+}
+
+// Timestamps returns the timestamps of all versions
+func (v Versions) Timestamps() []time.Time {
+	timestamps := make([]time.Time, 0, len(v.Versions))
+	for _, version := range v.Versions {
+		timestamps = append(timestamps, version.Timestamp)
+	}
+	return timestamps
+}
+
+// TotalSize returns the total size of all versions
+func (v Versions) TotalSize() uint64 {
+	var size uint64
+	for _, version := range v.Versions {
+		size += version.Size()
+	}
+	return size
+}
+
+// Version returns the version with the given timestamp, or nil if there is no such version
+func (v Versions) Version(timestamp time.Time) *Version {
+	for i := range v.Versions {
+		if v.Versions[i].Timestamp.Equal(timestamp) {
+			return &v.Versions[i]
+		}
+	}
+	return nil
+}
+
+// Version holds the files of a version of a dataset
+type Version struct {
+	Timestamp time.Time `json:"timestamp"`
+	Files     []File    `json:"files"`
+}
+
+// Size returns the total size of the files of the version
+func (v Version) Size() uint64 {
+	return totalSize(v.Files)
+}
+
+// File is a file of a version of a dataset
+type File struct {
+	URI  string `json:"uri"`
+	Size uint64 `json:"size"`
+}
+
+// Name returns the name of the file, i.e. the last element of its URI
+func (f File) Name() string {
+	return path.Base(f.URI)
+}
+
+// SearchQuery holds the parameters of a dataset search
+type SearchQuery struct {
+	// Text is a free-text query matched against the dataset paths (and whatever else the service indexes)
+	Text string
+	// Path restricts the search to datasets and folders under this path
+	Path string
+	// Filters restricts the search to datasets and folders with the given field values: type (dataset or folder),
+	// valuation, state or createdBy
+	Filters map[string]string
+	// Limit is the maximum number of results to return in one page, or 0 to let the service decide
+	Limit int
+	// PageToken is the NextPageToken of the previous page, or empty for the first page
+	PageToken string
+}
+
+// SearchResults holds a page of search results, ordered by descending score
+type SearchResults struct {
+	Results       []SearchResult `json:"results"`
+	Total         int            `json:"total"`
+	NextPageToken string         `json:"nextPageToken"`
+}
+
+// SearchResult is a single dataset or folder matching a search, along with its relevance score
+type SearchResult struct {
+	Dataset
+	Score float64 `json:"score"`
+}
+
+// Deleted describes the versions and files of a dataset that were deleted, or would be deleted in a dry run
+type Deleted struct {
+	DatasetPath string           `json:"datasetPath"`
+	TotalSize   uint64           `json:"totalSize"`
+	Versions    []DeletedVersion `json:"versions"`
+}
+
+// Files returns the number of deleted files
+func (d Deleted) Files() int {
+	files := 0
+	for _, version := range d.Versions {
+		files += len(version.Files)
+	}
+	return files
+}
+
+// Timestamps returns the timestamps of the deleted versions
+func (d Deleted) Timestamps() []time.Time {
+	timestamps := make([]time.Time, 0, len(d.Versions))
+	for _, version := range d.Versions {
+		timestamps = append(timestamps, version.Timestamp)
+	}
+	return timestamps
+}
+
+// DeletedVersion holds the deleted files of a version of a dataset
+type DeletedVersion struct {
+	Timestamp time.Time `json:"timestamp"`
+	Files     []File    `json:"files"`
+}
+
+// Size returns the total size of the deleted files of the version
+func (v DeletedVersion) Size() uint64 {
+	return totalSize(v.Files)
+}
+
+// Moved describes a dataset that was moved or restored
+type Moved struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	// Status is either moved or skipped (if the destination exists and the conflict policy is ConflictSkip)
+	Status string `json:"status"`
+}
+
+// Copied summarizes a copied dataset
+type Copied struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Files       int    `json:"files"`
+	Size        uint64 `json:"size"`
+}
+
+// CopyProgress describes a single file copied by DatasetsService.Copy
+type CopyProgress struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Size        uint64 `json:"size"`
+}
+
+// Trashed describes a dataset in the trash of the user
+type Trashed struct {
+	DatasetPath string    `json:"datasetPath"`
+	TrashedAt   time.Time `json:"trashedAt"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	Versions    int       `json:"versions"`
+	TotalSize   uint64    `json:"totalSize"`
+}
+
+// ConflictPolicy decides what happens when the destination of a move, copy or restore already exists
+type ConflictPolicy string
+
+// Supported conflict policies
+const (
+	ConflictFail      ConflictPolicy = "fail"
+	ConflictSkip      ConflictPolicy = "skip"
+	ConflictOverwrite ConflictPolicy = "overwrite"
+)
+
+// SkipFolder can be returned by the function passed to DatasetsService.Walk to skip the contents of a folder
+var SkipFolder = errors.New("skip this folder")
+
+// DatasetsService manages the datasets in the data-maintenance service. Paths are absolute, e.g. /skatt/person.
+type DatasetsService struct {
+	api maintenance.API
+}
+
+// client returns the data-maintenance client, or ErrNotConfigured if the service has no URL
+func (s *DatasetsService) client() (maintenance.API, error) {
+	if s.api == nil {
+		return nil, ErrNotConfigured
+	}
+	return s.api, nil
+}
+
+// List returns the datasets and folders directly under path
+func (s *DatasetsService) List(ctx context.Context, path string) ([]Dataset, error) {
+	var datasets []Dataset
+	err := s.Iterate(ctx, path, func(dataset Dataset) error {
+		datasets = append(datasets, dataset)
+		return nil
+	})
+	return datasets, err
+}
+
+// Iterate calls fn for each dataset and folder directly under path as they are received, following paginated
+// responses. If fn returns an error, the iteration stops and the error is returned.
+func (s *DatasetsService) Iterate(ctx context.Context, path string, fn func(dataset Dataset) error) error {
+	api, err := s.client()
+	if err != nil {
+		return err
+	}
+	return convertError(api.IterateDatasets(ctx, path, func(element maintenance.ListDatasetElement) error {
+		return fn(Dataset(element))
+	}))
+}
+
+// Walk calls fn for each dataset and folder in the tree under root, visiting folders before their contents. If fn
+// returns SkipFolder for a folder, its contents are skipped. If fn returns SkipFolder for a dataset, the rest of the
+// folder holding it is skipped, like filepath.SkipDir. Any other error stops the walk and is returned.
+func (s *DatasetsService) Walk(ctx context.Context, root string, fn func(dataset Dataset) error) error {
+	api, err := s.client()
+	if err != nil {
+		return err
+	}
+	// skipped is the folder whose remaining contents are skipped, since fn returned SkipFolder for a dataset in it
+	skipped := ""
+	err = maintenance.WalkDatasets(ctx, api, root, func(element maintenance.ListDatasetElement) error {
+		if path.Dir(element.Path) == skipped {
+			if element.IsFolder() {
+				return maintenance.SkipFolder
+			}
+			return nil
+		}
+		err := fn(Dataset(element))
+		if err != SkipFolder {
+			return err
+		}
+		if element.IsDataset() {
+			skipped = path.Dir(element.Path)
+			return nil
+		}
+		return maintenance.SkipFolder
+	})
+	if err == maintenance.SkipFolder {
+		return SkipFolder
+	}
+	return convertError(err)
+}
+
+// Search searches for datasets and folders. If the service does not support searching, the tree under the query path
+// is walked instead, matching the terms of the query text against the paths, and all results are returned in one
+// page.
+func (s *DatasetsService) Search(ctx context.Context, query SearchQuery) (*SearchResults, error) {
+	api, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+	res, err := api.SearchDatasets(ctx, maintenance.SearchQuery(query))
+	if maintenance.IsSearchUnavailable(err) {
+		res, err = maintenance.WalkSearch(ctx, api, maintenance.SearchQuery(query))
+	}
+	if err != nil {
+		return nil, convertError(err)
+	}
+	results := &SearchResults{Total: res.Total, NextPageToken: res.NextPageToken}
+	for _, result := range res.Results {
+		results.Results = append(results.Results, SearchResult{Dataset: Dataset(result.ListDatasetElement), Score: result.Score})
+	}
+	return results, nil
+}
+
+// Info returns the metadata of the dataset at path, including its pseudo rules and lineage
+func (s *DatasetsService) Info(ctx context.Context, path string) (*DatasetInfo, error) {
+	api, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+	res, err := api.GetDatasetInfo(ctx, path)
+	if err != nil {
+		return nil, convertError(err)
+	}
+	info := &DatasetInfo{
+		Path:        res.Path,
+		CreatedBy:   res.CreatedBy,
+		CreatedAt:   res.CreatedAt,
+		Type:        res.Type,
+		Valuation:   res.Valuation,
+		State:       res.State,
+		PseudoRules: newPseudoRules(res.PseudoRules),
+	}
+	for _, source := range res.Lineage {
+		info.Lineage = append(info.Lineage, LineageSource(source))
+	}
+	return info, nil
+}
+
+// Schema returns the fields of the dataset at path
+func (s *DatasetsService) Schema(ctx context.Context, path string) (*Schema, error) {
+	api, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+	res, err := api.GetDatasetSchema(ctx, path)
+	if err != nil {
+		return nil, convertError(err)
+	}
+	schema := &Schema{DatasetPath: res.DatasetPath}
+	for _, field := range res.Fields {
+		schema.Fields = append(schema.Fields, SchemaField(field))
+	}
+	return schema, nil
+}
+
+// Versions returns the versions of the dataset at path, and their files
+func (s *DatasetsService) Versions(ctx context.Context, path string) (*Versions, error) {
+	api, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+	res, err := api.ListVersions(ctx, path)
+	if err != nil {
+		return nil, convertError(err)
+	}
+	versions := &Versions{DatasetPath: res.DatasetPath}
+	for _, version := range res.Versions {
+		versions.Versions = append(versions.Versions, Version{Timestamp: version.Timestamp, Files: newFiles(version.Files)})
+	}
+	return versions, nil
+}
+
+// Delete deletes the dataset at path with all its versions. If dryRun is true, nothing is deleted, but the response
+// describes what would be.
+func (s *DatasetsService) Delete(ctx context.Context, path string, dryRun bool) (*Deleted, error) {
+	api, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+	return newDeleted(api.DeleteDatasets(ctx, path, dryRun))
+}
+
+// DeleteVersions deletes the versions with the given timestamps of the dataset at path
+func (s *DatasetsService) DeleteVersions(ctx context.Context, path string, timestamps []time.Time, dryRun bool) (*Deleted, error) {
+	api, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+	return newDeleted(api.DeleteDatasetVersions(ctx, path, timestamps, dryRun))
+}
+
+// Move moves the dataset at src to dst. The conflict policy decides what happens if dst exists.
+func (s *DatasetsService) Move(ctx context.Context, src, dst string, conflict ConflictPolicy, dryRun bool) (*Moved, error) {
+	api, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+	return newMoved(api.MoveDataset(ctx, src, dst, maintenance.ConflictPolicy(conflict), dryRun))
+}
+
+// Copy copies the dataset at src to dst. Only the versions with the given timestamps are copied, or all versions if
+// there are none. If progress is not nil, it is called for each copied file.
+func (s *DatasetsService) Copy(ctx context.Context, src, dst string, versions []time.Time, progress func(CopyProgress)) (*Copied, error) {
+	api, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+	var copyProgress func(maintenance.CopyProgress)
+	if progress != nil {
+		copyProgress = func(file maintenance.CopyProgress) {
+			progress(CopyProgress(file))
+		}
+	}
+	res, err := api.CopyDataset(ctx, src, dst, versions, copyProgress)
+	if err != nil {
+		return nil, convertError(err)
+	}
+	copied := Copied(*res)
+	return &copied, nil
+}
+
+// Trash moves the dataset at path to the trash of the user. It is deleted for good when expiry has passed, unless it
+// is restored before then.
+func (s *DatasetsService) Trash(ctx context.Context, path string, expiry time.Duration, dryRun bool) (*Trashed, error) {
+	api, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+	res, err := api.TrashDataset(ctx, path, expiry, dryRun)
+	if err != nil {
+		return nil, convertError(err)
+	}
+	trashed := Trashed(*res)
+	return &trashed, nil
+}
+
+// ListTrash returns the datasets in the trash of the user
+func (s *DatasetsService) ListTrash(ctx context.Context) ([]Trashed, error) {
+	api, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+	res, err := api.ListTrash(ctx)
+	if err != nil {
+		return nil, convertError(err)
+	}
+	trashed := make([]Trashed, 0, len(res))
+	for _, dataset := range res {
+		trashed = append(trashed, Trashed(dataset))
+	}
+	return trashed, nil
+}
+
+// Restore moves the trashed dataset at path back to dst, or to path if dst is empty
+func (s *DatasetsService) Restore(ctx context.Context, path, dst string, conflict ConflictPolicy) (*Moved, error) {
+	api, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+	return newMoved(api.RestoreDataset(ctx, path, dst, maintenance.ConflictPolicy(conflict)))
+}
+
+// EmptyTrash deletes the trashed datasets under path for good, or the whole trash if path is empty
+func (s *DatasetsService) EmptyTrash(ctx context.Context, path string, dryRun bool) ([]Deleted, error) {
+	api, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+	res, err := api.EmptyTrash(ctx, path, dryRun)
+	if err != nil {
+		return nil, convertError(err)
+	}
+	deleted := make([]Deleted, 0, len(res))
+	for i := range res {
+		dataset, _ := newDeleted(&res[i], nil)
+		deleted = append(deleted, *dataset)
+	}
+	return deleted, nil
+}
+
+// ProtectedPaths returns the glob patterns of the dataset paths that should not be deleted, moved or trashed. The
+// dapla command refuses to touch them, and other clients should do the same.
+func (s *DatasetsService) ProtectedPaths(ctx context.Context) ([]string, error) {
+	api, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := api.ListProtectedPaths(ctx)
+	return paths, convertError(err)
+}
+
+// newDeleted converts the response and error of a deletion by the data-maintenance client. The response is kept
+// along with the error, since a deletion may fail after deleting some of the versions.
+func newDeleted(res *maintenance.DeleteDatasetResponse, err error) (*Deleted, error) {
+	if res == nil {
+		return nil, convertError(err)
+	}
+	deleted := &Deleted{DatasetPath: res.DatasetPath, TotalSize: res.TotalSize}
+	for _, version := range res.DatasetVersion {
+		deleted.Versions = append(deleted.Versions, DeletedVersion{
+			Timestamp: version.Timestamp,
+			Files:     newFiles(version.DeletedFiles),
+		})
+	}
+	return deleted, convertError(err)
+}
+
+// newMoved converts the response and error of a move or restore by the data-maintenance client
+func newMoved(res *maintenance.MoveDatasetResponse, err error) (*Moved, error) {
+	if err != nil {
+		return nil, convertError(err)
+	}
+	moved := Moved(*res)
+	return &moved, nil
+}
+
+func newFiles(files []maintenance.DatasetFile) []File {
+	converted := make([]File, 0, len(files))
+	for _, file := range files {
+		converted = append(converted, File(file))
+	}
+	return converted
+}
+
+func totalSize(files []File) uint64 {
+	var size uint64
+	for _, file := range files {
+		size += file.Size
+	}
+	return size
+}
@@ -0,0 +1,25 @@
+// Package dapla is a Go client for the Dapla APIs used by the dapla command: the data-maintenance service, which
+// manages datasets, and the dapla-pseudo-service, which exports and pseudonymizes them. Services written in Go can use
+// it to call the APIs the same way as the command does.
+//
+// A Client is created with New, and groups the APIs in services, which share the auth token and HTTP client:
+//
+//	client := dapla.New(
+//		dapla.WithDataMaintenanceURL("https://data-maintenance.staging-bip-app.ssb.no"),
+//		dapla.WithPseudoServiceURL("https://dapla-pseudo-service.staging-bip-app.ssb.no"),
+//		dapla.WithToken(token),
+//	)
+//	datasets, err := client.Datasets.List(ctx, "/skatt")
+//
+// Errors reported by the APIs are returned as *Error, holding the HTTP status code and the message of the response.
+//
+// Plugins of the dapla command can create a client from the configuration they are given with FromEnvironment.
+//
+// # Stability
+//
+// This package is versioned with the module of the dapla command, which has no v1 release yet. Until it does, the
+// package is not stable: like any v0 Go module, a release may change it in incompatible ways, and such changes are
+// listed in the changelog of the command. From v1 on, it follows semantic versioning. Its types are defined here,
+// rather than in the other packages of the module, such as maintenance, export and cmd, which implement the command
+// and may change in any release, even after v1; import this package instead.
+package dapla
@@ -0,0 +1,213 @@
+package dapla_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/statisticsnorway/dapla-cli/devserver"
+	"github.com/statisticsnorway/dapla-cli/pkg/dapla"
+)
+
+// startServices starts the fake services of dapla dev serve, which the examples run against, and returns their URLs
+func startServices() (maintenanceURL, pseudoURL string, stop func()) {
+	server := devserver.New(devserver.SeedCatalog())
+	maintenanceServer := httptest.NewServer(server.MaintenanceHandler())
+	pseudoServer := httptest.NewServer(server.PseudoHandler())
+	return maintenanceServer.URL, pseudoServer.URL, func() {
+		maintenanceServer.Close()
+		pseudoServer.Close()
+	}
+}
+
+func Example() {
+	maintenanceURL, pseudoURL, stop := startServices()
+	defer stop()
+
+	client := dapla.New(
+		dapla.WithDataMaintenanceURL(maintenanceURL),
+		dapla.WithPseudoServiceURL(pseudoURL),
+		dapla.WithToken("my-token"),
+		dapla.WithTimeout(time.Minute),
+	)
+
+	datasets, err := client.Datasets.List(context.Background(), "/skatt/person")
+	if err != nil {
+		panic(err)
+	}
+	for _, dataset := range datasets {
+		fmt.Println(dataset.Path, dataset.Valuation)
+	}
+	// Output:
+	// /skatt/person/formue SENSITIVE
+	// /skatt/person/inntekt SENSITIVE
+}
+
+func ExampleFromEnvironment() {
+	// In a plugin of the dapla command, the URLs and the auth token are given in the environment
+	client := dapla.New(dapla.FromEnvironment())
+
+	datasets, err := client.Datasets.List(context.Background(), "/skatt")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(len(datasets))
+}
+
+func ExampleWithTransport() {
+	maintenanceURL, _, stop := startServices()
+	defer stop()
+
+	client := dapla.New(
+		dapla.WithDataMaintenanceURL(maintenanceURL),
+		dapla.WithTransport(logTransport{http.DefaultTransport}),
+	)
+	if _, err := client.Datasets.Info(context.Background(), "/skatt/person/inntekt"); err != nil {
+		panic(err)
+	}
+	// Output:
+	// GET 200 OK
+}
+
+// logTransport prints the requests sent with it
+type logTransport struct {
+	next http.RoundTripper
+}
+
+func (t logTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(req)
+	if err == nil {
+		fmt.Println(req.Method, res.Status)
+	}
+	return res, err
+}
+
+func ExampleError() {
+	maintenanceURL, _, stop := startServices()
+	defer stop()
+	client := dapla.New(dapla.WithDataMaintenanceURL(maintenanceURL))
+
+	_, err := client.Datasets.Info(context.Background(), "/skatt/nope")
+	var apiErr *dapla.Error
+	if errors.As(err, &apiErr) {
+		fmt.Println(apiErr.StatusCode(), apiErr.Message())
+	}
+	// Output:
+	// 404 dataset /skatt/nope not found
+}
+
+func ExampleDatasetsService_Walk() {
+	maintenanceURL, _, stop := startServices()
+	defer stop()
+	client := dapla.New(dapla.WithDataMaintenanceURL(maintenanceURL))
+
+	err := client.Datasets.Walk(context.Background(), "/", func(dataset dapla.Dataset) error {
+		if dataset.IsFolder() && dataset.Path == "/produkt" {
+			return dapla.SkipFolder
+		}
+		if dataset.IsDataset() {
+			fmt.Println(dataset.Path)
+		}
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+	// Output:
+	// /raw/skatt/hendelser
+	// /skatt/2020/inntekt
+	// /skatt/person/formue
+	// /skatt/person/inntekt
+}
+
+func ExampleDatasetsService_Search() {
+	maintenanceURL, _, stop := startServices()
+	defer stop()
+	client := dapla.New(dapla.WithDataMaintenanceURL(maintenanceURL))
+
+	results, err := client.Datasets.Search(context.Background(), dapla.SearchQuery{
+		Text:    "inntekt",
+		Path:    "/skatt",
+		Filters: map[string]string{"type": "dataset"},
+	})
+	if err != nil {
+		panic(err)
+	}
+	for _, result := range results.Results {
+		fmt.Println(result.Path)
+	}
+	// Output:
+	// /skatt/2020/inntekt
+	// /skatt/person/inntekt
+}
+
+func ExampleDatasetsService_Versions() {
+	maintenanceURL, _, stop := startServices()
+	defer stop()
+	client := dapla.New(dapla.WithDataMaintenanceURL(maintenanceURL))
+
+	versions, err := client.Datasets.Versions(context.Background(), "/skatt/person/inntekt")
+	if err != nil {
+		panic(err)
+	}
+	for _, version := range versions.Versions {
+		fmt.Println(version.Timestamp.Format(time.RFC3339), len(version.Files), version.Size())
+	}
+	// Output:
+	// 2021-01-04T08:00:00Z 2 1572864
+	// 2021-02-01T08:00:00Z 2 1835008
+	// 2021-03-01T08:00:00Z 3 1900544
+}
+
+func ExampleExportService_Dataset() {
+	maintenanceURL, pseudoURL, stop := startServices()
+	defer stop()
+	client := dapla.New(
+		dapla.WithDataMaintenanceURL(maintenanceURL),
+		dapla.WithPseudoServiceURL(pseudoURL),
+	)
+	ctx := context.Background()
+
+	rules, err := client.Pseudo.Rules(ctx, "/skatt/person/inntekt")
+	if err != nil {
+		panic(err)
+	}
+	res, err := client.Export.Dataset(ctx, dapla.ExportRequest{
+		DatasetPath:       "/skatt/person/inntekt",
+		ColumnSelectors:   []string{"person/**", "inntekt"},
+		TargetContentName: "inntekt",
+		TargetContentType: "application/json",
+		TargetPassword:    "kensentme",
+		PseudoRules:       rules,
+	})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(res.TargetURI)
+	// Output:
+	// gs://dapla-dev-export/export/skatt/person/inntekt/20210301-inntekt.zip
+}
+
+func ExamplePseudoService_Preview() {
+	maintenanceURL, _, stop := startServices()
+	defer stop()
+	client := dapla.New(dapla.WithDataMaintenanceURL(maintenanceURL))
+
+	preview, err := client.Pseudo.Preview(context.Background(), dapla.ExportRequest{
+		DatasetPath:            "/skatt/person/formue",
+		PseudoRulesDatasetPath: "/skatt/person/inntekt",
+	})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(preview.Columns)
+	for _, rule := range preview.PseudoRules {
+		fmt.Println(rule.Pattern, rule.Func, rule.Fields)
+	}
+	// Output:
+	// [person/fnr formue]
+	// **/fnr fpe-fnr(secret1) [person/fnr]
+}
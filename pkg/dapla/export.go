@@ -0,0 +1,66 @@
+package dapla
+
+import (
+	"context"
+	"time"
+
+	"github.com/statisticsnorway/dapla-cli/export"
+)
+
+// ExportRequest holds the parameters of an export: the dataset (and optionally the version) to export, the columns
+// to include, the name, type and password of the exported archive, and how to pseudonymize or depseudonymize the data
+type ExportRequest struct {
+	DatasetPath            string       `json:"datasetPath"`
+	DatasetTimestamp       *time.Time   `json:"datasetTimestamp,omitempty"`
+	ColumnSelectors        []string     `json:"columnSelectors"`
+	TargetContentName      string       `json:"targetContentName"`
+	TargetContentType      string       `json:"targetContentType"`
+	TargetPassword         string       `json:"targetPassword"`
+	Depseudonymize         bool         `json:"depseudonymize"`
+	PseudoRules            []PseudoRule `json:"pseudoRules"`
+	PseudoRulesDatasetPath string       `json:"pseudoRulesDatasetPath"`
+}
+
+// request converts the request to a request of the export client
+func (r ExportRequest) request() export.Request {
+	req := export.Request{
+		DatasetPath:            r.DatasetPath,
+		DatasetTimestamp:       r.DatasetTimestamp,
+		ColumnSelectors:        r.ColumnSelectors,
+		TargetContentName:      r.TargetContentName,
+		TargetContentType:      r.TargetContentType,
+		TargetPassword:         r.TargetPassword,
+		Depseudonymize:         r.Depseudonymize,
+		PseudoRulesDatasetPath: r.PseudoRulesDatasetPath,
+	}
+	if r.PseudoRules != nil {
+		req.PseudoRules = make([]export.PseudoRule, 0, len(r.PseudoRules))
+		for _, rule := range r.PseudoRules {
+			req.PseudoRules = append(req.PseudoRules, export.PseudoRule(rule))
+		}
+	}
+	return req
+}
+
+// ExportResponse holds the URI of the exported archive
+type ExportResponse struct {
+	TargetURI string `json:"targetUri"`
+}
+
+// ExportService exports datasets with the dapla-pseudo-service
+type ExportService struct {
+	client *export.Client
+}
+
+// Dataset exports a dataset as described by req, and returns the URI of the archive once it is written. Exports may
+// take a long time, and are stopped if ctx is done.
+func (s *ExportService) Dataset(ctx context.Context, req ExportRequest) (*ExportResponse, error) {
+	if s.client == nil {
+		return nil, ErrNotConfigured
+	}
+	res, err := s.client.Export(ctx, req.request())
+	if err != nil {
+		return nil, convertError(err)
+	}
+	return &ExportResponse{TargetURI: res.TargetURI}, nil
+}
@@ -0,0 +1,109 @@
+package dapla
+
+import (
+	"context"
+	"errors"
+
+	"github.com/statisticsnorway/dapla-cli/api"
+	"github.com/statisticsnorway/dapla-cli/export"
+)
+
+// PseudoRule describes how the fields matching a glob pattern are pseudonymized, e.g. the pattern **/fnr with the
+// func fpe-fnr(secret1)
+type PseudoRule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	Func    string `json:"func"`
+}
+
+// Preview describes which fields an export would include, and which of them each pseudo rule would match
+type Preview struct {
+	Fields      []string
+	Columns     []string
+	Selectors   []PatternMatch
+	PseudoRules []PatternMatch
+}
+
+// PatternMatch holds the fields matched by a column selector or pseudo rule in a Preview
+type PatternMatch struct {
+	Pattern string
+	Func    string
+	Fields  []string
+}
+
+// IsEmpty returns true iff the pattern did not match any fields
+func (m PatternMatch) IsEmpty() bool {
+	return len(m.Fields) == 0
+}
+
+// PseudoService describes and previews the pseudonymization of datasets. It uses the data-maintenance service.
+type PseudoService struct {
+	datasets *DatasetsService
+}
+
+// Funcs returns descriptions of the pseudo functions supported by the dapla-pseudo-service, by name. The functions
+// take the ID of the key to use, e.g. fpe-fnr(secret1).
+func (s *PseudoService) Funcs() map[string]string {
+	funcs := make(map[string]string, len(export.PseudoFuncs))
+	for name, description := range export.PseudoFuncs {
+		funcs[name] = description
+	}
+	return funcs
+}
+
+// Rules returns the pseudo rules of the dataset at path, which can be used in export requests
+func (s *PseudoService) Rules(ctx context.Context, path string) ([]PseudoRule, error) {
+	info, err := s.datasets.Info(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return info.PseudoRules, nil
+}
+
+// Preview matches the column selectors and pseudo rules of req against the schema of the dataset to export, without
+// exporting anything. If req has a PseudoRulesDatasetPath, the rules of that dataset are used, like the export does.
+// Only the latest version can be previewed, so req must not have a DatasetTimestamp.
+func (s *PseudoService) Preview(ctx context.Context, req ExportRequest) (*Preview, error) {
+	if req.DatasetTimestamp != nil {
+		return nil, errors.New("cannot preview a specific version of a dataset")
+	}
+	schema, err := s.datasets.Schema(ctx, req.DatasetPath)
+	if err != nil {
+		return nil, err
+	}
+	if req.PseudoRulesDatasetPath != "" {
+		if req.PseudoRules, err = s.Rules(ctx, req.PseudoRulesDatasetPath); err != nil {
+			return nil, err
+		}
+	}
+	preview := export.NewPreview(req.request(), schema.FieldPaths())
+	return &Preview{
+		Fields:      preview.Fields,
+		Columns:     preview.Columns,
+		Selectors:   newPatternMatches(preview.Selectors),
+		PseudoRules: newPatternMatches(preview.PseudoRules),
+	}, nil
+}
+
+// newPseudoRules converts the pseudo rules of the data-maintenance and export clients
+func newPseudoRules(rules []api.PseudoRule) []PseudoRule {
+	if rules == nil {
+		return nil
+	}
+	converted := make([]PseudoRule, 0, len(rules))
+	for _, rule := range rules {
+		converted = append(converted, PseudoRule(rule))
+	}
+	return converted
+}
+
+func newPatternMatches(matches []export.PatternMatch) []PatternMatch {
+	if matches == nil {
+		return nil
+	}
+	converted := make([]PatternMatch, 0, len(matches))
+	for _, match := range matches {
+		converted = append(converted, PatternMatch(match))
+	}
+	return converted
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+// +build !windows
+
+package plugin
+
+import (
+	"os"
+)
+
+// isExecutable returns true if file can be executed by anyone
+func isExecutable(file os.FileInfo) bool {
+	return file.Mode()&0111 != 0
+}
+
+// trimExecutableExt returns name, since executables have no extension outside Windows
+func trimExecutableExt(name string) string {
+	return name
+}
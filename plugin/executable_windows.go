@@ -0,0 +1,26 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// executableExts are the extensions of executables on Windows
+var executableExts = []string{".exe", ".bat", ".cmd", ".com"}
+
+// isExecutable returns true if file has the extension of an executable
+func isExecutable(file os.FileInfo) bool {
+	ext := strings.ToLower(filepath.Ext(file.Name()))
+	for _, executableExt := range executableExts {
+		if ext == executableExt {
+			return true
+		}
+	}
+	return false
+}
+
+// trimExecutableExt removes the extension of an executable from name, e.g. dapla-foo.exe becomes dapla-foo
+func trimExecutableExt(name string) string {
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
@@ -0,0 +1,183 @@
+// Package plugin implements plugins of the dapla command, in the style of git and kubectl: any executable named
+// dapla-NAME on the PATH is run as the dapla NAME command. Plugins receive the resolved configuration of the dapla
+// command in environment variables (see Context), so that they can call the APIs as the user without being
+// configured separately. Their standard input is left to them.
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Prefix is the prefix of the names of plugin executables
+const Prefix = "dapla-"
+
+// Plugin is an executable that is run as a dapla command
+type Plugin struct {
+	// Name is the name of the command, e.g. foo for the dapla-foo executable
+	Name string
+	// Path is the path of the executable
+	Path string
+	// Shadowed lists the paths of other executables with the same name, which are not run since they come later
+	// on the PATH
+	Shadowed []string
+}
+
+// Find returns the plugins in the directories of pathList, which is a list of directories like $PATH, sorted by name.
+// If several directories hold a plugin with the same name, the first one is used. Directories that can not be read
+// are skipped.
+func Find(pathList string) []Plugin {
+	plugins := map[string]*Plugin{}
+	for _, dir := range filepath.SplitList(pathList) {
+		if dir == "" {
+			continue
+		}
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			name, ok := pluginName(file)
+			if !ok {
+				continue
+			}
+			path := filepath.Join(dir, file.Name())
+			if plugin, ok := plugins[name]; ok {
+				plugin.Shadowed = append(plugin.Shadowed, path)
+				continue
+			}
+			plugins[name] = &Plugin{Name: name, Path: path}
+		}
+	}
+
+	found := make([]Plugin, 0, len(plugins))
+	for _, plugin := range plugins {
+		found = append(found, *plugin)
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].Name < found[j].Name
+	})
+	return found
+}
+
+// pluginName returns the name of the plugin in file, and false if file is not a plugin
+func pluginName(file os.FileInfo) (string, bool) {
+	if file.IsDir() || !strings.HasPrefix(file.Name(), Prefix) || !isExecutable(file) {
+		return "", false
+	}
+	name := strings.TrimPrefix(trimExecutableExt(file.Name()), Prefix)
+	return name, name != ""
+}
+
+// Context is the configuration of the dapla command passed on to plugins
+type Context struct {
+	// APIs holds the URLs of the APIs, by name, e.g. data-maintenance
+	APIs map[string]string `json:"apis"`
+	// AuthToken is the auth token of the user, if one could be retrieved
+	AuthToken string `json:"authToken,omitempty"`
+	// WorkingDir is the folder that relative dataset paths are resolved against: the working folder of the dapla
+	// shell, or the home folder. Empty if there is none.
+	WorkingDir string `json:"workingDir,omitempty"`
+	// Debug is true if debug information should be printed
+	Debug bool `json:"debug"`
+	// Version is the version of the dapla command
+	Version string `json:"version"`
+}
+
+// Environ returns the environment variables passing the context to a plugin. DAPLA_CONTEXT holds the whole context
+// as JSON, and the fields are also given one by one:
+//
+//	DAPLA_API_<NAME>   the URL of each API, with the name in upper case and - replaced by _,
+//	                   e.g. DAPLA_API_DATA_MAINTENANCE
+//	DAPLA_AUTHTOKEN    the auth token, if one could be retrieved
+//	DAPLA_WORKING_DIR  the folder that relative dataset paths are resolved against, if any
+//	DAPLA_DEBUG        true or false
+//	DAPLA_VERSION      the version of the dapla command
+func (c Context) Environ() []string {
+	data, _ := json.Marshal(c)
+	environ := []string{"DAPLA_CONTEXT=" + string(data)}
+
+	names := make([]string, 0, len(c.APIs))
+	for name := range c.APIs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		variable := "DAPLA_API_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		environ = append(environ, variable+"="+c.APIs[name])
+	}
+	if c.AuthToken != "" {
+		environ = append(environ, "DAPLA_AUTHTOKEN="+c.AuthToken)
+	}
+	if c.WorkingDir != "" {
+		environ = append(environ, "DAPLA_WORKING_DIR="+c.WorkingDir)
+	}
+	return append(environ, "DAPLA_DEBUG="+strconv.FormatBool(c.Debug), "DAPLA_VERSION="+c.Version)
+}
+
+// Command returns the command running plugin with args and the context in its environment. The standard streams of
+// the command are not set.
+func (p Plugin) Command(ctx context.Context, args []string, pluginContext Context) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, p.Path, args...)
+	cmd.Env = append(os.Environ(), pluginContext.Environ()...)
+	return cmd
+}
+
+// Run runs plugin with args, with the context in its environment and the given standard streams. Returns the exit
+// code of the plugin, or an error if it could not be run.
+func (p Plugin) Run(ctx context.Context, args []string, pluginContext Context, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	cmd := p.Command(ctx, args, pluginContext)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdin, stdout, stderr
+	err := cmd.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return exit.ExitCode(), nil
+	}
+	return 0, err
+}
+
+// ErrNoCompletion is returned by Complete if the plugin does not support completion
+var ErrNoCompletion = errors.New("the plugin does not support completion")
+
+// Complete asks the plugin for the completions of toComplete, following args: the plugin is run with the __complete
+// command, like the completion scripts of the cobra library do. Plugins written with cobra support this out of the
+// box. Returns the completion lines and the directive, see ParseCompletions.
+func (p Plugin) Complete(ctx context.Context, args []string, toComplete string, pluginContext Context) ([]string, int, error) {
+	cmd := p.Command(ctx, append(append([]string{"__complete"}, args...), toComplete), pluginContext)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, 0, ErrNoCompletion
+	}
+	return ParseCompletions(string(output))
+}
+
+// ParseCompletions parses the output of the __complete command of the cobra library: a completion per line,
+// optionally followed by a tab and a description, and a last line holding a colon and a number, the directive.
+// Returns the completion lines and the directive, or ErrNoCompletion if the output does not follow the protocol.
+func ParseCompletions(output string) ([]string, int, error) {
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	last := strings.TrimRight(lines[len(lines)-1], "\r")
+	if !strings.HasPrefix(last, ":") {
+		return nil, 0, ErrNoCompletion
+	}
+	directive, err := strconv.Atoi(last[1:])
+	if err != nil {
+		return nil, 0, ErrNoCompletion
+	}
+	var completions []string
+	for _, line := range lines[:len(lines)-1] {
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			completions = append(completions, line)
+		}
+	}
+	return completions, directive, nil
+}
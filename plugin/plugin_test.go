@@ -0,0 +1,94 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writePlugin writes an executable shell script named dapla-name in dir
+func writePlugin(t *testing.T, dir string, name string, script string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	path := filepath.Join(dir, Prefix+name)
+	assert.NoError(t, ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755))
+	return path
+}
+
+func TestFind(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	foo := writePlugin(t, first, "foo", "")
+	shadowed := writePlugin(t, second, "foo", "")
+	bar := writePlugin(t, second, "bar", "")
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(first, "dapla-data.txt"), nil, 0644))
+	assert.NoError(t, os.Mkdir(filepath.Join(first, "dapla-folder"), 0755))
+	writePlugin(t, first, "", "")
+
+	pathList := strings.Join([]string{first, "", filepath.Join(first, "missing"), second}, string(filepath.ListSeparator))
+	assert.Equal(t, []Plugin{
+		{Name: "bar", Path: bar},
+		{Name: "foo", Path: foo, Shadowed: []string{shadowed}},
+	}, Find(pathList))
+	assert.Empty(t, Find(""))
+}
+
+func TestContext_Environ(t *testing.T) {
+	pluginContext := Context{
+		APIs:       map[string]string{"data-maintenance": "http://maintenance", "dapla-pseudo-service": "http://pseudo"},
+		AuthToken:  "token",
+		WorkingDir: "/skatt",
+		Version:    "1.2.3",
+	}
+	assert.Equal(t, []string{
+		`DAPLA_CONTEXT={"apis":{"dapla-pseudo-service":"http://pseudo","data-maintenance":"http://maintenance"},` +
+			`"authToken":"token","workingDir":"/skatt","debug":false,"version":"1.2.3"}`,
+		"DAPLA_API_DAPLA_PSEUDO_SERVICE=http://pseudo",
+		"DAPLA_API_DATA_MAINTENANCE=http://maintenance",
+		"DAPLA_AUTHTOKEN=token",
+		"DAPLA_WORKING_DIR=/skatt",
+		"DAPLA_DEBUG=false",
+		"DAPLA_VERSION=1.2.3",
+	}, pluginContext.Environ())
+
+	assert.Equal(t, []string{
+		`DAPLA_CONTEXT={"apis":{},"debug":true,"version":""}`,
+		"DAPLA_DEBUG=true",
+		"DAPLA_VERSION=",
+	}, Context{APIs: map[string]string{}, Debug: true}.Environ())
+}
+
+func TestPlugin_Run(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "echo", `echo "$@" "$DAPLA_AUTHTOKEN"; cat; exit 3`)
+	plugin := Find(dir)[0]
+
+	var stdout, stderr bytes.Buffer
+	code, err := plugin.Run(context.Background(), []string{"--flag", "arg"}, Context{AuthToken: "token"},
+		strings.NewReader("input\n"), &stdout, &stderr)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, code)
+	assert.Equal(t, "--flag arg token\ninput\n", stdout.String())
+}
+
+func TestPlugin_Complete(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "complete", `[ "$1" = __complete ] && printf 'one\tthe first\n%s\n:4\n' "$3"`)
+	writePlugin(t, dir, "plain", `echo "$@"`)
+	plugins := Find(dir)
+
+	completions, directive, err := plugins[0].Complete(context.Background(), []string{"arg"}, "o", Context{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"one\tthe first", "o"}, completions)
+	assert.Equal(t, 4, directive)
+
+	_, _, err = plugins[1].Complete(context.Background(), nil, "", Context{})
+	assert.Equal(t, ErrNoCompletion, err)
+}
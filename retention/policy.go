@@ -0,0 +1,136 @@
+package retention
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/statisticsnorway/dapla-cli/glob"
+	"github.com/statisticsnorway/dapla-cli/maintenance"
+	"gopkg.in/yaml.v2"
+)
+
+// Policy maps dataset path globs to retention rules. The rules are evaluated in order, and the first rule with a
+// matching path applies. Datasets that are not matched by any rule are left untouched.
+//
+// Example:
+//
+//	rules:
+//	  - path: /produkt/**
+//	    keep-all: true
+//	  - path: /tmp/**
+//	    keep-within: 7d
+//	  - path: /raw/**
+//	    keep-last: 3
+type Policy struct {
+	Rules []PolicyRule `yaml:"rules"`
+}
+
+// PolicyRule holds the retention rule for datasets with a path matching a glob
+type PolicyRule struct {
+	Path       string `yaml:"path"`
+	KeepLast   int    `yaml:"keep-last"`
+	KeepWithin string `yaml:"keep-within"`
+	KeepAll    bool   `yaml:"keep-all"`
+
+	rule Rule
+}
+
+// Rule returns the retention rule. The rule is empty if all versions should be kept.
+func (r PolicyRule) Rule() Rule {
+	return r.rule
+}
+
+// String returns a human readable description of the policy rule
+func (r PolicyRule) String() string {
+	if r.KeepAll {
+		return r.Path + " keep-all"
+	}
+	return r.Path + " " + r.rule.String()
+}
+
+// LoadPolicy reads and validates a retention policy from a YAML file
+func LoadPolicy(filename string) (*Policy, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePolicy(data)
+}
+
+// ParsePolicy parses and validates a retention policy
+func ParsePolicy(data []byte) (*Policy, error) {
+	policy := Policy{}
+	if err := yaml.UnmarshalStrict(data, &policy); err != nil {
+		return nil, fmt.Errorf("invalid retention policy: %v", err)
+	}
+
+	for i := range policy.Rules {
+		r := &policy.Rules[i]
+		if !strings.HasPrefix(r.Path, "/") {
+			return nil, fmt.Errorf("invalid retention policy rule %d: path must be absolute", i+1)
+		}
+		if err := glob.Validate(r.Path); err != nil {
+			return nil, fmt.Errorf("invalid retention policy rule %d: %v", i+1, err)
+		}
+
+		r.rule = Rule{KeepLast: r.KeepLast}
+		if r.KeepWithin != "" {
+			keepWithin, err := ParseDuration(r.KeepWithin)
+			if err != nil {
+				return nil, fmt.Errorf("invalid retention policy rule %d: %v", i+1, err)
+			}
+			r.rule.KeepWithin = keepWithin
+		}
+
+		if r.KeepAll == !r.rule.IsEmpty() {
+			return nil, fmt.Errorf("invalid retention policy rule %d: specify either keep-all or keep-last and/or keep-within", i+1)
+		}
+	}
+
+	return &policy, nil
+}
+
+// RuleFor returns the first rule that matches the dataset path, or nil if there is no matching rule
+func (p Policy) RuleFor(datasetPath string) *PolicyRule {
+	for i, r := range p.Rules {
+		if glob.Match(r.Path, datasetPath) {
+			return &p.Rules[i]
+		}
+	}
+	return nil
+}
+
+// Roots returns the folders that must be walked to find all datasets that may be matched by the policy, i.e. the
+// longest non-wildcard prefix of each rule path. The root of a rule without wildcards is the path of the rule, which
+// may be a dataset rather than a folder. Roots nested within other roots are omitted.
+func (p Policy) Roots() []string {
+	var roots []string
+	for _, r := range p.Rules {
+		if r.KeepAll {
+			continue
+		}
+		var prefix []string
+		for _, segment := range strings.Split(strings.Trim(r.Path, "/"), "/") {
+			if strings.ContainsAny(segment, "*?[") {
+				break
+			}
+			prefix = append(prefix, segment)
+		}
+		roots = addRoot(roots, "/"+strings.Join(prefix, "/"))
+	}
+	return roots
+}
+
+func addRoot(roots []string, root string) []string {
+	var result []string
+	for _, r := range roots {
+		if maintenance.IsWithin(root, r) {
+			return roots
+		}
+		if !maintenance.IsWithin(r, root) {
+			result = append(result, r)
+		}
+	}
+	return append(result, root)
+}
@@ -0,0 +1,87 @@
+package retention
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testPolicy = `
+rules:
+  - path: /produkt/**
+    keep-all: true
+  - path: /tmp/**
+    keep-within: 7d
+  - path: /raw/*/daily
+    keep-last: 3
+  - path: /raw/**
+    keep-last: 10
+    keep-within: 90d
+`
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("Got error %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/produkt/foo/bar", "/produkt/** keep-all"},
+		{"/tmp/foo", "/tmp/** keep-within 7d"},
+		{"/raw/skatt/daily", "/raw/*/daily keep-last 3"},
+		{"/raw/skatt/monthly", "/raw/** keep-last 10, keep-within 90d"},
+	}
+	for _, test := range tests {
+		rule := policy.RuleFor(test.path)
+		if rule == nil {
+			t.Errorf("Expected rule for %s", test.path)
+		} else if rule.String() != test.expected {
+			t.Errorf("Expected rule %s for %s, but got %s", test.expected, test.path, rule)
+		}
+	}
+
+	if rule := policy.RuleFor("/kilde/foo"); rule != nil {
+		t.Errorf("Expected no rule for /kilde/foo, but got %s", rule)
+	}
+
+	if rule := policy.RuleFor("/tmp/foo").Rule(); rule.KeepWithin != 7*24*time.Hour {
+		t.Errorf("Expected keep-within 7d, but got %v", rule)
+	}
+}
+
+func TestParsePolicy_Invalid(t *testing.T) {
+	tests := []string{
+		"rules:\n  - path: tmp/**\n    keep-last: 1",
+		"rules:\n  - path: /tmp/**",
+		"rules:\n  - path: /tmp/**\n    keep-all: true\n    keep-last: 1",
+		"rules:\n  - path: /tmp/**\n    keep-within: 7x",
+		"rules:\n  - path: /tmp/[\n    keep-last: 1",
+		"rules:\n  - path: /tmp/**\n    keep: 1",
+	}
+
+	for _, test := range tests {
+		if _, err := ParsePolicy([]byte(test)); err == nil {
+			t.Errorf("Expected error for policy:\n%s", test)
+		}
+	}
+}
+
+func TestPolicy_Roots(t *testing.T) {
+	policy, err := ParsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("Got error %v", err)
+	}
+
+	if expected, actual := []string{"/tmp", "/raw"}, policy.Roots(); !cmp.Equal(expected, actual) {
+		t.Errorf("Expected %v, but got %v", expected, actual)
+	}
+
+	policy, _ = ParsePolicy([]byte("rules:\n  - path: /raw/foo/**\n    keep-last: 1\n  - path: /**\n    keep-last: 2"))
+	if expected, actual := []string{"/"}, policy.Roots(); !cmp.Equal(expected, actual) {
+		t.Errorf("Expected %v, but got %v", expected, actual)
+	}
+}
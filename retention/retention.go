@@ -0,0 +1,91 @@
+// Package retention decides which versions of a dataset to keep and which to delete, based on retention rules
+package retention
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule holds the retention rules for a dataset. A version is kept if it is kept by any of the rules.
+type Rule struct {
+	// KeepLast keeps the n latest versions
+	KeepLast int
+	// KeepWithin keeps all versions that are newer than the duration
+	KeepWithin time.Duration
+}
+
+// IsEmpty returns true iff no rules are specified
+func (r Rule) IsEmpty() bool {
+	return r.KeepLast <= 0 && r.KeepWithin <= 0
+}
+
+// String returns a human readable description of the rule
+func (r Rule) String() string {
+	var rules []string
+	if r.KeepLast > 0 {
+		rules = append(rules, fmt.Sprintf("keep-last %d", r.KeepLast))
+	}
+	if r.KeepWithin > 0 {
+		rules = append(rules, "keep-within "+FormatDuration(r.KeepWithin))
+	}
+	return strings.Join(rules, ", ")
+}
+
+// Expired returns the timestamps of the versions that are not kept by the rule, oldest first. The latest version
+// is always kept, so that a dataset is never removed entirely. An empty rule keeps all versions.
+func (r Rule) Expired(timestamps []time.Time, now time.Time) []time.Time {
+	if r.IsEmpty() {
+		return nil
+	}
+
+	sorted := append([]time.Time{}, timestamps...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].After(sorted[j]) })
+
+	var expired []time.Time
+	for i, timestamp := range sorted {
+		switch {
+		case i == 0:
+		case i < r.KeepLast:
+		case r.KeepWithin > 0 && now.Sub(timestamp) < r.KeepWithin:
+		default:
+			expired = append([]time.Time{timestamp}, expired...)
+		}
+	}
+	return expired
+}
+
+// ParseDuration parses a duration such as 90d, 2w or 36h. In addition to the units supported by time.ParseDuration,
+// the units d (days) and w (weeks) are supported, but cannot be combined with other units.
+func ParseDuration(s string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+	for suffix, unit := range units {
+		if strings.HasSuffix(s, suffix) {
+			n, err := strconv.Atoi(strings.TrimSuffix(s, suffix))
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid duration %s", s)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %s", s)
+	}
+	return d, nil
+}
+
+// FormatDuration formats a duration using days if it is a whole number of days
+func FormatDuration(d time.Duration) string {
+	day := 24 * time.Hour
+	if d >= day && d%day == 0 {
+		return fmt.Sprintf("%dd", d/day)
+	}
+	return d.String()
+}
@@ -0,0 +1,75 @@
+package retention
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRule_Expired(t *testing.T) {
+	now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	timestamps := []time.Time{
+		now.Add(-100 * day),
+		now.Add(-1 * day),
+		now.Add(-200 * day),
+		now.Add(-50 * day),
+		now.Add(-2 * day),
+	}
+
+	tests := []struct {
+		rule     Rule
+		expected []time.Time
+	}{
+		{Rule{}, nil},
+		{Rule{KeepLast: 2}, []time.Time{timestamps[2], timestamps[0], timestamps[3]}},
+		{Rule{KeepWithin: 90 * day}, []time.Time{timestamps[2], timestamps[0]}},
+		{Rule{KeepLast: 4, KeepWithin: 10 * day}, []time.Time{timestamps[2]}},
+		{Rule{KeepLast: 10}, nil},
+		// The latest version is always kept
+		{Rule{KeepWithin: time.Hour}, []time.Time{timestamps[2], timestamps[0], timestamps[3], timestamps[4]}},
+	}
+
+	for _, test := range tests {
+		if actual := test.rule.Expired(timestamps, now); !cmp.Equal(test.expected, actual) {
+			t.Errorf("Rule %v: expected %v, but got %v", test.rule, test.expected, actual)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+	}{
+		{"90d", 90 * 24 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+		{"36h", 36 * time.Hour},
+		{"1h30m", 90 * time.Minute},
+	}
+
+	for _, test := range tests {
+		actual, err := ParseDuration(test.input)
+		if err != nil {
+			t.Errorf("Got error %v", err)
+		} else if actual != test.expected {
+			t.Errorf("Expected %s to be %v, but got %v", test.input, test.expected, actual)
+		}
+	}
+
+	for _, invalid := range []string{"", "d", "xd", "-1d", "90"} {
+		if _, err := ParseDuration(invalid); err == nil {
+			t.Errorf("Expected error for duration %q", invalid)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	if actual := FormatDuration(90 * 24 * time.Hour); actual != "90d" {
+		t.Errorf("Expected 90d, but got %s", actual)
+	}
+	if actual := FormatDuration(36 * time.Hour); actual != "36h0m0s" {
+		t.Errorf("Expected 36h0m0s, but got %s", actual)
+	}
+}
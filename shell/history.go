@@ -0,0 +1,50 @@
+package shell
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// History holds the lines read by a LineReader, oldest first, up to a maximum number of lines
+type History struct {
+	Lines []string
+	max   int
+}
+
+// NewHistory creates an empty history holding at most max lines
+func NewHistory(max int) *History {
+	return &History{max: max}
+}
+
+// Add adds a line to the history, unless it is blank or the same as the previous line. The oldest line is dropped
+// if the history is full.
+func (h *History) Add(line string) {
+	if strings.TrimSpace(line) == "" || (len(h.Lines) > 0 && h.Lines[len(h.Lines)-1] == line) {
+		return
+	}
+	h.Lines = append(h.Lines, line)
+	if len(h.Lines) > h.max {
+		h.Lines = h.Lines[len(h.Lines)-h.max:]
+	}
+}
+
+// Load adds the lines read from r, one per line, to the history
+func (h *History) Load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		h.Add(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// Save writes the lines of the history to w, one per line
+func (h *History) Save(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	for _, line := range h.Lines {
+		if _, err := writer.WriteString(line + "\n"); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,67 @@
+package shell
+
+import (
+	"bufio"
+	"unicode"
+)
+
+// Key is a key pressed on a terminal in raw mode: either a character, including control characters such as '\r' and
+// Ctrl+C (3), or one of the special keys below
+type Key rune
+
+// The special keys, which are sent by the terminal as escape sequences
+const (
+	KeyUp Key = -(iota + 1)
+	KeyDown
+	KeyRight
+	KeyLeft
+	KeyHome
+	KeyEnd
+	KeyDelete
+	KeyPageUp
+	KeyPageDown
+	// KeyUnknown is an escape sequence that is not recognized
+	KeyUnknown
+)
+
+// escapeKeys maps the escape sequences of the special keys, without the escape character, to the keys. The
+// sequences differ between terminals and modes, so there may be several per key.
+var escapeKeys = map[string]Key{
+	"[A": KeyUp, "OA": KeyUp,
+	"[B": KeyDown, "OB": KeyDown,
+	"[C": KeyRight, "OC": KeyRight,
+	"[D": KeyLeft, "OD": KeyLeft,
+	"[H": KeyHome, "OH": KeyHome, "[1~": KeyHome, "[7~": KeyHome,
+	"[F": KeyEnd, "OF": KeyEnd, "[4~": KeyEnd, "[8~": KeyEnd,
+	"[3~": KeyDelete,
+	"[5~": KeyPageUp,
+	"[6~": KeyPageDown,
+}
+
+// ReadKey reads a key press from in, which should be read from a terminal in raw mode
+func ReadKey(in *bufio.Reader) (Key, error) {
+	r, _, err := in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if r != 27 {
+		return Key(r), nil
+	}
+
+	// Read the rest of the escape sequence, which ends with a letter or ~, except for the [ or O introducing it
+	var seq []rune
+	for {
+		r, _, err := in.ReadRune()
+		if err != nil {
+			break
+		}
+		seq = append(seq, r)
+		if len(seq) > 1 && (unicode.IsLetter(r) || r == '~') || len(seq) == 1 && r != '[' && r != 'O' {
+			break
+		}
+	}
+	if key, ok := escapeKeys[string(seq)]; ok {
+		return key, nil
+	}
+	return KeyUnknown, nil
+}
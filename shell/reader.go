@@ -0,0 +1,275 @@
+package shell
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// ErrInterrupted is returned by ReadLine when the user presses Ctrl+C while editing a line
+var ErrInterrupted = errors.New("interrupted")
+
+// ErrNotTerminal is returned by MakeRaw and Size if the file is not a terminal (or the platform is not supported)
+var ErrNotTerminal = errors.New("not a terminal")
+
+// CompleteFunc returns the candidates for completing the last word of head, which is the line up to the cursor. The
+// candidates replace the whole word. If there is only one candidate, it is followed by a space if space is true.
+type CompleteFunc func(head string) (candidates []string, space bool)
+
+// terminal switches a terminal between raw mode, where the LineReader handles every key press, and the mode it was in
+type terminal interface {
+	// raw puts the terminal in raw mode, and returns a function that restores the previous mode
+	raw() (restore func(), err error)
+}
+
+// LineReader reads command lines. On a terminal, the line can be edited with the usual keys: the arrow keys, Home,
+// End, Backspace and Delete, and Emacs style Ctrl keys. Up and Down (or Ctrl+P and Ctrl+N) recall the lines in the
+// History, and TAB completes the word before the cursor. Otherwise lines are read as they are, without echo.
+type LineReader struct {
+	// History holds the lines read, and is used to recall previous lines
+	History *History
+	// Complete is called to complete words when TAB is pressed, or nil to disable completion
+	Complete CompleteFunc
+
+	in       *bufio.Reader
+	out      io.Writer
+	terminal terminal
+}
+
+// NewLineReader creates a reader of lines from in, printing prompts and the edited lines to out. Lines can be edited
+// if file is a terminal. Since in is shared, other parts of the program may read from it between the lines.
+func NewLineReader(in *bufio.Reader, out io.Writer, file *os.File) *LineReader {
+	return &LineReader{
+		History:  NewHistory(1000),
+		in:       in,
+		out:      out,
+		terminal: newTerminal(file),
+	}
+}
+
+// Interactive returns true if the lines can be edited, i.e. if they are read from a terminal
+func (r *LineReader) Interactive() bool {
+	return r.terminal != nil
+}
+
+// ReadLine prints prompt and reads a line, without the line ending. Returns io.EOF at the end of the input (or
+// Ctrl+D on an empty line), and ErrInterrupted if the line is discarded with Ctrl+C.
+func (r *LineReader) ReadLine(prompt string) (string, error) {
+	if r.terminal != nil {
+		restore, err := r.terminal.raw()
+		if err == nil {
+			defer restore()
+			return r.edit(prompt)
+		}
+	}
+
+	fmt.Fprint(r.out, prompt)
+	line, err := r.in.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	r.History.Add(line)
+	return line, nil
+}
+
+// lineEditor holds the state of the line being edited
+type lineEditor struct {
+	prompt string
+	out    io.Writer
+	line   []rune
+	pos    int
+	// history is the index of the history line being edited, or the number of history lines for a new line
+	history int
+	// draft holds the new line while the history is browsed
+	draft []rune
+}
+
+func (r *LineReader) edit(prompt string) (string, error) {
+	e := &lineEditor{prompt: prompt, out: r.out, history: len(r.History.Lines)}
+	e.redraw()
+	for {
+		key, err := ReadKey(r.in)
+		if err == io.EOF && len(e.line) > 0 {
+			key, err = '\r', nil
+		}
+		if err != nil {
+			fmt.Fprintln(r.out)
+			return "", err
+		}
+
+		switch key {
+		case '\r', '\n':
+			fmt.Fprintln(r.out)
+			r.History.Add(string(e.line))
+			return string(e.line), nil
+		case 3: // Ctrl+C
+			fmt.Fprintln(r.out, "^C")
+			return "", ErrInterrupted
+		case 4: // Ctrl+D
+			if len(e.line) == 0 {
+				fmt.Fprintln(r.out)
+				return "", io.EOF
+			}
+			e.delete(e.pos, e.pos+1)
+		case 1: // Ctrl+A
+			e.pos = 0
+		case 5: // Ctrl+E
+			e.pos = len(e.line)
+		case 2: // Ctrl+B
+			e.move(-1)
+		case 6: // Ctrl+F
+			e.move(1)
+		case 11: // Ctrl+K
+			e.delete(e.pos, len(e.line))
+		case 21: // Ctrl+U
+			e.delete(0, e.pos)
+		case 23: // Ctrl+W
+			e.delete(wordStart(e.line[:e.pos], true), e.pos)
+		case 12: // Ctrl+L
+			fmt.Fprint(r.out, "\x1b[H\x1b[2J")
+		case 16: // Ctrl+P
+			e.recall(r.History, -1)
+		case 14: // Ctrl+N
+			e.recall(r.History, 1)
+		case 8, 127: // Backspace
+			if e.pos > 0 {
+				e.delete(e.pos-1, e.pos)
+			}
+		case '\t':
+			r.complete(e)
+		case KeyUp:
+			e.recall(r.History, -1)
+		case KeyDown:
+			e.recall(r.History, 1)
+		case KeyRight:
+			e.move(1)
+		case KeyLeft:
+			e.move(-1)
+		case KeyHome:
+			e.pos = 0
+		case KeyEnd:
+			e.pos = len(e.line)
+		case KeyDelete:
+			e.delete(e.pos, e.pos+1)
+		default:
+			if key > 0 && unicode.IsPrint(rune(key)) {
+				e.insert([]rune{rune(key)})
+			}
+		}
+		e.redraw()
+	}
+}
+
+// complete replaces the word before the cursor with its completion. If there are several candidates, the word is
+// extended with their common prefix, or the candidates are listed if the word can not be extended.
+func (r *LineReader) complete(e *lineEditor) {
+	if r.Complete == nil {
+		return
+	}
+	candidates, space := r.Complete(string(e.line[:e.pos]))
+	start := wordStart(e.line[:e.pos], false)
+	word := string(e.line[start:e.pos])
+
+	var completion string
+	switch {
+	case len(candidates) == 0:
+		fmt.Fprint(r.out, "\a")
+		return
+	case len(candidates) == 1:
+		completion = candidates[0]
+		if space {
+			completion += " "
+		}
+	default:
+		completion = commonPrefix(candidates)
+		if len(completion) <= len(word) {
+			fmt.Fprintln(r.out)
+			fmt.Fprintln(r.out, strings.Join(candidates, "  "))
+			return
+		}
+	}
+	e.delete(start, e.pos)
+	e.insert([]rune(completion))
+}
+
+// wordStart returns the index of the start of the last word of line. Whitespace at the end of line is skipped if
+// skipSpace is true, otherwise the word is empty if line ends with whitespace.
+func wordStart(line []rune, skipSpace bool) int {
+	i := len(line)
+	for skipSpace && i > 0 && unicode.IsSpace(line[i-1]) {
+		i--
+	}
+	for i > 0 && !unicode.IsSpace(line[i-1]) {
+		i--
+	}
+	return i
+}
+
+func commonPrefix(words []string) string {
+	prefix := []rune(words[0])
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(word, string(prefix)) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return string(prefix)
+}
+
+func (e *lineEditor) insert(runes []rune) {
+	line := append([]rune{}, e.line[:e.pos]...)
+	line = append(line, runes...)
+	e.line = append(line, e.line[e.pos:]...)
+	e.pos += len(runes)
+}
+
+func (e *lineEditor) delete(from, to int) {
+	if to > len(e.line) {
+		to = len(e.line)
+	}
+	if from >= to {
+		return
+	}
+	e.line = append(e.line[:from], e.line[to:]...)
+	e.pos = from
+}
+
+func (e *lineEditor) move(n int) {
+	if e.pos+n >= 0 && e.pos+n <= len(e.line) {
+		e.pos += n
+	}
+}
+
+// recall replaces the line with the previous (-1) or next (1) line of the history. The new line is kept as a draft
+// while the history is browsed.
+func (e *lineEditor) recall(history *History, n int) {
+	i := e.history + n
+	if i < 0 || i > len(history.Lines) {
+		return
+	}
+	if e.history == len(history.Lines) {
+		e.draft = e.line
+	}
+	e.history = i
+	if i == len(history.Lines) {
+		e.line = e.draft
+	} else {
+		e.line = []rune(history.Lines[i])
+	}
+	e.pos = len(e.line)
+}
+
+// redraw prints the prompt and the line over the current line of the terminal, and moves the cursor into place
+func (e *lineEditor) redraw() {
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", e.prompt, string(e.line))
+	if back := len(e.line) - e.pos; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
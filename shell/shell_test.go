@@ -0,0 +1,122 @@
+package shell
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTerminal is a terminal that is always in raw mode
+type fakeTerminal struct{}
+
+func (fakeTerminal) raw() (func(), error) {
+	return func() {}, nil
+}
+
+func newTestReader(input string, interactive bool) (*LineReader, *bytes.Buffer) {
+	var out bytes.Buffer
+	r := NewLineReader(bufio.NewReader(strings.NewReader(input)), &out, nil)
+	if interactive {
+		r.terminal = fakeTerminal{}
+	}
+	return r, &out
+}
+
+func readLines(r *LineReader) ([]string, error) {
+	var lines []string
+	for {
+		line, err := r.ReadLine("> ")
+		if err != nil {
+			return lines, err
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	for line, expected := range map[string][]string{
+		"":                                 nil,
+		"  ls  -l /skatt ":                 {"ls", "-l", "/skatt"},
+		`find . -name 'inntekt *' -print0`: {"find", ".", "-name", "inntekt *", "-print0"},
+		`export -p "a \"secret\"" x`:       {"export", "-p", `a "secret"`, "x"},
+		`rm my\ dataset ''`:                {"rm", "my dataset", ""},
+	} {
+		args, err := Split(line)
+		assert.NoError(t, err, line)
+		assert.Equal(t, expected, args, line)
+	}
+
+	_, err := Split(`ls 'skatt`)
+	assert.EqualError(t, err, "unterminated ' quote")
+	_, err = Split(`ls skatt\`)
+	assert.EqualError(t, err, `unterminated \ escape`)
+}
+
+func TestHistory(t *testing.T) {
+	history := NewHistory(2)
+	assert.NoError(t, history.Load(strings.NewReader("ls\nls\n\npwd\ncd /skatt\n")))
+	assert.Equal(t, []string{"pwd", "cd /skatt"}, history.Lines)
+
+	var saved bytes.Buffer
+	assert.NoError(t, history.Save(&saved))
+	assert.Equal(t, "pwd\ncd /skatt\n", saved.String())
+}
+
+func TestLineReader_Plain(t *testing.T) {
+	r, out := newTestReader("ls /skatt\r\n\npwd", false)
+	assert.False(t, r.Interactive())
+
+	lines, err := readLines(r)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, []string{"ls /skatt", "", "pwd"}, lines)
+	assert.Equal(t, "> > > > ", out.String())
+	assert.Equal(t, []string{"ls /skatt", "pwd"}, r.History.Lines)
+}
+
+func TestLineReader_Edit(t *testing.T) {
+	// Typing, moving around with the arrow keys and Ctrl+A, deleting with Backspace, Delete and Ctrl+W
+	r, _ := newTestReader("lx\x7fs /skaxt\x1b[D\x1b[D\x1b[3~\x1b[Ct\r"+
+		"/skatt\x01rm \x05 /tmp/x\x17/kilde\n", true)
+	assert.True(t, r.Interactive())
+
+	lines, err := readLines(r)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, []string{"ls /skatt", "rm /skatt /kilde"}, lines)
+}
+
+func TestLineReader_History(t *testing.T) {
+	r, _ := newTestReader("\x1b[A\x1b[A\x1b[A\r"+
+		"new\x10\x0e\x0e\r"+
+		"\x03", true)
+	r.History.Add("ls")
+	r.History.Add("pwd")
+
+	lines, err := readLines(r)
+	assert.Equal(t, ErrInterrupted, err)
+	assert.Equal(t, []string{"ls", "new"}, lines)
+	assert.Equal(t, []string{"ls", "pwd", "ls", "new"}, r.History.Lines)
+}
+
+func TestLineReader_Complete(t *testing.T) {
+	r, out := newTestReader("ls /s\t\tin\t\r", true)
+	r.Complete = func(head string) ([]string, bool) {
+		switch head {
+		case "ls /s":
+			return []string{"/skatt/", "/skole/"}, false
+		case "ls /sk":
+			return []string{"/skatt/", "/skole/"}, false
+		case "ls /skin":
+			return []string{"/skinke"}, true
+		}
+		return nil, false
+	}
+
+	lines, err := readLines(r)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, []string{"ls /skinke "}, lines)
+	assert.Contains(t, out.String(), "\n/skatt/  /skole/\n")
+}
@@ -0,0 +1,69 @@
+// Package shell implements the line editing of the interactive dapla shell. On a terminal, lines can be edited, with
+// history and tab completion, and otherwise lines are read as they are. It also splits command lines into arguments,
+// and reads the key presses of full-screen programs such as the dataset browser.
+package shell
+
+import (
+	"errors"
+	"strings"
+)
+
+// Split splits a command line into arguments, much like a POSIX shell but without any expansions. Arguments are
+// separated by whitespace. Single quotes preserve everything up to the next single quote, double quotes preserve
+// everything up to the next double quote except backslash escapes, and a backslash outside of quotes escapes the
+// next character.
+func Split(line string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else if r == '\\' {
+				escaped = true
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == '\\':
+			escaped = true
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, errors.New("unterminated " + string(quote) + " quote")
+	}
+	if escaped {
+		return nil, errors.New("unterminated \\ escape")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,21 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package shell
+
+import "os"
+
+// newTerminal returns nil, since lines can only be edited on Linux and macOS terminals
+func newTerminal(file *os.File) terminal {
+	return nil
+}
+
+// MakeRaw returns ErrNotTerminal, since raw mode is only supported on Linux and macOS terminals
+func MakeRaw(file *os.File) (restore func(), err error) {
+	return nil, ErrNotTerminal
+}
+
+// Size returns ErrNotTerminal, since the size is only known on Linux and macOS terminals
+func Size(file *os.File) (width, height int, err error) {
+	return 0, 0, ErrNotTerminal
+}
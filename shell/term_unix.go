@@ -0,0 +1,69 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package shell
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// unixTerminal is a terminal controlled with termios
+type unixTerminal struct {
+	fd int
+}
+
+// newTerminal returns the terminal of file, or nil if file is not a terminal
+func newTerminal(file *os.File) terminal {
+	if file == nil {
+		return nil
+	}
+	if _, err := unix.IoctlGetTermios(int(file.Fd()), ioctlGetTermios); err != nil {
+		return nil
+	}
+	return &unixTerminal{fd: int(file.Fd())}
+}
+
+// raw turns off echo, line buffering and signals such as Ctrl+C, like cfmakeraw, but keeps the output processing so
+// that newlines are printed as usual
+func (t *unixTerminal) raw() (func(), error) {
+	termios, err := unix.IoctlGetTermios(t.fd, ioctlGetTermios)
+	if err != nil {
+		return nil, err
+	}
+	previous := *termios
+
+	termios.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	termios.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	termios.Cflag &^= unix.CSIZE | unix.PARENB
+	termios.Cflag |= unix.CS8
+	termios.Cc[unix.VMIN] = 1
+	termios.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(t.fd, ioctlSetTermios, termios); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		unix.IoctlSetTermios(t.fd, ioctlSetTermios, &previous)
+	}, nil
+}
+
+// MakeRaw puts the terminal of file in raw mode (see LineReader), and returns a function that restores the previous
+// mode. Returns ErrNotTerminal if file is not a terminal.
+func MakeRaw(file *os.File) (restore func(), err error) {
+	t := newTerminal(file)
+	if t == nil {
+		return nil, ErrNotTerminal
+	}
+	return t.raw()
+}
+
+// Size returns the number of columns and rows of the terminal of file
+func Size(file *os.File) (width, height int, err error) {
+	size, err := unix.IoctlGetWinsize(int(file.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, 0, ErrNotTerminal
+	}
+	return int(size.Col), int(size.Row), nil
+}